@@ -7,6 +7,7 @@ import (
 
 	"github.com/marceloaguero/go-nats-products/gateway/pkg/delivery/products"
 	"github.com/marceloaguero/go-nats-products/gateway/pkg/delivery/router"
+	"github.com/marceloaguero/go-nats-products/products/pkg/productsclient"
 	"github.com/nats-io/nats.go"
 )
 
@@ -14,7 +15,6 @@ func main() {
 	pathPrefix := os.Getenv("PATH_PREFIX")
 	natsURLs := os.Getenv("NATS_URLS")
 	productsSubjPrefix := os.Getenv("PRODUCTS_SUBJ_PREFIX")
-	productsQueue := os.Getenv("PRODUCTS_QUEUE")
 
 	// Connect to NATS server
 	nc, err := nats.Connect(natsURLs)
@@ -23,7 +23,8 @@ func main() {
 	}
 	defer nc.Close()
 
-	productsDelivery := products.NewDelivery(nc, productsSubjPrefix, productsQueue)
+	productsClient := productsclient.NewClient(nc, productsSubjPrefix, productsclient.DefaultTimeout)
+	productsDelivery := products.NewDelivery(productsClient)
 
 	_, err = router.NewRouter(productsDelivery, pathPrefix)
 	if err != nil {
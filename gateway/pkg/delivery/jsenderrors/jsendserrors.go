@@ -12,3 +12,10 @@ func ReturnError(c *gin.Context, message string) {
 		"message": message,
 	})
 }
+
+func ReturnFail(c *gin.Context, httpStatus int, data interface{}) {
+	c.JSON(httpStatus, gin.H{
+		"status": "fail",
+		"data":   data,
+	})
+}
@@ -1,155 +1,993 @@
 package products
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/marceloaguero/go-nats-products/gateway/pkg/delivery/jsenderrors"
-	"github.com/nats-io/nats.go"
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/marceloaguero/go-nats-products/products/pkg/productsclient"
+	"github.com/pkg/errors"
 )
 
-const (
-	timeout = time.Millisecond * 500
-)
-
-type NatsMsgData struct {
-	Status string `json:"status"`
-}
-
 type Delivery interface {
 	Create(c *gin.Context)
 	GetByID(c *gin.Context)
 	GetByName(c *gin.Context)
 	GetAll(c *gin.Context)
+	GetLowStock(c *gin.Context)
 	Update(c *gin.Context)
 	Delete(c *gin.Context)
+	Restore(c *gin.Context)
+	History(c *gin.Context)
 	UpdateStock(c *gin.Context)
+	AddStockMovement(c *gin.Context)
+	GetStockMovements(c *gin.Context)
+	Reserve(c *gin.Context)
+	GetReservation(c *gin.Context)
+	ConfirmReservation(c *gin.Context)
+	ReleaseReservation(c *gin.Context)
+	SchedulePrice(c *gin.Context)
+	GetPrices(c *gin.Context)
+	CancelPrice(c *gin.Context)
+	CreateCategory(c *gin.Context)
+	GetCategories(c *gin.Context)
+	GetCategory(c *gin.Context)
+	UpdateCategory(c *gin.Context)
+	DeleteCategory(c *gin.Context)
+	SetProductCategories(c *gin.Context)
+	GetProductCategories(c *gin.Context)
+	CreateVariant(c *gin.Context)
+	GetVariants(c *gin.Context)
+	GetVariant(c *gin.Context)
+	GetVariantBySKU(c *gin.Context)
+	UpdateVariant(c *gin.Context)
+	DeleteVariant(c *gin.Context)
+	CreateLocation(c *gin.Context)
+	GetLocations(c *gin.Context)
+	GetLocationStock(c *gin.Context)
+	GetProductStock(c *gin.Context)
+	TransferStock(c *gin.Context)
+	ImportProducts(c *gin.Context)
+	ExportProducts(c *gin.Context)
+	Batch(c *gin.Context)
 }
 
 type delivery struct {
-	nc         *nats.Conn
-	subjPrefix string
-	queue      string
+	client productsclient.Client
 }
 
-func NewDelivery(nc *nats.Conn, subjPrefix, queue string) Delivery {
+func NewDelivery(client productsclient.Client) Delivery {
 	return &delivery{
-		nc:         nc,
-		subjPrefix: subjPrefix,
-		queue:      queue,
+		client: client,
 	}
 }
 
-func sendRequest(c *gin.Context, d *delivery, method string, subj string, request []byte, defaultStatus int) {
-	msg, err := d.nc.Request(subj, request, timeout)
-	if err != nil {
-		log.Printf("%s - Request error: %s", method, err.Error())
+// ActorHeader es el header HTTP con el que se informa quién realiza el pedido, para el historial de auditoría
+const ActorHeader = "X-Actor"
+
+// clientFor devuelve el cliente del servicio de productos que informa como autor de los cambios al actor del pedido
+func (d *delivery) clientFor(c *gin.Context) productsclient.Client {
+	return d.client.WithActor(c.GetHeader(ActorHeader))
+}
+
+func replySuccess(c *gin.Context, httpStatus int, data interface{}) {
+	c.JSON(httpStatus, gin.H{
+		"status": "success",
+		"data":   data,
+	})
+}
+
+// errorStatus mapea cada código de error del servicio de productos a un status HTTP
+var errorStatus = map[string]int{
+	product.CodeNotFound:        http.StatusNotFound,
+	product.CodeAlreadyExists:   http.StatusConflict,
+	product.CodeValidation:      http.StatusUnprocessableEntity,
+	product.CodeConflict:        http.StatusConflict,
+	product.CodeVersionMismatch: http.StatusConflict,
+}
+
+func replyError(c *gin.Context, method string, err error) {
+	log.Printf("%s - Request error: %s", method, err.Error())
+
+	code := productsclient.ErrorCode(err)
+	httpStatus, ok := errorStatus[code]
+	if !ok {
 		jsenderrors.ReturnError(c, err.Error())
 		return
 	}
 
-	msgData := &NatsMsgData{}
-	err = json.Unmarshal(msg.Data, &msgData)
+	data := gin.H{
+		"code":    code,
+		"message": err.Error(),
+	}
+	if fields := productsclient.ErrorFields(err); fields != nil {
+		data["fields"] = fields
+	}
+	jsenderrors.ReturnFail(c, httpStatus, data)
+}
+
+// paramID obtiene un ID numérico de los parámetros del path.
+// Si no es válido, responde fail y devuelve false.
+func paramID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
 	if err != nil {
-		log.Printf("%s - Unmarshal reply error: %s", method, err.Error())
-		jsenderrors.ReturnError(c, err.Error())
-		return
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, "invalid "+name+": "+c.Param(name))
+		return 0, false
 	}
 
-	stat := msgData.Status
-	var httpStatus int
-	switch {
-	case stat == "fail":
-		httpStatus = http.StatusBadRequest
-		break
-	case stat == "error":
-		httpStatus = http.StatusInternalServerError
-		break
-	default:
-		httpStatus = defaultStatus
+	return uint(id), true
+}
+
+// bindProduct decodifica el body del request en un producto.
+// Si no es válido, responde fail y devuelve false.
+func bindProduct(c *gin.Context) (*product.Product, bool) {
+	p := &product.Product{}
+	if err := c.ShouldBindJSON(p); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return nil, false
 	}
-	c.Data(httpStatus, "application/json", msg.Data)
+
+	return p, true
 }
 
 func (d *delivery) Create(c *gin.Context) {
-	subj := d.subjPrefix + ".create"
-	request, err := ioutil.ReadAll(c.Request.Body)
+	p, ok := bindProduct(c)
+	if !ok {
+		return
+	}
+
+	productCreated, err := d.clientFor(c).Create(p)
 	if err != nil {
-		jsenderrors.ReturnError(c, err.Error())
+		replyError(c, "DLV - Products - Create", err)
 		return
 	}
 
-	method := "DLV - Products - Create"
-	sendRequest(c, d, method, subj, request, http.StatusCreated)
+	setETag(c, productCreated)
+	replySuccess(c, http.StatusCreated, productCreated)
 }
 
 func (d *delivery) GetByID(c *gin.Context) {
-	id := c.Param("id")
-	request := []byte(fmt.Sprintf("{ \"id\": %s }", id))
-	subj := d.subjPrefix + ".getbyid"
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
-	method := "DLV - Products - GetByID"
-	sendRequest(c, d, method, subj, request, http.StatusOK)
+	includeDeleted, err := flagParam(c, "include_deleted")
+	if err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var productRetrieved *product.Product
+	if includeDeleted {
+		productRetrieved, err = d.client.GetByIDIncludeDeleted(id)
+	} else {
+		productRetrieved, err = d.client.GetByID(id)
+	}
+	if err != nil {
+		replyError(c, "DLV - Products - GetByID", err)
+		return
+	}
+
+	// Con price_list, se informa además el precio efectivo del producto en esa lista de precios
+	if priceList := c.Query("price_list"); priceList != "" {
+		productRetrieved.ResolvedPrice, err = d.client.ResolvePrice(productRetrieved.ID, priceList)
+		if err != nil {
+			replyError(c, "DLV - Products - GetByID", err)
+			return
+		}
+	}
+
+	setETag(c, productRetrieved)
+	replySuccess(c, http.StatusOK, productRetrieved)
 }
 
 func (d *delivery) GetByName(c *gin.Context) {
-	name := c.Param("name")
-	request := []byte(fmt.Sprintf("{ \"name\": %s }", name))
-	subj := d.subjPrefix + ".getbyname"
+	productRetrieved, err := d.client.GetByName(c.Param("name"))
+	if err != nil {
+		replyError(c, "DLV - Products - GetByName", err)
+		return
+	}
 
-	method := "DLV - Products - GetByName"
-	sendRequest(c, d, method, subj, request, http.StatusOK)
+	replySuccess(c, http.StatusOK, productRetrieved)
 }
 
 func (d *delivery) GetAll(c *gin.Context) {
-	var request []byte = nil
+	query, err := parseQuery(c)
+	if err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := d.client.GetAll(query)
+	if err != nil {
+		replyError(c, "DLV - Products - GetAll", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, page)
+}
+
+// GetLowStock responde el reporte de productos con stock bajo, con los mismos filtros y paginación que GetAll.
+// Cada producto incluye su umbral y cantidad de reposición.
+func (d *delivery) GetLowStock(c *gin.Context) {
+	query, err := parseQuery(c)
+	if err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	query.LowStock = true
 
-	subj := d.subjPrefix + ".getall"
+	page, err := d.client.GetAll(query)
+	if err != nil {
+		replyError(c, "DLV - Products - GetLowStock", err)
+		return
+	}
 
-	method := "DLV - Products - GetAll"
-	sendRequest(c, d, method, subj, request, http.StatusOK)
+	replySuccess(c, http.StatusOK, page)
 }
 
 func (d *delivery) Update(c *gin.Context) {
-	id := c.Param("id")
-	log.Printf("Updating product with ID: %s", id)
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	log.Printf("Updating product with ID: %d", id)
 
-	subj := d.subjPrefix + ".update"
-	request, err := ioutil.ReadAll(c.Request.Body)
+	p, ok := bindProduct(c)
+	if !ok {
+		return
+	}
+	p.ID = id
+
+	// If-Match tiene prioridad sobre la versión informada en el body
+	version, ifMatch, err := parseIfMatch(c)
 	if err != nil {
-		jsenderrors.ReturnError(c, err.Error())
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if ifMatch {
+		p.Version = version
+	}
 
-	method := "DLV - Products - Update"
-	sendRequest(c, d, method, subj, request, http.StatusOK)
+	productUpdated, err := d.clientFor(c).Update(p)
+	// Sólo la versión desactualizada responde 412: el resto de los conflictos se responden 409
+	if ifMatch && productsclient.IsVersionMismatch(err) {
+		log.Printf("DLV - Products - Update - Precondition failed: %s", err.Error())
+		jsenderrors.ReturnFail(c, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	if err != nil {
+		replyError(c, "DLV - Products - Update", err)
+		return
+	}
+
+	setETag(c, productUpdated)
+	replySuccess(c, http.StatusOK, productUpdated)
 }
 
 func (d *delivery) Delete(c *gin.Context) {
-	id := c.Param("id")
-	request := []byte(fmt.Sprintf("{ \"id\": %s }", id))
-	subj := d.subjPrefix + ".delete"
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
-	method := "DLV - Products - Delete"
-	sendRequest(c, d, method, subj, request, http.StatusOK)
+	// Con hard=true el producto se elimina definitivamente. Si no, es una baja lógica que puede revertirse con Restore
+	hard, err := flagParam(c, "hard")
+	if err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if hard {
+		err = d.clientFor(c).Purge(&product.Product{ID: id})
+	} else {
+		err = d.clientFor(c).Delete(&product.Product{ID: id})
+	}
+	if err != nil {
+		replyError(c, "DLV - Products - Delete", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, nil)
+}
+
+func (d *delivery) Restore(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	productRestored, err := d.clientFor(c).Restore(&product.Product{ID: id})
+	if err != nil {
+		replyError(c, "DLV - Products - Restore", err)
+		return
+	}
+
+	setETag(c, productRestored)
+	replySuccess(c, http.StatusOK, productRestored)
+}
+
+func (d *delivery) History(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	query := &product.HistoryQuery{ProductID: id}
+	var err error
+	if query.Limit, err = intParam(c, "limit"); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.Offset, err = intParam(c, "offset"); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := d.client.History(query)
+	if err != nil {
+		replyError(c, "DLV - Products - History", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, page)
 }
 
 func (d *delivery) UpdateStock(c *gin.Context) {
-	id := c.Param("id")
-	log.Printf("Updating stock for product with ID: %s", id)
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	log.Printf("Updating stock for product with ID: %d", id)
+
+	p, ok := bindProduct(c)
+	if !ok {
+		return
+	}
 
-	subj := d.subjPrefix + ".updatestock"
-	request, err := ioutil.ReadAll(c.Request.Body)
+	productUpdated, err := d.clientFor(c).UpdateStock(id, p.Stock)
 	if err != nil {
-		jsenderrors.ReturnError(c, err.Error())
+		replyError(c, "DLV - Products - UpdateStock", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, productUpdated)
+}
+
+func (d *delivery) AddStockMovement(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	movement := &product.StockMovement{}
+	if err := c.ShouldBindJSON(movement); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	movement.ProductID = id
+
+	productUpdated, err := d.clientFor(c).AddStockMovement(movement)
+	if err != nil {
+		replyError(c, "DLV - Products - AddStockMovement", err)
+		return
+	}
+
+	replySuccess(c, http.StatusCreated, productUpdated)
+}
+
+func (d *delivery) GetStockMovements(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	movements, err := d.client.GetStockMovements(id)
+	if err != nil {
+		replyError(c, "DLV - Products - GetStockMovements", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, movements)
+}
+
+func (d *delivery) Reserve(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	reservation := &product.Reservation{}
+	if err := c.ShouldBindJSON(reservation); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	reservation.ProductID = id
+
+	reservationCreated, err := d.clientFor(c).Reserve(reservation)
+	if err != nil {
+		replyError(c, "DLV - Products - Reserve", err)
+		return
+	}
+
+	replySuccess(c, http.StatusCreated, reservationCreated)
+}
+
+func (d *delivery) GetReservation(c *gin.Context) {
+	reservation, err := d.client.GetReservation(c.Param("id"))
+	if err != nil {
+		replyError(c, "DLV - Products - GetReservation", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, reservation)
+}
+
+func (d *delivery) ConfirmReservation(c *gin.Context) {
+	reservation, err := d.clientFor(c).ConfirmReservation(c.Param("id"))
+	if err != nil {
+		replyError(c, "DLV - Products - ConfirmReservation", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, reservation)
+}
+
+func (d *delivery) ReleaseReservation(c *gin.Context) {
+	reservation, err := d.clientFor(c).ReleaseReservation(c.Param("id"))
+	if err != nil {
+		replyError(c, "DLV - Products - ReleaseReservation", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, reservation)
+}
+
+func (d *delivery) SchedulePrice(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	price := &product.Price{}
+	if err := c.ShouldBindJSON(price); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	price.ProductID = id
+
+	priceScheduled, err := d.clientFor(c).SchedulePrice(price)
+	if err != nil {
+		replyError(c, "DLV - Products - SchedulePrice", err)
+		return
+	}
+
+	replySuccess(c, http.StatusCreated, priceScheduled)
+}
+
+func (d *delivery) GetPrices(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	history, err := d.client.GetPrices(id)
+	if err != nil {
+		replyError(c, "DLV - Products - GetPrices", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, history)
+}
+
+func (d *delivery) CancelPrice(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	price, err := d.clientFor(c).CancelPrice(id)
+	if err != nil {
+		replyError(c, "DLV - Products - CancelPrice", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, price)
+}
+
+// bindCategory decodifica el body del request en una categoría.
+// Si no es válido, responde fail y devuelve false.
+func bindCategory(c *gin.Context) (*product.Category, bool) {
+	category := &product.Category{}
+	if err := c.ShouldBindJSON(category); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	return category, true
+}
+
+func (d *delivery) CreateCategory(c *gin.Context) {
+	category, ok := bindCategory(c)
+	if !ok {
+		return
+	}
+
+	categoryCreated, err := d.clientFor(c).CreateCategory(category)
+	if err != nil {
+		replyError(c, "DLV - Products - CreateCategory", err)
+		return
+	}
+
+	replySuccess(c, http.StatusCreated, categoryCreated)
+}
+
+func (d *delivery) GetCategories(c *gin.Context) {
+	categories, err := d.client.GetCategories()
+	if err != nil {
+		replyError(c, "DLV - Products - GetCategories", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, categories)
+}
+
+func (d *delivery) GetCategory(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	category, err := d.client.GetCategory(id)
+	if err != nil {
+		replyError(c, "DLV - Products - GetCategory", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, category)
+}
+
+func (d *delivery) UpdateCategory(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	category, ok := bindCategory(c)
+	if !ok {
+		return
+	}
+	category.ID = id
+
+	categoryUpdated, err := d.clientFor(c).UpdateCategory(category)
+	if err != nil {
+		replyError(c, "DLV - Products - UpdateCategory", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, categoryUpdated)
+}
+
+func (d *delivery) DeleteCategory(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	err := d.clientFor(c).DeleteCategory(id)
+	if err != nil {
+		replyError(c, "DLV - Products - DeleteCategory", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, nil)
+}
+
+func (d *delivery) SetProductCategories(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	assignment := &product.ProductCategories{}
+	if err := c.ShouldBindJSON(assignment); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	assignment.ProductID = id
+
+	categories, err := d.clientFor(c).SetProductCategories(assignment)
+	if err != nil {
+		replyError(c, "DLV - Products - SetProductCategories", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, categories)
+}
+
+func (d *delivery) GetProductCategories(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	categories, err := d.client.GetProductCategories(id)
+	if err != nil {
+		replyError(c, "DLV - Products - GetProductCategories", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, categories)
+}
+
+// bindVariant decodifica el body del request en una variante del producto indicado en el path.
+// Si no es válido, responde fail y devuelve false.
+func bindVariant(c *gin.Context) (*product.Variant, bool) {
+	productID, ok := paramID(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	variant := &product.Variant{}
+	if err := c.ShouldBindJSON(variant); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	variant.ProductID = productID
+
+	return variant, true
+}
+
+func (d *delivery) CreateVariant(c *gin.Context) {
+	variant, ok := bindVariant(c)
+	if !ok {
+		return
+	}
+
+	variantCreated, err := d.clientFor(c).CreateVariant(variant)
+	if err != nil {
+		replyError(c, "DLV - Products - CreateVariant", err)
+		return
+	}
+
+	replySuccess(c, http.StatusCreated, variantCreated)
+}
+
+func (d *delivery) GetVariants(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	variants, err := d.client.GetVariants(id)
+	if err != nil {
+		replyError(c, "DLV - Products - GetVariants", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, variants)
+}
+
+func (d *delivery) GetVariant(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	variantID, ok := paramID(c, "variant_id")
+	if !ok {
+		return
+	}
+
+	variant, err := d.client.GetVariant(id, variantID)
+	if err != nil {
+		replyError(c, "DLV - Products - GetVariant", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, variant)
+}
+
+func (d *delivery) GetVariantBySKU(c *gin.Context) {
+	variant, err := d.client.GetVariantBySKU(c.Param("sku"))
+	if err != nil {
+		replyError(c, "DLV - Products - GetVariantBySKU", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, variant)
+}
+
+func (d *delivery) UpdateVariant(c *gin.Context) {
+	variantID, ok := paramID(c, "variant_id")
+	if !ok {
+		return
+	}
+	variant, ok := bindVariant(c)
+	if !ok {
+		return
+	}
+	variant.ID = variantID
+
+	variantUpdated, err := d.clientFor(c).UpdateVariant(variant)
+	if err != nil {
+		replyError(c, "DLV - Products - UpdateVariant", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, variantUpdated)
+}
+
+func (d *delivery) DeleteVariant(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	variantID, ok := paramID(c, "variant_id")
+	if !ok {
+		return
+	}
+
+	err := d.clientFor(c).DeleteVariant(id, variantID)
+	if err != nil {
+		replyError(c, "DLV - Products - DeleteVariant", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, nil)
+}
+
+func (d *delivery) CreateLocation(c *gin.Context) {
+	location := &product.Location{}
+	if err := c.ShouldBindJSON(location); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	locationCreated, err := d.clientFor(c).CreateLocation(location)
+	if err != nil {
+		replyError(c, "DLV - Products - CreateLocation", err)
+		return
+	}
+
+	replySuccess(c, http.StatusCreated, locationCreated)
+}
+
+func (d *delivery) GetLocations(c *gin.Context) {
+	locations, err := d.client.GetLocations()
+	if err != nil {
+		replyError(c, "DLV - Products - GetLocations", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, locations)
+}
+
+// GetLocationStock responde el stock de todos los productos en una ubicación
+func (d *delivery) GetLocationStock(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	levels, err := d.client.GetStockLevels(&product.StockLevelQuery{LocationID: id})
+	if err != nil {
+		replyError(c, "DLV - Products - GetLocationStock", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, levels)
+}
+
+// GetProductStock responde el stock de un producto en cada ubicación
+func (d *delivery) GetProductStock(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	levels, err := d.client.GetStockLevels(&product.StockLevelQuery{ProductID: id})
+	if err != nil {
+		replyError(c, "DLV - Products - GetProductStock", err)
+		return
+	}
+
+	replySuccess(c, http.StatusOK, levels)
+}
+
+func (d *delivery) TransferStock(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	transfer := &product.StockTransfer{}
+	if err := c.ShouldBindJSON(transfer); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	transfer.ProductID = id
+
+	movements, err := d.clientFor(c).TransferStock(transfer)
+	if err != nil {
+		replyError(c, "DLV - Products - TransferStock", err)
+		return
+	}
+
+	replySuccess(c, http.StatusCreated, movements)
+}
+
+// ImportProducts importa en forma masiva los productos de un archivo CSV o JSON Lines, que se recibe en el body.
+// El formato se indica con el parámetro format (csv o jsonl) o con el Content-Type, y con dry_run=true se valida
+// el archivo sin modificar nada. Las filas se leen a medida que llegan y se envían al servicio de productos en lotes
+// de ImportBatchSize. Responde el reporte con el resultado de cada fila.
+//
+// Cada lote se importa en su propia transacción. Si falla un lote después de importar otros, los anteriores quedan
+// importados: se responde un error con el reporte parcial y el rango de líneas importadas (partialImport).
+// En modo de prueba cada lote también se valida por separado y se descarta, por lo que un lote no ve los productos
+// que crearían los anteriores: por ejemplo, una fila con SKU de un producto que se crea en un lote anterior se informa
+// como alta del producto, y un nombre repetido en dos lotes se informa como alta en ambos.
+func (d *delivery) ImportProducts(c *gin.Context) {
+	dryRun, err := flagParam(c, "dry_run")
+	if err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reader, err := newRowReader(importFormat(c.Query("format"), c.ContentType()), c.Request.Body)
+	if err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	client := d.clientFor(c)
+	report := &product.ImportReport{DryRun: dryRun, Results: []*product.ImportResult{}}
+	committed := &partialImport{Report: report}
+	batch := []*product.ImportRow{}
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		batchReport, err := client.ImportProducts(&product.ImportRequest{Rows: batch, DryRun: dryRun})
+		if err != nil {
+			return errors.Wrapf(err, "import stopped at line %d", batch[0].Line)
+		}
+		for _, result := range batchReport.Results {
+			report.Add(result)
+		}
+		if !dryRun {
+			if committed.FromLine == 0 {
+				committed.FromLine = batch[0].Line
+			}
+			committed.ToLine = batch[len(batch)-1].Line
+		}
+		batch = []*product.ImportRow{}
+
+		return nil
+	}
+	fail := func(err error) {
+		if committed.ToLine == 0 {
+			replyError(c, "DLV - Products - ImportProducts", err)
+			return
+		}
+		replyPartialImport(c, "DLV - Products - ImportProducts", err, committed)
+	}
+
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var invalid *invalidRow
+		if errors.As(err, &invalid) {
+			report.Add(product.NewImportFailure(invalid.line, invalid.err))
+			continue
+		}
+		if err != nil {
+			fail(errors.Wrap(err, "can't read the import file"))
+			return
+		}
+
+		batch = append(batch, row)
+		if len(batch) == ImportBatchSize {
+			if err := send(); err != nil {
+				fail(err)
+				return
+			}
+		}
+	}
+	if err := send(); err != nil {
+		fail(err)
+		return
+	}
+
+	sortImportResults(report)
+	replySuccess(c, http.StatusOK, report)
+}
+
+// ExportProducts exporta el catálogo completo, con los mismos filtros y orden que GetAll, como archivo CSV (por defecto),
+// JSON Lines o XLSX según el parámetro format. El archivo se envía a medida que se recuperan las páginas del servicio
+// de productos, sin armarlo completo en memoria. El parámetro limit indica la cantidad de productos de cada página.
+func (d *delivery) ExportProducts(c *gin.Context) {
+	query, err := parseQuery(c)
+	if err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", FormatCSV))
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, "invalid format: "+format)
+		return
+	}
+
+	// Los errores de la primera página se informan como en el resto de la API
+	page, err := d.client.ExportProducts(query)
+	if err != nil {
+		replyError(c, "DLV - Products - ExportProducts", err)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="products.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	writer, err := newExportWriter(format, c.Writer)
+	for err == nil {
+		for _, p := range page.Products {
+			if err = writer.Write(p); err != nil {
+				break
+			}
+		}
+		if err != nil || page.NextCursor == "" {
+			break
+		}
+		c.Writer.Flush()
+
+		query.Cursor = page.NextCursor
+		page, err = d.client.ExportProducts(query)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Printf("DLV - Products - ExportProducts - Export aborted: %s", err.Error())
+		abortExport(c)
+	}
+}
+
+// abortExport corta la conexión de una exportación que ya comenzó a enviarse, porque no se puede informar el error
+// en la respuesta. Así el cliente no toma como completo un archivo truncado.
+func abortExport(c *gin.Context) {
+	conn, _, err := c.Writer.Hijack()
+	if err != nil {
+		return
+	}
+	conn.Close()
+}
+
+// Batch ejecuta en un único pedido un lote de altas, modificaciones, bajas y modificaciones de stock de productos,
+// en forma atómica (mode atomic, por defecto) o independiente (mode best_effort). Responde el reporte con el resultado
+// de cada operación, aunque alguna haya fallado.
+func (d *delivery) Batch(c *gin.Context) {
+	request := &product.BatchRequest{}
+	if err := c.ShouldBindJSON(request); err != nil {
+		jsenderrors.ReturnFail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := d.clientFor(c).Batch(request)
+	if err != nil {
+		replyError(c, "DLV - Products - Batch", err)
 		return
 	}
 
-	method := "DLV - Products - UpdateStock"
-	sendRequest(c, d, method, subj, request, http.StatusOK)
+	replySuccess(c, http.StatusOK, report)
 }
@@ -0,0 +1,32 @@
+package products
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/pkg/errors"
+)
+
+// setETag informa la versión del producto en el header ETag de la respuesta
+func setETag(c *gin.Context, p *product.Product) {
+	c.Header("ETag", strconv.Quote(strconv.FormatUint(uint64(p.Version), 10)))
+}
+
+// parseIfMatch obtiene la versión esperada del producto a partir del header If-Match.
+// Devuelve false si el header no está presente o es "*" (cualquier versión).
+func parseIfMatch(c *gin.Context) (uint, bool, error) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, false, nil
+	}
+
+	etag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	version, err := strconv.ParseUint(etag, 10, 0)
+	if err != nil || version == 0 {
+		return 0, false, errors.Errorf("invalid If-Match: %s", ifMatch)
+	}
+
+	return uint(version), true, nil
+}
@@ -0,0 +1,248 @@
+package products
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// FormatXLSX es el formato de planilla de Excel (Office Open XML). Sólo se admite en las exportaciones.
+const FormatXLSX = "xlsx"
+
+// exportContentTypes son los Content-Type de los formatos de exportación
+var exportContentTypes = map[string]string{
+	FormatCSV:   "text/csv; charset=utf-8",
+	FormatJSONL: "application/x-ndjson",
+	FormatXLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// exportColumns son las columnas de los archivos CSV y XLSX exportados. Las que admite la importación tienen el mismo
+// nombre, para que el archivo pueda editarse y volver a importarse quitando las demás.
+var exportColumns = []string{
+	"id", "name", "description", "unit", "price", "currency", "stock", "reserved", "available",
+	"is_active", "reorder_threshold", "reorder_quantity", "version", "deleted_at",
+}
+
+// exportValues devuelve los valores de las columnas exportColumns de un producto
+func exportValues(p *product.Product) []interface{} {
+	deletedAt := ""
+	if p.DeletedAt != nil {
+		deletedAt = p.DeletedAt.UTC().Format(time.RFC3339)
+	}
+
+	return []interface{}{
+		p.ID, p.Name, p.Description, p.Unit, p.Price, p.Currency, p.Stock, p.Reserved, p.Available(),
+		p.IsActive, p.ReorderThreshold, p.ReorderQuantity, p.Version, deletedAt,
+	}
+}
+
+// formatValue convierte a texto un valor de exportValues
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case decimal.Decimal:
+		return v.String()
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
+
+// exportWriter escribe de a uno los productos de un archivo de exportación
+type exportWriter interface {
+	Write(p *product.Product) error // Write agrega un producto al archivo
+	Close() error                   // Close completa el archivo. No cierra el io.Writer en el que se escribe
+}
+
+// newExportWriter crea el escritor del formato indicado. Los formatos con encabezado lo escriben en w.
+func newExportWriter(format string, w io.Writer) (exportWriter, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{writer: writer}, nil
+	case FormatJSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, errors.Errorf("invalid format: %s", format)
+	}
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(p *product.Product) error {
+	values := exportValues(p)
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = formatValue(value)
+	}
+
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// jsonlWriter escribe cada producto en una línea, con la misma representación JSON que el resto de la API
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(p *product.Product) error {
+	return w.encoder.Encode(p)
+}
+
+func (w *jsonlWriter) Close() error {
+	return nil
+}
+
+// Partes fijas de un archivo XLSX con una única hoja, xlsxSheet, cuyo contenido escribe xlsxWriter
+const (
+	xlsxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+	xlsxSheet  = "xl/worksheets/sheet1.xml"
+)
+
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", xlsxHeader +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/` + xlsxSheet + `" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xlsxHeader +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xlsxHeader +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Products" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xlsxHeader +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter escribe un archivo XLSX a medida que recibe los productos: las partes fijas al crearlo,
+// y las filas de la hoja en una entrada del zip que se completa al cerrarlo
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	writer := &xlsxWriter{zip: zip.NewWriter(w)}
+	for _, part := range xlsxParts {
+		entry, err := writer.zip.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if writer.sheet, err = writer.zip.Create(xlsxSheet); err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(writer.sheet, xlsxHeader+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]interface{}, len(exportColumns))
+	for i, column := range exportColumns {
+		header[i] = column
+	}
+	if err := writer.writeRow(header); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+func (w *xlsxWriter) Write(p *product.Product) error {
+	return w.writeRow(exportValues(p))
+}
+
+func (w *xlsxWriter) Close() error {
+	if _, err := io.WriteString(w.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+
+	return w.zip.Close()
+}
+
+// writeRow agrega una fila a la hoja. Los números se escriben como celdas numéricas, para que la planilla pueda operar
+// con ellos, y los textos como inline strings, para no tener que armar la tabla de strings compartidos.
+func (w *xlsxWriter) writeRow(values []interface{}) error {
+	w.row++
+	row := strconv.Itoa(w.row)
+
+	var b strings.Builder
+	b.WriteString(`<row r="` + row + `">`)
+	for i, value := range values {
+		ref := xlsxColumn(i) + row
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+			b.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+			// EscapeText reemplaza los caracteres que no admite XML, por lo que no puede fallar al escribir en memoria
+			_ = xml.EscapeText(&b, []byte(v))
+			b.WriteString(`</t></is></c>`)
+		case bool:
+			flag := "0"
+			if v {
+				flag = "1"
+			}
+			b.WriteString(`<c r="` + ref + `" t="b"><v>` + flag + `</v></c>`)
+		default:
+			b.WriteString(`<c r="` + ref + `"><v>` + formatValue(v) + `</v></c>`)
+		}
+	}
+	b.WriteString(`</row>`)
+
+	_, err := io.WriteString(w.sheet, b.String())
+	return err
+}
+
+// xlsxColumn devuelve el nombre de la columna i (desde 0) de una hoja: A, B, ..., Z, AA, AB, ...
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+
+	return name
+}
@@ -0,0 +1,162 @@
+package products
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/shopspring/decimal"
+)
+
+func TestXLSXColumn(t *testing.T) {
+	tests := []struct {
+		index    int
+		expected string
+	}{
+		{0, "A"},
+		{1, "B"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+	for _, tt := range tests {
+		if column := xlsxColumn(tt.index); column != tt.expected {
+			t.Fatalf("xlsxColumn(%d): expected %s, got %s", tt.index, tt.expected, column)
+		}
+	}
+}
+
+func TestXLSXWriteRow(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []interface{}
+		expected string
+	}{
+		{"escaped text", []interface{}{`<b>"Tom" & Jerry</b>`},
+			`<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">&lt;b&gt;&#34;Tom&#34; &amp; Jerry&lt;/b&gt;</t></is></c></row>`},
+		{"empty text is skipped", []interface{}{"", "x"},
+			`<row r="1"><c r="B1" t="inlineStr"><is><t xml:space="preserve">x</t></is></c></row>`},
+		{"numbers and booleans", []interface{}{uint(7), 2.5, decimal.RequireFromString("10.25"), true, false},
+			`<row r="1"><c r="A1"><v>7</v></c><c r="B1"><v>2.5</v></c><c r="C1"><v>10.25</v></c><c r="D1" t="b"><v>1</v></c><c r="E1" t="b"><v>0</v></c></row>`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			w := &xlsxWriter{sheet: &b}
+			if err := w.writeRow(tt.values); err != nil {
+				t.Fatalf("writeRow: unexpected error: %v", err)
+			}
+			if b.String() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, b.String())
+			}
+		})
+	}
+}
+
+func newExportProduct() *product.Product {
+	deletedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &product.Product{
+		ID:        1,
+		Name:      "Shirt, <large>",
+		Unit:      "unit",
+		Price:     decimal.RequireFromString("20.50"),
+		Currency:  "USD",
+		Stock:     10,
+		Reserved:  4,
+		IsActive:  true,
+		Version:   3,
+		DeletedAt: &deletedAt,
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	var b bytes.Buffer
+	w, err := newExportWriter(FormatCSV, &b)
+	if err != nil {
+		t.Fatalf("newExportWriter: unexpected error: %v", err)
+	}
+	if err := w.Write(newExportProduct()); err != nil {
+		t.Fatalf("Write: unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: unexpected error: %v", err)
+	}
+	if len(records) != 2 || strings.Join(records[0], ",") != strings.Join(exportColumns, ",") {
+		t.Fatalf("unexpected records %v", records)
+	}
+	expected := []string{"1", "Shirt, <large>", "", "unit", "20.5", "USD", "10", "4", "6", "true", "0", "0", "3", "2024-03-01T12:00:00Z"}
+	if strings.Join(records[1], "|") != strings.Join(expected, "|") {
+		t.Fatalf("expected %v, got %v", expected, records[1])
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	var b bytes.Buffer
+	w, err := newExportWriter(FormatXLSX, &b)
+	if err != nil {
+		t.Fatalf("newExportWriter: unexpected error: %v", err)
+	}
+	if err := w.Write(newExportProduct()); err != nil {
+		t.Fatalf("Write: unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: unexpected error: %v", err)
+	}
+	var sheet string
+	names := map[string]bool{}
+	for _, file := range archive.File {
+		names[file.Name] = true
+		if file.Name != xlsxSheet {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("Open: unexpected error: %v", err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll: unexpected error: %v", err)
+		}
+		sheet = string(data)
+	}
+	for _, part := range xlsxParts {
+		if !names[part.name] {
+			t.Fatalf("expected part %s in the file", part.name)
+		}
+	}
+
+	// Encabezado en la fila 1 y el producto en la 2, con la última columna (N) y el texto escapado
+	for _, expected := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">Shirt, &lt;large&gt;</t></is></c>`,
+		`<c r="N2" t="inlineStr"><is><t xml:space="preserve">2024-03-01T12:00:00Z</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Fatalf("expected %s in the sheet, got %s", expected, sheet)
+		}
+	}
+	if !strings.HasSuffix(sheet, `</sheetData></worksheet>`) {
+		t.Fatalf("expected a complete sheet, got %s", sheet)
+	}
+}
@@ -0,0 +1,280 @@
+package products
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/marceloaguero/go-nats-products/products/pkg/productsclient"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Formatos de los archivos de importación y exportación
+const (
+	FormatCSV   = "csv"   // CSV con una fila de encabezado con los nombres de las columnas
+	FormatJSONL = "jsonl" // JSON Lines: un product.ImportRow por línea
+)
+
+// ImportBatchSize es la cantidad de filas que se envían al servicio de productos en cada lote.
+// Cada lote se importa en una transacción.
+const ImportBatchSize = 200
+
+// optionPrefix es el prefijo de las columnas CSV con las opciones de la variante, por ejemplo "option.size"
+const optionPrefix = "option."
+
+// maxJSONLine es el tamaño máximo de una línea de un archivo JSON Lines
+const maxJSONLine = 1024 * 1024
+
+// invalidRow es una fila que no se pudo interpretar. No detiene la importación: se informa como fallida en el reporte.
+type invalidRow struct {
+	line int
+	err  error
+}
+
+func (e *invalidRow) Error() string {
+	return "line " + strconv.Itoa(e.line) + ": " + e.err.Error()
+}
+
+// partialImport es la respuesta de una importación que se interrumpió después de importar algunos lotes:
+// las filas de las líneas FromLine a ToLine quedaron importadas, con el resultado que informa el reporte parcial.
+type partialImport struct {
+	FromLine int                   `json:"from_line"` // Primera línea de los lotes importados
+	ToLine   int                   `json:"to_line"`   // Última línea de los lotes importados
+	Report   *product.ImportReport `json:"report"`    // Reporte de las filas importadas y de las que no se pudieron interpretar
+}
+
+// replyPartialImport responde el error que interrumpió una importación, con las líneas que se importaron antes
+func replyPartialImport(c *gin.Context, method string, err error, partial *partialImport) {
+	log.Printf("%s - Request error after importing lines %d to %d: %s", method, partial.FromLine, partial.ToLine, err.Error())
+
+	httpStatus, ok := errorStatus[productsclient.ErrorCode(err)]
+	if !ok {
+		httpStatus = http.StatusInternalServerError
+	}
+	sortImportResults(partial.Report)
+	c.JSON(httpStatus, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+		"data":    partial,
+	})
+}
+
+// sortImportResults ordena los resultados del reporte por línea: las filas que no se pudieron interpretar
+// se informan sin esperar a su lote
+func sortImportResults(report *product.ImportReport) {
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].Line < report.Results[j].Line
+	})
+}
+
+// rowReader lee de a una las filas de un archivo de importación
+type rowReader interface {
+	// Next devuelve la próxima fila, o io.EOF al terminar. Si la fila no es válida devuelve un *invalidRow,
+	// y se puede seguir leyendo. Cualquier otro error detiene la lectura.
+	Next() (*product.ImportRow, error)
+}
+
+// newRowReader crea el lector de filas del formato indicado
+func newRowReader(format string, r io.Reader) (rowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLine)
+		return &jsonlReader{scanner: scanner}, nil
+	default:
+		return nil, errors.Errorf("invalid format: %s", format)
+	}
+}
+
+// importFormat determina el formato del archivo a partir del parámetro format o, si no se informa, del Content-Type
+func importFormat(format, contentType string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+
+	switch {
+	case strings.Contains(contentType, "ndjson"), strings.Contains(contentType, "jsonl"):
+		return FormatJSONL
+	default:
+		return FormatCSV
+	}
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+// csvColumns son las columnas que admite un archivo CSV, además de las opciones de la variante (optionPrefix)
+var csvColumns = map[string]bool{
+	"name": true, "description": true, "unit": true, "price": true, "currency": true, "stock": true,
+	"is_active": true, "reorder_threshold": true, "reorder_quantity": true, "sku": true,
+}
+
+// newCSVReader lee y valida el encabezado del archivo CSV
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty CSV file")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid CSV header")
+	}
+
+	columns := make([]string, len(header))
+	hasName := false
+	for i, column := range header {
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff") // BOM de UTF-8
+		}
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !csvColumns[column] && !(strings.HasPrefix(column, optionPrefix) && len(column) > len(optionPrefix)) {
+			return nil, errors.Errorf("unknown CSV column: %s", column)
+		}
+		hasName = hasName || column == "name"
+		columns[i] = column
+	}
+	if !hasName {
+		return nil, errors.New("the CSV file must have a name column")
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvReader) Next() (*product.ImportRow, error) {
+	record, err := r.reader.Read()
+	var parseError *csv.ParseError
+	if errors.As(err, &parseError) {
+		return nil, &invalidRow{line: parseError.StartLine, err: &product.ValidationError{Message: parseError.Err.Error()}}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	row := &product.ImportRow{Line: line}
+	for i, value := range record {
+		column := r.columns[i]
+		value = strings.TrimSpace(value)
+		if err := setColumn(row, column, value); err != nil {
+			return nil, &invalidRow{line: line, err: &product.ValidationError{
+				Message: "invalid " + column + ": " + value,
+				Fields:  map[string]string{column: err.Error()},
+			}}
+		}
+	}
+
+	return row, nil
+}
+
+// setColumn asigna a la fila el valor de una columna CSV. Los valores vacíos de las columnas opcionales no se asignan,
+// por lo que al actualizar un producto esos atributos conservan su valor. Si el valor no es válido, devuelve la regla que no cumple.
+func setColumn(row *product.ImportRow, column, value string) error {
+	if strings.HasPrefix(column, optionPrefix) {
+		if value != "" {
+			if row.Options == nil {
+				row.Options = map[string]string{}
+			}
+			row.Options[strings.TrimPrefix(column, optionPrefix)] = value
+		}
+		return nil
+	}
+
+	switch column {
+	case "name":
+		row.Name = value
+	case "sku":
+		row.SKU = value
+	case "description", "unit", "currency":
+		if value == "" {
+			return nil
+		}
+		switch column {
+		case "description":
+			row.Description = &value
+		case "unit":
+			row.Unit = &value
+		default:
+			row.Currency = &value
+		}
+	case "price":
+		if value == "" {
+			return nil
+		}
+		price, err := decimal.NewFromString(value)
+		if err != nil {
+			return errors.New("decimal")
+		}
+		row.Price = &price
+	case "is_active":
+		if value == "" {
+			return nil
+		}
+		isActive, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("boolean")
+		}
+		row.IsActive = &isActive
+	case "stock", "reorder_threshold", "reorder_quantity":
+		if value == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("number")
+		}
+		switch column {
+		case "stock":
+			row.Stock = &f
+		case "reorder_threshold":
+			row.ReorderThreshold = &f
+		default:
+			row.ReorderQuantity = &f
+		}
+	}
+
+	return nil
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlReader) Next() (*product.ImportRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := &product.ImportRow{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(row); err != nil {
+			return nil, &invalidRow{line: r.line, err: &product.ValidationError{Message: "invalid JSON: " + err.Error()}}
+		}
+		row.Line = r.line
+
+		return row, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
@@ -0,0 +1,182 @@
+package products
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+)
+
+func TestCSVHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		columns []string
+		err     string
+	}{
+		{"empty file", "", nil, "empty CSV file"},
+		{"missing name", "unit,price\n", nil, "the CSV file must have a name column"},
+		{"unknown column", "name,colour\n", nil, "unknown CSV column: colour"},
+		{"empty option", "name,option.\n", nil, "unknown CSV column: option."},
+		{"normalized columns", " Name ,PRICE\n", []string{"name", "price"}, ""},
+		{"byte order mark", "\ufeffname,unit\n", []string{"name", "unit"}, ""},
+		{"option columns", "name,sku,option.size,Option.Color\n", []string{"name", "sku", "option.size", "option.color"}, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := newCSVReader(strings.NewReader(tt.input))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newCSVReader: unexpected error: %v", err)
+			}
+			if strings.Join(reader.columns, ",") != strings.Join(tt.columns, ",") {
+				t.Fatalf("expected columns %v, got %v", tt.columns, reader.columns)
+			}
+		})
+	}
+}
+
+func TestCSVRows(t *testing.T) {
+	input := "name,description,unit,price,stock,is_active,sku,option.size,option.color\n" +
+		"shirt,\"a long\ndescription\",unit,20.50,3,true,shirt-m,M,\n" +
+		"pants,,,abc,,,,,\n" +
+		"hat,,,,-,,,,\n" +
+		"socks,,,,,maybe,,,\n" +
+		"belt,,box,,,,,,\n"
+
+	reader, err := newCSVReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("newCSVReader: unexpected error: %v", err)
+	}
+
+	row, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	if row.Line != 2 || row.Name != "shirt" || row.SKU != "shirt-m" || row.Price == nil || row.Price.String() != "20.5" {
+		t.Fatalf("unexpected row %+v", row)
+	}
+	if row.Description == nil || *row.Description != "a long\ndescription" || row.Stock == nil || *row.Stock != 3 || row.IsActive == nil || !*row.IsActive {
+		t.Fatalf("unexpected row %+v", row)
+	}
+	// Las opciones vacías no se asignan
+	if len(row.Options) != 1 || row.Options["size"] != "M" {
+		t.Fatalf("expected only the size option, got %v", row.Options)
+	}
+
+	// El número de línea tiene en cuenta los campos de varias líneas
+	tests := []struct {
+		line  int
+		field string
+		rule  string
+	}{
+		{4, "price", "decimal"},
+		{5, "stock", "number"},
+		{6, "is_active", "boolean"},
+	}
+	for _, tt := range tests {
+		_, err := reader.Next()
+		var invalid *invalidRow
+		if !errors.As(err, &invalid) {
+			t.Fatalf("expected an invalid row, got %v", err)
+		}
+		if invalid.line != tt.line || product.ErrorFields(invalid.err)[tt.field] != tt.rule {
+			t.Fatalf("expected line %d with %s=%s, got line %d: %v", tt.line, tt.field, tt.rule, invalid.line, invalid.err)
+		}
+	}
+
+	// Los valores vacíos no se asignan, para que no se modifiquen
+	row, err = reader.Next()
+	if err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	if row.Line != 7 || row.Unit == nil || *row.Unit != "box" || row.Description != nil || row.Price != nil || row.Stock != nil {
+		t.Fatalf("unexpected row %+v", row)
+	}
+
+	if _, err := reader.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestCSVParseError(t *testing.T) {
+	reader, err := newCSVReader(strings.NewReader("name,unit\nshirt,unit\n\"pants,unit\n"))
+	if err != nil {
+		t.Fatalf("newCSVReader: unexpected error: %v", err)
+	}
+	if _, err := reader.Next(); err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+
+	_, err = reader.Next()
+	var invalid *invalidRow
+	if !errors.As(err, &invalid) || invalid.line != 3 || product.ErrorCode(invalid.err) != product.CodeValidation {
+		t.Fatalf("expected an invalid row at line 3, got %v", err)
+	}
+}
+
+func TestJSONLRows(t *testing.T) {
+	input := `{"name": "shirt", "unit": "unit", "price": "20.5", "sku": "shirt-m", "options": {"size": "M"}}` + "\n" +
+		"\n" +
+		`{"name": "pants", "colour": "blue"}` + "\n" +
+		`{"name": "hat"}`
+
+	reader, err := newRowReader(FormatJSONL, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("newRowReader: unexpected error: %v", err)
+	}
+
+	row, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	if row.Line != 1 || row.Name != "shirt" || row.Price == nil || row.Price.String() != "20.5" || row.Options["size"] != "M" {
+		t.Fatalf("unexpected row %+v", row)
+	}
+
+	// Las líneas vacías se saltean, pero cuentan para el número de línea
+	_, err = reader.Next()
+	var invalid *invalidRow
+	if !errors.As(err, &invalid) || invalid.line != 3 {
+		t.Fatalf("expected an invalid row at line 3, got %v", err)
+	}
+
+	row, err = reader.Next()
+	if err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	if row.Line != 4 || row.Name != "hat" || row.Unit != nil {
+		t.Fatalf("unexpected row %+v", row)
+	}
+
+	if _, err := reader.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestImportFormat(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+		expected    string
+	}{
+		{"", "text/csv", FormatCSV},
+		{"", "application/x-ndjson", FormatJSONL},
+		{"", "application/jsonl", FormatJSONL},
+		{"", "", FormatCSV},
+		{"JSONL", "text/csv", FormatJSONL},
+	}
+	for _, tt := range tests {
+		if format := importFormat(tt.format, tt.contentType); format != tt.expected {
+			t.Fatalf("importFormat(%q, %q): expected %s, got %s", tt.format, tt.contentType, tt.expected, format)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package products
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// parseQuery arma un product.Query a partir de los query parameters del request:
+// limit, offset, cursor, sort_by, sort_order, is_active, min_price, max_price, min_stock, max_stock, name_prefix, include_deleted, category_id y low_stock
+func parseQuery(c *gin.Context) (*product.Query, error) {
+	query := &product.Query{
+		Cursor:     c.Query("cursor"),
+		SortBy:     c.Query("sort_by"),
+		SortOrder:  c.Query("sort_order"),
+		NamePrefix: c.Query("name_prefix"),
+	}
+
+	var err error
+	if query.Limit, err = intParam(c, "limit"); err != nil {
+		return nil, err
+	}
+	if query.Offset, err = intParam(c, "offset"); err != nil {
+		return nil, err
+	}
+	if query.IsActive, err = boolParam(c, "is_active"); err != nil {
+		return nil, err
+	}
+	if query.MinPrice, err = decimalParam(c, "min_price"); err != nil {
+		return nil, err
+	}
+	if query.MaxPrice, err = decimalParam(c, "max_price"); err != nil {
+		return nil, err
+	}
+	if query.MinStock, err = floatParam(c, "min_stock"); err != nil {
+		return nil, err
+	}
+	if query.MaxStock, err = floatParam(c, "max_stock"); err != nil {
+		return nil, err
+	}
+	if query.IncludeDeleted, err = flagParam(c, "include_deleted"); err != nil {
+		return nil, err
+	}
+	if query.CategoryID, err = idParam(c, "category_id"); err != nil {
+		return nil, err
+	}
+	if query.LowStock, err = flagParam(c, "low_stock"); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+func intParam(c *gin.Context, name string) (int, error) {
+	value, ok := c.GetQuery(name)
+	if !ok || value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Errorf("invalid %s: %s", name, value)
+	}
+
+	return i, nil
+}
+
+// idParam lee un ID opcional, nil si no se informa
+func idParam(c *gin.Context, name string) (*uint, error) {
+	value, ok := c.GetQuery(name)
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	i, err := strconv.ParseUint(value, 10, 0)
+	if err != nil {
+		return nil, errors.Errorf("invalid %s: %s", name, value)
+	}
+
+	id := uint(i)
+	return &id, nil
+}
+
+func boolParam(c *gin.Context, name string) (*bool, error) {
+	value, ok := c.GetQuery(name)
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.Errorf("invalid %s: %s", name, value)
+	}
+
+	return &b, nil
+}
+
+// flagParam lee un parámetro booleano opcional, false si no se informa
+func flagParam(c *gin.Context, name string) (bool, error) {
+	b, err := boolParam(c, name)
+	if err != nil || b == nil {
+		return false, err
+	}
+
+	return *b, nil
+}
+
+func floatParam(c *gin.Context, name string) (*float64, error) {
+	value, ok := c.GetQuery(name)
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, errors.Errorf("invalid %s: %s", name, value)
+	}
+
+	return &f, nil
+}
+
+// decimalParam lee un parámetro numérico exacto, como los precios
+func decimalParam(c *gin.Context, name string) (*decimal.Decimal, error) {
+	value, ok := c.GetQuery(name)
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, errors.Errorf("invalid %s: %s", name, value)
+	}
+
+	return &d, nil
+}
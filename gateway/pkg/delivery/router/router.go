@@ -27,18 +27,104 @@ func NewRouter(productsDelivery products.Delivery, pathPrefix string) (*router,
 	{
 		// Crear un nuevo producto
 		products.POST("/", router.productsDelivery.Create)
+		// Importar productos en forma masiva desde un archivo CSV o JSON Lines (con ?dry_run=true sólo se valida)
+		products.POST("/import", router.productsDelivery.ImportProducts)
+		// Ejecutar un lote de altas, modificaciones, bajas y modificaciones de stock (atómico o best effort)
+		products.POST("/batch", router.productsDelivery.Batch)
 		// Recuperar todos los productos
 		products.GET("/", router.productsDelivery.GetAll)
+		// Recuperar los productos con stock bajo (disponible en su umbral de reposición o por debajo)
+		products.GET("/low-stock", router.productsDelivery.GetLowStock)
+		// Exportar el catálogo completo, con los mismos filtros que el listado, en formato CSV, JSON Lines o XLSX (?format=)
+		products.GET("/export", router.productsDelivery.ExportProducts)
 		// Recuperar un producto por su ID
 		products.GET("/:id", router.productsDelivery.GetByID)
 		// Recuperar producto por nombre
 		products.GET("/names/:name", router.productsDelivery.GetByName)
 		// Modificar un producto
 		products.PUT("/:id", router.productsDelivery.Update)
-		// Eliminar un producto
+		// Eliminar un producto (baja lógica, o definitiva con ?hard=true)
 		products.DELETE("/:id", router.productsDelivery.Delete)
+		// Restaurar un producto eliminado
+		products.POST("/:id/restore", router.productsDelivery.Restore)
+		// Recuperar el historial de cambios de un producto (paginado con limit y offset)
+		products.GET("/:id/history", router.productsDelivery.History)
 		// Actualizar el stock de un producto
 		products.PUT("/:id/updatestock", router.productsDelivery.UpdateStock)
+		// Registrar un movimiento de stock de un producto
+		products.POST("/:id/movements", router.productsDelivery.AddStockMovement)
+		// Recuperar los movimientos de stock de un producto
+		products.GET("/:id/movements", router.productsDelivery.GetStockMovements)
+		// Reservar stock de un producto
+		products.POST("/:id/reservations", router.productsDelivery.Reserve)
+		// Programar un cambio de precio de un producto
+		products.POST("/:id/prices", router.productsDelivery.SchedulePrice)
+		// Recuperar los precios pasados y programados de un producto
+		products.GET("/:id/prices", router.productsDelivery.GetPrices)
+		// Reemplazar las categorías de un producto
+		products.PUT("/:id/categories", router.productsDelivery.SetProductCategories)
+		// Recuperar las categorías de un producto
+		products.GET("/:id/categories", router.productsDelivery.GetProductCategories)
+		// Agregar una variante a un producto
+		products.POST("/:id/variants", router.productsDelivery.CreateVariant)
+		// Recuperar las variantes de un producto
+		products.GET("/:id/variants", router.productsDelivery.GetVariants)
+		// Recuperar una variante de un producto
+		products.GET("/:id/variants/:variant_id", router.productsDelivery.GetVariant)
+		// Modificar una variante de un producto (salvo el stock, que se modifica con movimientos que indican variant_id)
+		products.PUT("/:id/variants/:variant_id", router.productsDelivery.UpdateVariant)
+		// Eliminar una variante sin stock
+		products.DELETE("/:id/variants/:variant_id", router.productsDelivery.DeleteVariant)
+		// Recuperar el stock de un producto en cada ubicación
+		products.GET("/:id/stock", router.productsDelivery.GetProductStock)
+		// Transferir stock de un producto entre dos ubicaciones
+		products.POST("/:id/transfers", router.productsDelivery.TransferStock)
+	}
+
+	variants := r.Group("/variants")
+	{
+		// Recuperar una variante por SKU
+		variants.GET("/skus/:sku", router.productsDelivery.GetVariantBySKU)
+	}
+
+	reservations := r.Group("/reservations")
+	{
+		// Recuperar una reserva por su ID
+		reservations.GET("/:id", router.productsDelivery.GetReservation)
+		// Confirmar una reserva, descontando el stock reservado
+		reservations.POST("/:id/confirm", router.productsDelivery.ConfirmReservation)
+		// Liberar una reserva, devolviendo el stock reservado
+		reservations.POST("/:id/release", router.productsDelivery.ReleaseReservation)
+	}
+
+	prices := r.Group("/prices")
+	{
+		// Cancelar un cambio de precio programado
+		prices.DELETE("/:id", router.productsDelivery.CancelPrice)
+	}
+
+	categories := r.Group("/categories")
+	{
+		// Crear una categoría, raíz o subcategoría de parent_id
+		categories.POST("/", router.productsDelivery.CreateCategory)
+		// Recuperar todas las categorías, ordenadas por camino
+		categories.GET("/", router.productsDelivery.GetCategories)
+		// Recuperar una categoría por su ID
+		categories.GET("/:id", router.productsDelivery.GetCategory)
+		// Modificar una categoría, o moverla a otra categoría padre junto con sus subcategorías
+		categories.PUT("/:id", router.productsDelivery.UpdateCategory)
+		// Eliminar una categoría sin subcategorías
+		categories.DELETE("/:id", router.productsDelivery.DeleteCategory)
+	}
+
+	locations := r.Group("/locations")
+	{
+		// Crear una ubicación de stock (depósito, tienda, etc.)
+		locations.POST("/", router.productsDelivery.CreateLocation)
+		// Recuperar todas las ubicaciones, ordenadas por código
+		locations.GET("/", router.productsDelivery.GetLocations)
+		// Recuperar el stock de todos los productos en una ubicación
+		locations.GET("/:id/stock", router.productsDelivery.GetLocationStock)
 	}
 
 	err := r.Run()
@@ -1,29 +1,77 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/marceloaguero/go-nats-products/products/pkg/delivery"
+	"github.com/marceloaguero/go-nats-products/products/pkg/events"
+	"github.com/marceloaguero/go-nats-products/products/pkg/outbox"
 	"github.com/marceloaguero/go-nats-products/products/pkg/product"
 	repo "github.com/marceloaguero/go-nats-products/products/pkg/repository"
+	"github.com/marceloaguero/go-nats-products/products/pkg/repository/memory"
+	"github.com/marceloaguero/go-nats-products/products/pkg/worker"
+)
+
+const (
+	outboxInterval       = time.Second      // Frecuencia con que se publican los eventos pendientes del outbox
+	pruneInterval        = time.Hour        // Frecuencia con que se eliminan del outbox los eventos publicados antiguos
+	reservationsInterval = 30 * time.Second // Frecuencia con que se liberan las reservas vencidas
+	pricesInterval       = 10 * time.Second // Frecuencia con que se aplican los cambios de precio programados
 )
 
 func main() {
+	// server migrate ... aplica o revierte las migraciones del esquema y termina
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	dbDriver := os.Getenv("DB_DRIVER")
 	dbDsn := os.Getenv("DB_DSN")
 	dbName := os.Getenv("DB_NAME")
 	natsURLs := os.Getenv("NATS_URLS")
 	subjPrefix := os.Getenv("SUBJ_PREFIX")
 	queue := os.Getenv("QUEUE")
 
-	repository, err := repo.NewRepo(dbDsn, dbName)
+	repository, err := newRepository(dbDriver, dbDsn, dbName)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	publisher, err := events.NewPublisher(natsURLs)
 	if err != nil {
 		log.Panic(err)
 	}
 
+	// Publish the events recorded in the outbox
+	relay := outbox.NewRelay(repository, publisher, outbox.DefaultBatchSize)
+	relayWorker := worker.New("Outbox relay", outboxInterval, relay.Relay)
+	relayWorker.Start()
+	pruneWorker := worker.New("Outbox pruning", pruneInterval, relay.Prune)
+	pruneWorker.Start()
+
 	usecase := product.NewUsecase(repository)
 
+	// Release the expired stock reservations
+	sweeperWorker := worker.New("Reservations sweeper", reservationsInterval, func() error {
+		_, err := usecase.ExpireReservations()
+		return err
+	})
+	sweeperWorker.Start()
+
+	// Apply the scheduled price changes
+	pricesWorker := worker.New("Price scheduler", pricesInterval, func() error {
+		_, err := usecase.ApplyScheduledPrices()
+		return err
+	})
+	pricesWorker.Start()
+
 	delivery, err := delivery.NewDelivery(usecase, natsURLs, subjPrefix, queue)
 	if err != nil {
 		log.Panic(err)
@@ -36,5 +84,29 @@ func main() {
 	<-c
 	log.Printf("Draining...")
 	delivery.Drain()
+	pricesWorker.Stop()
+	sweeperWorker.Stop()
+	pruneWorker.Stop()
+	relayWorker.Stop()
+	publisher.Drain()
 	log.Fatalf("Exiting")
 }
+
+// newRepository crea el repositorio del driver indicado. Por defecto, MySQL.
+// Con el driver postgres, dsn es la cadena de conexión completa y no se utiliza dbName.
+// Con el driver sqlite, dsn es el archivo de la base de datos (por ejemplo "file:products.db") y no se utiliza dbName.
+// El driver memory no persiste los datos y permite ejecutar el servicio sin una base de datos.
+func newRepository(driver, dsn, dbName string) (product.Store, error) {
+	switch driver {
+	case "", "mysql":
+		return repo.NewRepo(dsn, dbName)
+	case "postgres":
+		return repo.NewPostgresRepo(dsn)
+	case "sqlite":
+		return repo.NewSQLiteRepo(dsn)
+	case "memory":
+		return memory.NewRepo(), nil
+	}
+
+	return nil, fmt.Errorf("Unknown DB_DRIVER %q", driver)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	repo "github.com/marceloaguero/go-nats-products/products/pkg/repository"
+	"github.com/marceloaguero/go-nats-products/products/pkg/repository/migrations"
+)
+
+const migrateUsage = `Usage: server migrate [-steps n] [-dry-run] up|down|status
+
+Applies or reverts the versioned schema migrations of the database configured with DB_DRIVER, DB_DSN and DB_NAME.
+  up      applies the pending migrations (all of them by default)
+  down    reverts the last applied migration (or the last n with -steps, all of them with -steps 0)
+  status  lists the migrations and whether they are applied
+`
+
+// migrate ejecuta el subcomando migrate
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+	steps := flags.Int("steps", -1, "number of migrations to apply or revert, 0 for all of them (-1: all for up, 1 for down)")
+	dryRun := flags.Bool("dry-run", false, "print the SQL statements without executing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("Migrate - Expected one command, got %d", flags.NArg())
+	}
+
+	migrator, err := repo.NewMigrator(os.Getenv("DB_DRIVER"), os.Getenv("DB_DSN"), os.Getenv("DB_NAME"))
+	if err != nil {
+		return err
+	}
+
+	var result []*migrations.Migration
+	switch command := flags.Arg(0); command {
+	case "status":
+		result, err = migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, m := range result {
+			status := "pending"
+			if m.AppliedAt != nil {
+				status = "applied " + m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d %-40s %s\n", m.Version, m.Name, status)
+		}
+		return nil
+	case "up":
+		if *steps < 0 {
+			*steps = 0
+		}
+		result, err = migrator.Up(*steps, *dryRun)
+	case "down":
+		if *steps < 0 {
+			*steps = 1
+		}
+		result, err = migrator.Down(*steps, *dryRun)
+	default:
+		flags.Usage()
+		return fmt.Errorf("Migrate - Unknown command %q", command)
+	}
+	if err != nil {
+		return err
+	}
+
+	action := map[string]string{"up": "Applied", "down": "Reverted"}[flags.Arg(0)]
+	if *dryRun {
+		action = map[string]string{"up": "Would apply", "down": "Would revert"}[flags.Arg(0)]
+	}
+	if len(result) == 0 {
+		fmt.Println("No migrations to run")
+	}
+	for _, m := range result {
+		fmt.Printf("%s %04d %s\n", action, m.Version, m.Name)
+		if !*dryRun {
+			continue
+		}
+
+		statements := m.Up
+		if flags.Arg(0) == "down" {
+			statements = m.Down
+		}
+		fmt.Println(indent(strings.Join(statements, "\n\n")))
+	}
+
+	return nil
+}
+
+// indent sangra cada línea de s, para distinguir las sentencias SQL en la salida
+func indent(s string) string {
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ")
+}
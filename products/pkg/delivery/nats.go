@@ -6,6 +6,7 @@ import (
 
 	"clevergo.tech/jsend"
 	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/marceloaguero/go-nats-products/products/pkg/subjects"
 	"github.com/nats-io/nats.go"
 )
 
@@ -44,48 +45,194 @@ func (d *delivery) Drain() {
 func Subscribe(delivery *delivery, nc *nats.Conn, subjPrefix string, queue string) error {
 	var s string
 
-	s = subjPrefix + ".create"
+	s = subjPrefix + subjects.Create
 	_, err := nc.QueueSubscribe(s, queue, delivery.Create)
 
-	s = subjPrefix + ".getbyid"
+	s = subjPrefix + subjects.GetByID
 	_, err = nc.QueueSubscribe(s, queue, delivery.GetByID)
 
-	s = subjPrefix + ".getbyname"
+	s = subjPrefix + subjects.GetByName
 	_, err = nc.QueueSubscribe(s, queue, delivery.GetByName)
 
-	s = subjPrefix + ".getall"
+	s = subjPrefix + subjects.GetAll
 	_, err = nc.QueueSubscribe(s, queue, delivery.GetAll)
 
-	s = subjPrefix + ".update"
+	s = subjPrefix + subjects.Update
 	_, err = nc.QueueSubscribe(s, queue, delivery.Update)
 
-	s = subjPrefix + ".delete"
+	s = subjPrefix + subjects.Delete
 	_, err = nc.QueueSubscribe(s, queue, delivery.Delete)
 
-	s = subjPrefix + ".updatestock"
+	s = subjPrefix + subjects.Restore
+	_, err = nc.QueueSubscribe(s, queue, delivery.Restore)
+
+	s = subjPrefix + subjects.Purge
+	_, err = nc.QueueSubscribe(s, queue, delivery.Purge)
+
+	s = subjPrefix + subjects.UpdateStock
 	_, err = nc.QueueSubscribe(s, queue, delivery.UpdateStock)
 
+	s = subjPrefix + subjects.History
+	_, err = nc.QueueSubscribe(s, queue, delivery.History)
+
+	s = subjPrefix + subjects.AddStockMovement
+	_, err = nc.QueueSubscribe(s, queue, delivery.AddStockMovement)
+
+	s = subjPrefix + subjects.GetStockMovements
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetStockMovements)
+
+	s = subjPrefix + subjects.Reserve
+	_, err = nc.QueueSubscribe(s, queue, delivery.Reserve)
+
+	s = subjPrefix + subjects.GetReservation
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetReservation)
+
+	s = subjPrefix + subjects.Confirm
+	_, err = nc.QueueSubscribe(s, queue, delivery.ConfirmReservation)
+
+	s = subjPrefix + subjects.Release
+	_, err = nc.QueueSubscribe(s, queue, delivery.ReleaseReservation)
+
+	s = subjPrefix + subjects.SchedulePrice
+	_, err = nc.QueueSubscribe(s, queue, delivery.SchedulePrice)
+
+	s = subjPrefix + subjects.CancelPrice
+	_, err = nc.QueueSubscribe(s, queue, delivery.CancelPrice)
+
+	s = subjPrefix + subjects.GetPrices
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetPrices)
+
+	s = subjPrefix + subjects.CreatePriceList
+	_, err = nc.QueueSubscribe(s, queue, delivery.CreatePriceList)
+
+	s = subjPrefix + subjects.GetPriceList
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetPriceList)
+
+	s = subjPrefix + subjects.GetPriceLists
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetPriceLists)
+
+	s = subjPrefix + subjects.DeletePriceList
+	_, err = nc.QueueSubscribe(s, queue, delivery.DeletePriceList)
+
+	s = subjPrefix + subjects.UpdateListPrices
+	_, err = nc.QueueSubscribe(s, queue, delivery.UpdateListPrices)
+
+	s = subjPrefix + subjects.GetListPrices
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetListPrices)
+
+	s = subjPrefix + subjects.ResolvePrice
+	_, err = nc.QueueSubscribe(s, queue, delivery.ResolvePrice)
+
+	s = subjPrefix + subjects.CreateCategory
+	_, err = nc.QueueSubscribe(s, queue, delivery.CreateCategory)
+
+	s = subjPrefix + subjects.GetCategory
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetCategory)
+
+	s = subjPrefix + subjects.GetCategories
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetCategories)
+
+	s = subjPrefix + subjects.UpdateCategory
+	_, err = nc.QueueSubscribe(s, queue, delivery.UpdateCategory)
+
+	s = subjPrefix + subjects.DeleteCategory
+	_, err = nc.QueueSubscribe(s, queue, delivery.DeleteCategory)
+
+	s = subjPrefix + subjects.SetProductCategories
+	_, err = nc.QueueSubscribe(s, queue, delivery.SetProductCategories)
+
+	s = subjPrefix + subjects.GetProductCategories
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetProductCategories)
+
+	s = subjPrefix + subjects.CreateVariant
+	_, err = nc.QueueSubscribe(s, queue, delivery.CreateVariant)
+
+	s = subjPrefix + subjects.GetVariant
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetVariant)
+
+	s = subjPrefix + subjects.GetVariantBySKU
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetVariantBySKU)
+
+	s = subjPrefix + subjects.GetVariants
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetVariants)
+
+	s = subjPrefix + subjects.UpdateVariant
+	_, err = nc.QueueSubscribe(s, queue, delivery.UpdateVariant)
+
+	s = subjPrefix + subjects.DeleteVariant
+	_, err = nc.QueueSubscribe(s, queue, delivery.DeleteVariant)
+
+	s = subjPrefix + subjects.CreateLocation
+	_, err = nc.QueueSubscribe(s, queue, delivery.CreateLocation)
+
+	s = subjPrefix + subjects.GetLocations
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetLocations)
+
+	s = subjPrefix + subjects.GetStockLevels
+	_, err = nc.QueueSubscribe(s, queue, delivery.GetStockLevels)
+
+	s = subjPrefix + subjects.TransferStock
+	_, err = nc.QueueSubscribe(s, queue, delivery.TransferStock)
+
+	s = subjPrefix + subjects.ImportProducts
+	_, err = nc.QueueSubscribe(s, queue, delivery.ImportProducts)
+
+	s = subjPrefix + subjects.ExportProducts
+	_, err = nc.QueueSubscribe(s, queue, delivery.ExportProducts)
+
+	s = subjPrefix + subjects.Batch
+	_, err = nc.QueueSubscribe(s, queue, delivery.Batch)
+
 	return err
 }
 
-func JsendFailReply(d *delivery, msg *nats.Msg, errMsg string) {
-	jsendFailReply := jsend.NewFail(errMsg)
+// FailData es el contenido de una respuesta fail o error: el código que identifica el tipo de error
+// (ver product.ErrorCode), el mensaje y, en los errores de validación, el detalle por atributo
+type FailData struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// JsendFailReply responde con el error err. Las fallas internas se responden con status error,
+// y el resto (datos inválidos, producto inexistente, etc.) con status fail
+func JsendFailReply(d *delivery, msg *nats.Msg, err error) {
+	failData := &FailData{
+		Code:    product.ErrorCode(err),
+		Message: err.Error(),
+		Fields:  product.ErrorFields(err),
+	}
+
+	jsendFailReply := jsend.NewFail(failData)
+	if failData.Code == product.CodeInternal {
+		jsendFailReply = jsend.NewError(failData.Message, 0, failData)
+	}
 	failReply, _ := json.Marshal(&jsendFailReply)
 
 	d.nc.Publish(msg.Reply, failReply)
 }
 
+// JsendInvalidRequestReply responde fail a un pedido cuyo contenido no se puede decodificar
+func JsendInvalidRequestReply(d *delivery, msg *nats.Msg, err error) {
+	JsendFailReply(d, msg, &product.ValidationError{Message: "invalid request: " + err.Error()})
+}
+
+// usecaseFor devuelve los casos de uso que registran como autor de los cambios al actor informado en el pedido
+func (d *delivery) usecaseFor(msg *nats.Msg) product.Usecase {
+	return d.usecase.WithActor(msg.Header.Get(subjects.ActorHeader))
+}
+
 func (d *delivery) Create(msg *nats.Msg) {
 	product := &product.Product{}
 	err := json.Unmarshal(msg.Data, &product)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendInvalidRequestReply(d, msg, err)
 		return
 	}
 
-	productCreated, err := d.usecase.Create(product)
+	productCreated, err := d.usecaseFor(msg).Create(product)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -93,7 +240,7 @@ func (d *delivery) Create(msg *nats.Msg) {
 	reply, err := json.Marshal(&jsendReply)
 	if err != nil {
 		log.Println("DLV - Create - Can't marshal jsend reply")
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -101,16 +248,21 @@ func (d *delivery) Create(msg *nats.Msg) {
 }
 
 func (d *delivery) GetByID(msg *nats.Msg) {
-	product := &product.Product{}
-	err := json.Unmarshal(msg.Data, &product)
+	query := &product.ByIDQuery{}
+	err := json.Unmarshal(msg.Data, &query)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendInvalidRequestReply(d, msg, err)
 		return
 	}
 
-	productRetrieved, err := d.usecase.GetByID(product.ID)
+	var productRetrieved *product.Product
+	if query.IncludeDeleted {
+		productRetrieved, err = d.usecase.GetByIDIncludeDeleted(query.ID)
+	} else {
+		productRetrieved, err = d.usecase.GetByID(query.ID)
+	}
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -118,7 +270,7 @@ func (d *delivery) GetByID(msg *nats.Msg) {
 	reply, err := json.Marshal(&jsendReply)
 	if err != nil {
 		log.Println("DLV - GetByID - Can't marshal jsend reply")
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -129,13 +281,13 @@ func (d *delivery) GetByName(msg *nats.Msg) {
 	product := &product.Product{}
 	err := json.Unmarshal(msg.Data, &product)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendInvalidRequestReply(d, msg, err)
 		return
 	}
 
 	productRetrieved, err := d.usecase.GetByName(product.Name)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -143,7 +295,7 @@ func (d *delivery) GetByName(msg *nats.Msg) {
 	reply, err := json.Marshal(&jsendReply)
 	if err != nil {
 		log.Println("DLV - GetByName - Can't marshal jsend reply")
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -151,17 +303,26 @@ func (d *delivery) GetByName(msg *nats.Msg) {
 }
 
 func (d *delivery) GetAll(msg *nats.Msg) {
-	products, err := d.usecase.GetAll()
+	query := &product.Query{}
+	if len(msg.Data) > 0 {
+		err := json.Unmarshal(msg.Data, &query)
+		if err != nil {
+			JsendInvalidRequestReply(d, msg, err)
+			return
+		}
+	}
+
+	page, err := d.usecase.GetAll(query)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
-	jsendReply := jsend.New(products)
+	jsendReply := jsend.New(page)
 	reply, err := json.Marshal(&jsendReply)
 	if err != nil {
 		log.Println("DLV - GetAll - Can't marshal jsend reply")
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -172,13 +333,13 @@ func (d *delivery) Update(msg *nats.Msg) {
 	product := &product.Product{}
 	err := json.Unmarshal(msg.Data, &product)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendInvalidRequestReply(d, msg, err)
 		return
 	}
 
-	productUpdated, err := d.usecase.Update(product)
+	productUpdated, err := d.usecaseFor(msg).Update(product)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -186,7 +347,7 @@ func (d *delivery) Update(msg *nats.Msg) {
 	reply, err := json.Marshal(&jsendReply)
 	if err != nil {
 		log.Println("DLV - Update - Can't marshal jsend reply")
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -197,13 +358,13 @@ func (d *delivery) Delete(msg *nats.Msg) {
 	product := &product.Product{}
 	err := json.Unmarshal(msg.Data, &product)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendInvalidRequestReply(d, msg, err)
 		return
 	}
 
-	err = d.usecase.Delete(product)
+	err = d.usecaseFor(msg).Delete(product)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -211,7 +372,82 @@ func (d *delivery) Delete(msg *nats.Msg) {
 	reply, err := json.Marshal(&jsendReply)
 	if err != nil {
 		log.Println("DLV - Delete - Can't marshal jsend reply")
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) Restore(msg *nats.Msg) {
+	product := &product.Product{}
+	err := json.Unmarshal(msg.Data, &product)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	productRestored, err := d.usecaseFor(msg).Restore(product)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(productRestored)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - Restore - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) Purge(msg *nats.Msg) {
+	product := &product.Product{}
+	err := json.Unmarshal(msg.Data, &product)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	err = d.usecaseFor(msg).Purge(product)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(nil)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - Purge - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) History(msg *nats.Msg) {
+	query := &product.HistoryQuery{}
+	err := json.Unmarshal(msg.Data, &query)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	page, err := d.usecase.History(query)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(page)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - History - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -222,13 +458,13 @@ func (d *delivery) UpdateStock(msg *nats.Msg) {
 	product := &product.Product{}
 	err := json.Unmarshal(msg.Data, &product)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendInvalidRequestReply(d, msg, err)
 		return
 	}
 
-	productUpdated, err := d.usecase.UpdateStock(product.ID, product.Stock)
+	productUpdated, err := d.usecaseFor(msg).UpdateStock(product.ID, product.Stock)
 	if err != nil {
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -236,7 +472,888 @@ func (d *delivery) UpdateStock(msg *nats.Msg) {
 	reply, err := json.Marshal(&jsendReply)
 	if err != nil {
 		log.Println("DLV - UpdateStock - Can't marshal jsend reply")
-		JsendFailReply(d, msg, err.Error())
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) AddStockMovement(msg *nats.Msg) {
+	movement := &product.StockMovement{}
+	err := json.Unmarshal(msg.Data, &movement)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	productUpdated, err := d.usecaseFor(msg).AddStockMovement(movement)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(productUpdated)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - AddStockMovement - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetStockMovements(msg *nats.Msg) {
+	movement := &product.StockMovement{}
+	err := json.Unmarshal(msg.Data, &movement)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	movements, err := d.usecase.GetStockMovements(movement.ProductID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(movements)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetStockMovements - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) Reserve(msg *nats.Msg) {
+	reservation := &product.Reservation{}
+	err := json.Unmarshal(msg.Data, &reservation)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	reservationCreated, err := d.usecaseFor(msg).Reserve(reservation)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(reservationCreated)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - Reserve - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetReservation(msg *nats.Msg) {
+	reservation := &product.Reservation{}
+	err := json.Unmarshal(msg.Data, &reservation)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	reservationRetrieved, err := d.usecase.GetReservation(reservation.ID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(reservationRetrieved)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetReservation - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) ConfirmReservation(msg *nats.Msg) {
+	reservation := &product.Reservation{}
+	err := json.Unmarshal(msg.Data, &reservation)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	reservationConfirmed, err := d.usecaseFor(msg).ConfirmReservation(reservation.ID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(reservationConfirmed)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - ConfirmReservation - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) ReleaseReservation(msg *nats.Msg) {
+	reservation := &product.Reservation{}
+	err := json.Unmarshal(msg.Data, &reservation)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	reservationReleased, err := d.usecaseFor(msg).ReleaseReservation(reservation.ID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(reservationReleased)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - ReleaseReservation - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) SchedulePrice(msg *nats.Msg) {
+	price := &product.Price{}
+	err := json.Unmarshal(msg.Data, &price)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	priceScheduled, err := d.usecaseFor(msg).SchedulePrice(price)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(priceScheduled)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - SchedulePrice - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) CancelPrice(msg *nats.Msg) {
+	price := &product.Price{}
+	err := json.Unmarshal(msg.Data, &price)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	priceCancelled, err := d.usecaseFor(msg).CancelPrice(price.ID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(priceCancelled)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - CancelPrice - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetPrices(msg *nats.Msg) {
+	price := &product.Price{}
+	err := json.Unmarshal(msg.Data, &price)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	history, err := d.usecase.GetPrices(price.ProductID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(history)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetPrices - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) CreatePriceList(msg *nats.Msg) {
+	list := &product.PriceList{}
+	err := json.Unmarshal(msg.Data, &list)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	listCreated, err := d.usecaseFor(msg).CreatePriceList(list)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(listCreated)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - CreatePriceList - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetPriceList(msg *nats.Msg) {
+	list := &product.PriceList{}
+	err := json.Unmarshal(msg.Data, &list)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	listRetrieved, err := d.usecase.GetPriceList(list.Name)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(listRetrieved)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetPriceList - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetPriceLists(msg *nats.Msg) {
+	lists, err := d.usecase.GetPriceLists()
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(lists)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetPriceLists - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) DeletePriceList(msg *nats.Msg) {
+	list := &product.PriceList{}
+	err := json.Unmarshal(msg.Data, &list)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	err = d.usecaseFor(msg).DeletePriceList(list.Name)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(nil)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - DeletePriceList - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) UpdateListPrices(msg *nats.Msg) {
+	update := &product.ListPricesUpdate{}
+	err := json.Unmarshal(msg.Data, &update)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	result, err := d.usecaseFor(msg).UpdateListPrices(update)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(result)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - UpdateListPrices - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetListPrices(msg *nats.Msg) {
+	query := &product.ListPricesQuery{}
+	err := json.Unmarshal(msg.Data, &query)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	page, err := d.usecase.GetListPrices(query)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(page)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetListPrices - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) ResolvePrice(msg *nats.Msg) {
+	query := &product.ResolvePriceQuery{}
+	err := json.Unmarshal(msg.Data, &query)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	resolved, err := d.usecase.ResolvePrice(query.ProductID, query.PriceList)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(resolved)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - ResolvePrice - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) CreateCategory(msg *nats.Msg) {
+	category := &product.Category{}
+	err := json.Unmarshal(msg.Data, &category)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	categoryCreated, err := d.usecaseFor(msg).CreateCategory(category)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(categoryCreated)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - CreateCategory - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetCategory(msg *nats.Msg) {
+	category := &product.Category{}
+	err := json.Unmarshal(msg.Data, &category)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	categoryRetrieved, err := d.usecase.GetCategory(category.ID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(categoryRetrieved)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetCategory - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetCategories(msg *nats.Msg) {
+	categories, err := d.usecase.GetCategories()
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(categories)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetCategories - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) UpdateCategory(msg *nats.Msg) {
+	category := &product.Category{}
+	err := json.Unmarshal(msg.Data, &category)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	categoryUpdated, err := d.usecaseFor(msg).UpdateCategory(category)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(categoryUpdated)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - UpdateCategory - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) DeleteCategory(msg *nats.Msg) {
+	category := &product.Category{}
+	err := json.Unmarshal(msg.Data, &category)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	err = d.usecaseFor(msg).DeleteCategory(category.ID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(nil)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - DeleteCategory - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) SetProductCategories(msg *nats.Msg) {
+	assignment := &product.ProductCategories{}
+	err := json.Unmarshal(msg.Data, &assignment)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	categories, err := d.usecaseFor(msg).SetProductCategories(assignment)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(categories)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - SetProductCategories - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetProductCategories(msg *nats.Msg) {
+	assignment := &product.ProductCategories{}
+	err := json.Unmarshal(msg.Data, &assignment)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	categories, err := d.usecase.GetProductCategories(assignment.ProductID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(categories)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetProductCategories - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) CreateVariant(msg *nats.Msg) {
+	variant := &product.Variant{}
+	err := json.Unmarshal(msg.Data, &variant)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	variantCreated, err := d.usecaseFor(msg).CreateVariant(variant)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(variantCreated)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - CreateVariant - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetVariant(msg *nats.Msg) {
+	variant := &product.Variant{}
+	err := json.Unmarshal(msg.Data, &variant)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	variantRetrieved, err := d.usecase.GetVariant(variant.ProductID, variant.ID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(variantRetrieved)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetVariant - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetVariantBySKU(msg *nats.Msg) {
+	variant := &product.Variant{}
+	err := json.Unmarshal(msg.Data, &variant)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	variantRetrieved, err := d.usecase.GetVariantBySKU(variant.SKU)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(variantRetrieved)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetVariantBySKU - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetVariants(msg *nats.Msg) {
+	variant := &product.Variant{}
+	err := json.Unmarshal(msg.Data, &variant)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	variants, err := d.usecase.GetVariants(variant.ProductID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(variants)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetVariants - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) UpdateVariant(msg *nats.Msg) {
+	variant := &product.Variant{}
+	err := json.Unmarshal(msg.Data, &variant)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	variantUpdated, err := d.usecaseFor(msg).UpdateVariant(variant)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(variantUpdated)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - UpdateVariant - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) DeleteVariant(msg *nats.Msg) {
+	variant := &product.Variant{}
+	err := json.Unmarshal(msg.Data, &variant)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	err = d.usecaseFor(msg).DeleteVariant(variant.ProductID, variant.ID)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(nil)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - DeleteVariant - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) CreateLocation(msg *nats.Msg) {
+	location := &product.Location{}
+	err := json.Unmarshal(msg.Data, &location)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	locationCreated, err := d.usecaseFor(msg).CreateLocation(location)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(locationCreated)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - CreateLocation - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetLocations(msg *nats.Msg) {
+	locations, err := d.usecase.GetLocations()
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(locations)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetLocations - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) GetStockLevels(msg *nats.Msg) {
+	query := &product.StockLevelQuery{}
+	err := json.Unmarshal(msg.Data, &query)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	levels, err := d.usecase.GetStockLevels(query)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(levels)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - GetStockLevels - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) TransferStock(msg *nats.Msg) {
+	transfer := &product.StockTransfer{}
+	err := json.Unmarshal(msg.Data, &transfer)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	movements, err := d.usecaseFor(msg).TransferStock(transfer)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(movements)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - TransferStock - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) ImportProducts(msg *nats.Msg) {
+	request := &product.ImportRequest{}
+	err := json.Unmarshal(msg.Data, &request)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	report, err := d.usecaseFor(msg).ImportProducts(request)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(report)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - ImportProducts - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) ExportProducts(msg *nats.Msg) {
+	query := &product.Query{}
+	if len(msg.Data) > 0 {
+		err := json.Unmarshal(msg.Data, &query)
+		if err != nil {
+			JsendInvalidRequestReply(d, msg, err)
+			return
+		}
+	}
+
+	page, err := d.usecase.ExportProducts(query)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(page)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - ExportProducts - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	d.nc.Publish(msg.Reply, reply)
+}
+
+func (d *delivery) Batch(msg *nats.Msg) {
+	request := &product.BatchRequest{}
+	err := json.Unmarshal(msg.Data, &request)
+	if err != nil {
+		JsendInvalidRequestReply(d, msg, err)
+		return
+	}
+
+	report, err := d.usecaseFor(msg).Batch(request)
+	if err != nil {
+		JsendFailReply(d, msg, err)
+		return
+	}
+
+	jsendReply := jsend.New(report)
+	reply, err := json.Marshal(&jsendReply)
+	if err != nil {
+		log.Println("DLV - Batch - Can't marshal jsend reply")
+		JsendFailReply(d, msg, err)
 		return
 	}
 
@@ -0,0 +1,99 @@
+// Package events publica los eventos de productos en un stream de JetStream
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+const (
+	StreamName      = "PRODUCT_EVENTS" // Nombre del stream de eventos de productos
+	StreamSubjects  = "product.>"      // Subjects capturados por el stream
+	DuplicateWindow = 10 * time.Minute // Ventana en la que JetStream descarta los eventos con un Nats-Msg-Id ya publicado
+)
+
+type publisher struct {
+	nc *nats.Conn
+	js nats.JetStreamContext
+}
+
+// NewPublisher crea un publicador de eventos sobre JetStream.
+// Si el stream de eventos no existe, lo crea.
+func NewPublisher(natsURLs string) (*publisher, error) {
+	nc, err := nats.Connect(natsURLs)
+	if err != nil {
+		return nil, errors.Wrap(err, "EVT - Can't connect to NATS")
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, errors.Wrap(err, "EVT - Can't get JetStream context")
+	}
+
+	err = ensureStream(js)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	return &publisher{
+		nc: nc,
+		js: js,
+	}, nil
+}
+
+// ensureStream crea el stream de eventos si no existe. Si existe con otra ventana de duplicados, la actualiza.
+func ensureStream(js nats.JetStreamContext) error {
+	info, err := js.StreamInfo(StreamName)
+	if err == nil {
+		if info.Config.Duplicates == DuplicateWindow {
+			return nil
+		}
+		config := info.Config
+		config.Duplicates = DuplicateWindow
+		if _, err := js.UpdateStream(&config); err != nil {
+			return errors.Wrapf(err, "EVT - Can't update stream %s", StreamName)
+		}
+		return nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return errors.Wrapf(err, "EVT - Can't get info of stream %s", StreamName)
+	}
+
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:       StreamName,
+		Subjects:   []string{StreamSubjects},
+		Storage:    nats.FileStorage,
+		Duplicates: DuplicateWindow,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "EVT - Can't create stream %s", StreamName)
+	}
+
+	return nil
+}
+
+// Publish publica el evento en el subject correspondiente a su tipo.
+// El ID del evento viaja como Nats-Msg-Id para que JetStream descarte los duplicados dentro de DuplicateWindow.
+func (p *publisher) Publish(event *product.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "EVT - Can't marshal event")
+	}
+
+	_, err = p.js.Publish(event.Type, data, nats.MsgId(event.ID))
+	if err != nil {
+		return errors.Wrapf(err, "EVT - Can't publish event %s", event.ID)
+	}
+
+	return nil
+}
+
+func (p *publisher) Drain() {
+	p.nc.Drain()
+}
@@ -0,0 +1,74 @@
+// Package outbox publica los eventos de productos registrados en el outbox del repositorio
+package outbox
+
+import (
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultBatchSize = 100                // Cantidad de eventos que se leen del outbox en cada consulta
+	DefaultRetention = 7 * 24 * time.Hour // Tiempo que se conservan en el outbox los eventos publicados
+)
+
+// Relay lee los eventos pendientes del outbox, los publica y los marca como enviados.
+// Si el servicio se cae entre la publicación y la marca, o si varias instancias del servicio ejecutan el relay
+// en simultáneo, el evento se vuelve a publicar con el mismo ID (Nats-Msg-Id). JetStream sólo descarta el duplicado
+// si llega dentro de la ventana de duplicados del stream (events.DuplicateWindow): pasada esa ventana se publica
+// de nuevo, por lo que los consumidores deben ignorar los eventos cuyo ID ya procesaron.
+type Relay struct {
+	repository product.OutboxRepository
+	publisher  product.EventPublisher
+	batchSize  int
+	retention  time.Duration
+}
+
+// NewRelay crea un relay. Si batchSize es cero se usa DefaultBatchSize. Los eventos publicados se conservan DefaultRetention.
+func NewRelay(repository product.OutboxRepository, publisher product.EventPublisher, batchSize int) *Relay {
+	if batchSize == 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Relay{
+		repository: repository,
+		publisher:  publisher,
+		batchSize:  batchSize,
+		retention:  DefaultRetention,
+	}
+}
+
+// Relay publica todos los eventos pendientes, en orden.
+// Ante la primera falla se detiene, para no publicar eventos fuera de orden; se reintenta en la próxima ejecución.
+func (r *Relay) Relay() error {
+	for {
+		events, err := r.repository.GetPendingEvents(r.batchSize)
+		if err != nil {
+			return errors.Wrap(err, "OUTBOX - Can't get pending events")
+		}
+
+		for _, event := range events {
+			if err := r.publisher.Publish(event); err != nil {
+				return errors.Wrapf(err, "OUTBOX - Can't publish event %s", event.ID)
+			}
+			if err := r.repository.MarkEventSent(event); err != nil {
+				return errors.Wrapf(err, "OUTBOX - Can't mark event %s as sent", event.ID)
+			}
+		}
+
+		if len(events) < r.batchSize {
+			return nil
+		}
+	}
+}
+
+// Prune elimina del outbox los eventos publicados hace más de DefaultRetention, para que la tabla no crezca sin límite.
+// Los pendientes nunca se eliminan.
+func (r *Relay) Prune() error {
+	if _, err := r.repository.DeleteSentEvents(time.Now().Add(-r.retention)); err != nil {
+		return errors.Wrap(err, "OUTBOX - Can't delete sent events")
+	}
+
+	return nil
+}
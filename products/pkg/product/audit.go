@@ -0,0 +1,169 @@
+package product
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Operaciones registradas en el historial de auditoría
+const (
+	OperationCreate        = "create"         // Alta del producto
+	OperationUpdate        = "update"         // Modificación de los datos del producto
+	OperationDelete        = "delete"         // Baja lógica
+	OperationRestore       = "restore"        // Restauración de un producto eliminado
+	OperationPurge         = "purge"          // Baja definitiva
+	OperationUpdateStock   = "update_stock"   // Ajuste del stock a un valor dado
+	OperationStockMovement = "stock_movement" // Ingreso o egreso de stock
+	OperationApplyPrice    = "apply_price"    // Aplicación de un cambio de precio programado
+)
+
+// UnknownActor es el actor que se registra cuando el pedido no lo informa
+const UnknownActor = "unknown"
+
+// auditIgnoredFields son los atributos que no se comparan al registrar los cambios: la versión cambia siempre
+// y el stock disponible se deriva del stock y el stock reservado
+var auditIgnoredFields = map[string]bool{
+	"version":   true,
+	"available": true,
+}
+
+// FieldChange es el cambio de un atributo del producto. Old es nil en las altas y New es nil en las bajas definitivas.
+type FieldChange struct {
+	Field string      `json:"field"`         // Nombre del atributo, como en el JSON del producto
+	Old   interface{} `json:"old,omitempty"` // Valor anterior
+	New   interface{} `json:"new,omitempty"` // Valor nuevo
+}
+
+// AuditEntry registra un cambio en un producto: quién lo hizo, cuándo, con qué operación y qué atributos cambiaron
+type AuditEntry struct {
+	ID        uint          `json:"id" gorm:"primaryKey"`           // Identificador de la entrada, en orden cronológico
+	ProductID uint          `json:"product_id" gorm:"index"`        // Producto modificado
+	Actor     string        `json:"actor" gorm:"size:100"`          // Quién realizó el cambio, según el pedido
+	Operation string        `json:"operation" gorm:"size:32"`       // Operación que produjo el cambio
+	Changes   []FieldChange `json:"changes" gorm:"serializer:json"` // Atributos modificados, con sus valores anterior y nuevo
+	CreatedAt time.Time     `json:"created_at"`                     // Momento del cambio
+}
+
+// HistoryQuery es el pedido de una página del historial de auditoría de un producto, del cambio más reciente al más antiguo
+type HistoryQuery struct {
+	ProductID uint `json:"product_id" validate:"required"`           // Producto
+	Limit     int  `json:"limit,omitempty" validate:"gte=0,lte=500"` // Cantidad máxima de entradas a devolver, por defecto DefaultLimit
+	Offset    int  `json:"offset,omitempty" validate:"gte=0"`        // Cantidad de entradas a saltear
+}
+
+// HistoryPage es una página del historial de auditoría de un producto
+type HistoryPage struct {
+	Entries []*AuditEntry `json:"entries"` // Entradas de la página
+	Total   int64         `json:"total"`   // Cantidad total de entradas del producto
+}
+
+// AuditRepository representa el repositorio del historial de auditoría
+type AuditRepository interface {
+	AddAuditEntry(entry *AuditEntry) error                     // AddAuditEntry registra una entrada del historial
+	GetAuditEntries(query *HistoryQuery) (*HistoryPage, error) // GetAuditEntries recupera una página del historial de un producto, de la entrada más reciente a la más antigua
+}
+
+// History recupera una página del historial de auditoría de un producto.
+// El historial se conserva aun después de eliminar definitivamente el producto.
+func (u *usecase) History(query *HistoryQuery) (*HistoryPage, error) {
+	if err := validateStruct(query); err != nil {
+		return nil, errors.Wrap(err, "UC - History - Error during query validation")
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultLimit
+	}
+
+	page, err := u.repository.GetAuditEntries(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - History - Error fetching history of product with id %d", query.ProductID)
+	}
+
+	return page, nil
+}
+
+// recordAudit registra, dentro de la transacción tx, la entrada del historial correspondiente a un cambio en un producto.
+// Si no cambió ningún atributo, no registra nada.
+func recordAudit(tx Store, actor, operation string, before, after *Product) error {
+	changes, err := diff(before, after)
+	if err != nil {
+		return errors.Wrapf(err, "Can't compare product versions for %s", operation)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if actor == "" {
+		actor = UnknownActor
+	}
+	product := after
+	if product == nil {
+		product = before
+	}
+
+	entry := &AuditEntry{
+		ProductID: product.ID,
+		Actor:     actor,
+		Operation: operation,
+		Changes:   changes,
+		CreatedAt: time.Now(),
+	}
+	if err := tx.AddAuditEntry(entry); err != nil {
+		return errors.Wrapf(err, "Can't record audit entry for %s", operation)
+	}
+
+	return nil
+}
+
+// diff compara los atributos de dos versiones de un producto, tal como se serializan en JSON
+func diff(before, after *Product) ([]FieldChange, error) {
+	oldFields, err := productFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := productFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{}
+	for field := range oldFields {
+		fields = append(fields, field)
+	}
+	for field := range newFields {
+		if _, ok := oldFields[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []FieldChange{}
+	for _, field := range fields {
+		if auditIgnoredFields[field] || reflect.DeepEqual(oldFields[field], newFields[field]) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: field, Old: oldFields[field], New: newFields[field]})
+	}
+
+	return changes, nil
+}
+
+func productFields(p *Product) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if p == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
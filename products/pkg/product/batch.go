@@ -0,0 +1,193 @@
+package product
+
+import (
+	"github.com/pkg/errors"
+)
+
+// MaxBatchOperations es la cantidad máxima de operaciones de un lote
+const MaxBatchOperations = 500
+
+// Operaciones de un lote
+const (
+	BatchCreate = "create" // Alta de un producto (Create)
+	BatchUpdate = "update" // Modificación de un producto (Update)
+	BatchDelete = "delete" // Baja lógica de un producto (Delete)
+	BatchStock  = "stock"  // Modificación del stock de un producto (UpdateStock)
+)
+
+// Modos de ejecución de un lote
+const (
+	BatchAtomic     = "atomic"      // Todo o nada: si falla una operación, no se aplica ninguna. Es el modo por defecto
+	BatchBestEffort = "best_effort" // Cada operación se aplica o falla en forma independiente de las demás
+)
+
+// Resultados de una operación de un lote
+const (
+	BatchSucceeded = "succeeded" // La operación se aplicó
+	BatchFailed    = "failed"    // La operación falló
+	BatchAborted   = "aborted"   // La operación no se aplicó porque falló otra del mismo lote atómico
+)
+
+// errBatchAborted descarta la transacción de un lote atómico en el que falló una operación
+var errBatchAborted = errors.New("batch aborted")
+
+// BatchOperation es una operación de un lote. Las operaciones se ejecutan en el orden del lote, con los mismos casos de uso
+// y validaciones que los pedidos individuales.
+type BatchOperation struct {
+	Op      string   `json:"op"`                // Operación: BatchCreate, BatchUpdate, BatchDelete o BatchStock
+	ID      uint     `json:"id,omitempty"`      // Producto a modificar, eliminar o cuyo stock se modifica. No se informa en las altas
+	Product *Product `json:"product,omitempty"` // Datos del producto a crear o modificar
+	Stock   *float64 `json:"stock,omitempty"`   // Nuevo stock del producto, en las modificaciones de stock
+}
+
+// BatchRequest es un lote de operaciones sobre productos
+type BatchRequest struct {
+	Mode       string            `json:"mode,omitempty" validate:"omitempty,oneof=atomic best_effort"` // Modo de ejecución, por defecto BatchAtomic
+	Operations []*BatchOperation `json:"operations" validate:"required,gt=0,lte=500"`                  // Operaciones del lote
+}
+
+// BatchResult es el resultado de una operación de un lote
+type BatchResult struct {
+	Index   int               `json:"index"`             // Posición de la operación en el lote, desde 0
+	Op      string            `json:"op"`                // Operación
+	Status  string            `json:"status"`            // Resultado: BatchSucceeded, BatchFailed o BatchAborted
+	Product *Product          `json:"product,omitempty"` // Producto resultante de la operación, si se aplicó. No se informa en las bajas
+	Code    string            `json:"code,omitempty"`    // Código del error si la operación falló (ver ErrorCode)
+	Message string            `json:"message,omitempty"` // Mensaje del error si la operación falló
+	Fields  map[string]string `json:"fields,omitempty"`  // Detalle por atributo de los errores de validación
+}
+
+// BatchReport es el resultado de un lote, con el resultado de cada operación en el orden del lote.
+// En un lote atómico con una operación fallida, esa operación se informa como fallida y todas las demás como abortadas.
+type BatchReport struct {
+	Mode      string         `json:"mode"`      // Modo en que se ejecutó el lote
+	Succeeded int            `json:"succeeded"` // Cantidad de operaciones aplicadas
+	Failed    int            `json:"failed"`    // Cantidad de operaciones fallidas
+	Aborted   int            `json:"aborted"`   // Cantidad de operaciones no aplicadas por la falla de otra
+	Results   []*BatchResult `json:"results"`   // Resultado de cada operación
+}
+
+// Add agrega al reporte el resultado de una operación
+func (r *BatchReport) Add(result *BatchResult) {
+	switch result.Status {
+	case BatchSucceeded:
+		r.Succeeded++
+	case BatchAborted:
+		r.Aborted++
+	default:
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// Batch ejecuta un lote de operaciones en una transacción. Cada operación se ejecuta en una transacción anidada:
+// en el modo BatchBestEffort, si falla se descartan sólo sus cambios y se informa en el reporte; en el modo BatchAtomic,
+// se descarta el lote completo.
+func (u *usecase) Batch(request *BatchRequest) (*BatchReport, error) {
+	if err := validateStruct(request); err != nil {
+		return nil, errors.Wrap(err, "UC - Batch - Error during batch validation")
+	}
+	if request.Mode == "" {
+		request.Mode = BatchAtomic
+	}
+
+	var report *BatchReport
+	var failure *BatchResult
+	err := u.repository.Transaction(func(tx Store) error {
+		report = &BatchReport{Mode: request.Mode, Results: []*BatchResult{}}
+		for i, operation := range request.Operations {
+			var result *BatchResult
+			err := tx.Transaction(func(opTx Store) error {
+				var err error
+				result, err = (&usecase{repository: opTx, actor: u.actor}).runOperation(i, operation)
+				return err
+			})
+			if err != nil {
+				result = newBatchFailure(i, operation, err)
+				if request.Mode == BatchAtomic {
+					failure = result
+					return errBatchAborted
+				}
+			}
+			report.Add(result)
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchAborted) {
+		return nil, errors.Wrap(err, "UC - Batch - Error executing batch")
+	}
+
+	if failure != nil {
+		report = &BatchReport{Mode: request.Mode, Results: []*BatchResult{}}
+		for i, operation := range request.Operations {
+			if i == failure.Index {
+				report.Add(failure)
+				continue
+			}
+			report.Add(&BatchResult{Index: i, Op: operationName(operation), Status: BatchAborted})
+		}
+	}
+
+	return report, nil
+}
+
+// runOperation ejecuta la operación de la posición index de un lote. u opera dentro de la transacción de la operación.
+func (u *usecase) runOperation(index int, operation *BatchOperation) (*BatchResult, error) {
+	fields := map[string]string{}
+	switch {
+	case operation == nil:
+		fields["op"] = "required"
+	case operation.Op != BatchCreate && operation.Op != BatchUpdate && operation.Op != BatchDelete && operation.Op != BatchStock:
+		fields["op"] = "oneof=create update delete stock"
+	case operation.Op != BatchCreate && operation.ID == 0:
+		fields["id"] = "required"
+	case (operation.Op == BatchCreate || operation.Op == BatchUpdate) && operation.Product == nil:
+		fields["product"] = "required"
+	case operation.Op == BatchStock && operation.Stock == nil:
+		fields["stock"] = "required"
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Message: "invalid data", Fields: fields}
+	}
+
+	result := &BatchResult{Index: index, Op: operation.Op, Status: BatchSucceeded}
+	var err error
+	switch operation.Op {
+	case BatchCreate:
+		result.Product, err = u.Create(operation.Product)
+	case BatchUpdate:
+		operation.Product.ID = operation.ID
+		result.Product, err = u.Update(operation.Product)
+	case BatchDelete:
+		err = u.Delete(&Product{ID: operation.ID})
+	case BatchStock:
+		result.Product, err = u.UpdateStock(operation.ID, *operation.Stock)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// newBatchFailure arma el resultado de la operación de la posición index de un lote que falló por el error err
+func newBatchFailure(index int, operation *BatchOperation, err error) *BatchResult {
+	return &BatchResult{
+		Index:   index,
+		Op:      operationName(operation),
+		Status:  BatchFailed,
+		Code:    ErrorCode(err),
+		Message: err.Error(),
+		Fields:  ErrorFields(err),
+	}
+}
+
+// operationName devuelve el nombre de una operación de un lote, que puede ser nil si el lote no es válido
+func operationName(operation *BatchOperation) string {
+	if operation == nil {
+		return ""
+	}
+
+	return operation.Op
+}
@@ -0,0 +1,251 @@
+package product
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxCategoryDepth es la cantidad máxima de niveles del árbol de categorías
+const MaxCategoryDepth = 8
+
+// MaxProductCategories es la cantidad máxima de categorías que se asignan a un producto
+const MaxProductCategories = 50
+
+// ErrCategoryNotEmpty indica que se intentó eliminar una categoría que tiene subcategorías
+var ErrCategoryNotEmpty = errors.New("category has subcategories")
+
+// Category es una categoría de productos. Las categorías forman un árbol: cada una puede tener una categoría padre.
+// Cada categoría guarda su camino materializado (Path), con los IDs desde la raíz, por ejemplo "/1/4/".
+// Así, las subcategorías de cualquier nivel de una categoría son las que tienen un camino con su camino como prefijo.
+type Category struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`                                     // Identificador de la categoría
+	Name        string    `json:"name" gorm:"size:60" validate:"required,gte=2,lte=60"`     // Nombre de la categoría, obligatorio
+	NameKey     string    `json:"-" gorm:"size:80;uniqueIndex"`                             // Categoría padre y nombre normalizado (ver CategoryNameKey), único. Lo asigna el repositorio
+	Description string    `json:"description,omitempty" gorm:"size:250" validate:"lte=250"` // Descripción de la categoría, no obligatoria
+	ParentID    *uint     `json:"parent_id,omitempty" gorm:"index"`                         // Categoría padre, nil si es una categoría raíz
+	Path        string    `json:"path" gorm:"size:255;index"`                               // Camino materializado desde la raíz. Lo asigna el repositorio
+	CreatedAt   time.Time `json:"created_at"`                                               // Momento en que se creó la categoría
+	UpdatedAt   time.Time `json:"updated_at"`                                               // Momento de la última modificación
+}
+
+// Depth devuelve el nivel de la categoría en el árbol, 1 para las categorías raíz
+func (c *Category) Depth() int {
+	return strings.Count(c.Path, "/") - 1
+}
+
+// ProductCategory asigna un producto a una categoría
+type ProductCategory struct {
+	ProductID  uint `gorm:"primaryKey;autoIncrement:false"`
+	CategoryID uint `gorm:"primaryKey;autoIncrement:false"`
+}
+
+// ProductCategories es el pedido de asignación de las categorías de un producto.
+// Las categorías informadas reemplazan a las que tenía el producto.
+type ProductCategories struct {
+	ProductID   uint   `json:"product_id" validate:"required"` // Producto
+	CategoryIDs []uint `json:"category_ids" validate:"lte=50"` // Categorías del producto. Vacío quita todas
+}
+
+// CategoryNameKey devuelve la clave única de una categoría: dos categorías con el mismo padre no pueden tener el mismo nombre normalizado
+func CategoryNameKey(parentID *uint, name string) string {
+	parent := uint(0)
+	if parentID != nil {
+		parent = *parentID
+	}
+
+	return fmt.Sprintf("%d/%s", parent, NormalizeName(name))
+}
+
+// CategoryPath devuelve el camino materializado de la categoría id, hija de la categoría con camino parentPath ("" si es raíz)
+func CategoryPath(parentPath string, id uint) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+
+	return fmt.Sprintf("%s%d/", parentPath, id)
+}
+
+// CategoryRepository representa el repositorio de categorías
+type CategoryRepository interface {
+	// CreateCategory agrega una categoría y le asigna el camino según su categoría padre.
+	// Si el padre ya tiene otra subcategoría con el mismo nombre normalizado, devuelve AlreadyExistsError.
+	CreateCategory(category *Category) error
+	GetCategory(id uint) (*Category, error) // GetCategory recupera una categoría por ID
+	GetCategories() ([]*Category, error)    // GetCategories recupera todas las categorías, ordenadas por camino
+	// UpdateCategory modifica una categoría. Si cambia la categoría padre, actualiza el camino de la categoría y de todas sus subcategorías.
+	UpdateCategory(category *Category) error
+	// DeleteCategory elimina una categoría y sus asignaciones a productos. Si tiene subcategorías, no la elimina y devuelve ErrCategoryNotEmpty.
+	DeleteCategory(category *Category) error
+	SetProductCategories(productID uint, categoryIDs []uint) error // SetProductCategories reemplaza las categorías de un producto
+	GetProductCategories(productID uint) ([]*Category, error)      // GetProductCategories recupera las categorías de un producto, ordenadas por camino
+}
+
+// CreateCategory crea una categoría, como raíz o como subcategoría de category.ParentID
+func (u *usecase) CreateCategory(category *Category) (*Category, error) {
+	category.Name = strings.TrimSpace(category.Name)
+
+	if err := validateStruct(category); err != nil {
+		return nil, errors.Wrap(err, "UC - CreateCategory - Error during category validation")
+	}
+
+	err := u.repository.Transaction(func(tx Store) error {
+		if category.ParentID != nil {
+			parent, err := tx.GetCategory(*category.ParentID)
+			if err != nil {
+				return err
+			}
+			if parent.Depth() >= MaxCategoryDepth {
+				return &ValidationError{Message: "invalid data", Fields: map[string]string{"parent_id": fmt.Sprintf("depth=%d", MaxCategoryDepth)}}
+			}
+		}
+
+		category.ID = 0
+		return tx.CreateCategory(category)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - CreateCategory - Error creating category %s", category.Name)
+	}
+
+	return category, nil
+}
+
+// GetCategory recupera una categoría por ID
+func (u *usecase) GetCategory(id uint) (*Category, error) {
+	category, err := u.repository.GetCategory(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - GetCategory - Error fetching category with id %d", id)
+	}
+
+	return category, nil
+}
+
+// GetCategories recupera todas las categorías, ordenadas de modo que cada una sigue a su padre
+func (u *usecase) GetCategories() ([]*Category, error) {
+	categories, err := u.repository.GetCategories()
+	if err != nil {
+		return nil, errors.Wrap(err, "UC - GetCategories - Error fetching categories")
+	}
+
+	return categories, nil
+}
+
+// UpdateCategory modifica el nombre, la descripción o la categoría padre de una categoría.
+// Una categoría no puede moverse debajo de sí misma ni de una de sus subcategorías.
+func (u *usecase) UpdateCategory(category *Category) (*Category, error) {
+	category.Name = strings.TrimSpace(category.Name)
+
+	if err := validateStruct(category); err != nil {
+		return nil, errors.Wrap(err, "UC - UpdateCategory - Error during category validation")
+	}
+
+	err := u.repository.Transaction(func(tx Store) error {
+		current, err := tx.GetCategory(category.ID)
+		if err != nil {
+			return err
+		}
+
+		if category.ParentID != nil {
+			parent, err := tx.GetCategory(*category.ParentID)
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(parent.Path, current.Path) {
+				return &ValidationError{Message: "invalid data", Fields: map[string]string{"parent_id": "not_descendant"}}
+			}
+
+			// El subárbol que se mueve no puede superar la profundidad máxima
+			categories, err := tx.GetCategories()
+			if err != nil {
+				return err
+			}
+			height := 0
+			for _, c := range categories {
+				if strings.HasPrefix(c.Path, current.Path) && c.Depth()-current.Depth() > height {
+					height = c.Depth() - current.Depth()
+				}
+			}
+			if parent.Depth()+1+height > MaxCategoryDepth {
+				return &ValidationError{Message: "invalid data", Fields: map[string]string{"parent_id": fmt.Sprintf("depth=%d", MaxCategoryDepth)}}
+			}
+		}
+
+		return tx.UpdateCategory(category)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - UpdateCategory - Error updating category with id %d", category.ID)
+	}
+
+	return category, nil
+}
+
+// DeleteCategory elimina una categoría sin subcategorías. Los productos de la categoría dejan de estar asignados a ella.
+func (u *usecase) DeleteCategory(id uint) error {
+	category, err := u.GetCategory(id)
+	if err != nil {
+		return errors.Wrapf(err, "UC - DeleteCategory - Category with id %d does not exist", id)
+	}
+
+	if err := u.repository.DeleteCategory(category); err != nil {
+		return errors.Wrapf(err, "UC - DeleteCategory - Error deleting category with id %d", id)
+	}
+
+	return nil
+}
+
+// SetProductCategories reemplaza las categorías de un producto. Devuelve las categorías asignadas.
+func (u *usecase) SetProductCategories(assignment *ProductCategories) ([]*Category, error) {
+	if err := validateStruct(assignment); err != nil {
+		return nil, errors.Wrap(err, "UC - SetProductCategories - Error during assignment validation")
+	}
+
+	categoryIDs := []uint{}
+	seen := map[uint]bool{}
+	for _, id := range assignment.CategoryIDs {
+		if !seen[id] {
+			seen[id] = true
+			categoryIDs = append(categoryIDs, id)
+		}
+	}
+
+	var categories []*Category
+	err := u.repository.Transaction(func(tx Store) error {
+		if _, err := tx.GetByID(assignment.ProductID); err != nil {
+			return err
+		}
+		for _, id := range categoryIDs {
+			if _, err := tx.GetCategory(id); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.SetProductCategories(assignment.ProductID, categoryIDs); err != nil {
+			return err
+		}
+
+		var err error
+		categories, err = tx.GetProductCategories(assignment.ProductID)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - SetProductCategories - Error assigning categories to product with id %d", assignment.ProductID)
+	}
+
+	return categories, nil
+}
+
+// GetProductCategories recupera las categorías de un producto
+func (u *usecase) GetProductCategories(productID uint) ([]*Category, error) {
+	if _, err := u.GetByID(productID); err != nil {
+		return nil, errors.Wrapf(err, "UC - GetProductCategories - Product with id %d does not exist", productID)
+	}
+
+	categories, err := u.repository.GetProductCategories(productID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - GetProductCategories - Error fetching categories of product with id %d", productID)
+	}
+
+	return categories, nil
+}
@@ -1,17 +1,66 @@
 package product
 
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
 // Product describe un producto en el sistema
 // Se utiliza gorm (https://gorm.io/) para modelar la entidad Product en la base de datos.
 // Es por ello que en la declaración de los atributos, además del nombre que recibe el atributo en json,
 // también se declaran las características necesarias de gorm.
 type Product struct {
-	ID          uint    `json:"id" gorm:"primaryKey"`                                     // Identificador del producto. Es clave primaria en la tabla de la base de datos
-	Name        string  `json:"name" gorm:"size:60" validate:"required,gte=2,lte=60"`     // Nombre del producto, obligatorio, mínimo 2 caracteres, máximo 60 caracteres
-	Description string  `json:"description,omitempty" gorm:"size:250" validate:"lte=250"` // Descripción "larga" del producto, no obligatorio
-	Unit        string  `json:"unit" gorm:"size=32" validate:"required"`                  // Unidad de medida del producto (unidad, metros, litros, etc), hasta 32 caracteres, obligatorio
-	Price       float64 `json:"price" validate:"required"`                                // Precio, obligatorio
-	Stock       float64 `json:"stock"`                                                    // Cantidad del producto en stock
-	IsActive    bool    `json:"is_active"`                                                // Indica si el producto está activo. Sólo para utilizar algún atributo de tipo boolean ;-)
+	ID               uint            `json:"id" gorm:"primaryKey"`                                         // Identificador del producto. Es clave primaria en la tabla de la base de datos
+	Name             string          `json:"name" gorm:"size:60" validate:"required,gte=2,lte=60"`         // Nombre del producto, obligatorio, mínimo 2 caracteres, máximo 60 caracteres
+	NameKey          string          `json:"-" gorm:"size:60;uniqueIndex"`                                 // Nombre normalizado (ver NormalizeName), único. Lo asigna el repositorio, y lo libera al eliminar el producto
+	Description      string          `json:"description,omitempty" gorm:"size:250" validate:"lte=250"`     // Descripción "larga" del producto, no obligatorio
+	Unit             string          `json:"unit" gorm:"size=32" validate:"required"`                      // Unidad de medida del producto (unidad, metros, litros, etc), hasta 32 caracteres, obligatorio
+	Price            decimal.Decimal `json:"price" validate:"required"`                                    // Precio exacto, obligatorio, con hasta PriceScale decimales. En JSON se codifica como string para no perder precisión
+	Currency         string          `json:"currency" gorm:"size:3" validate:"required,iso4217"`           // Moneda del precio, código ISO 4217. Por defecto DefaultCurrency
+	Stock            float64         `json:"stock" validate:"gte=0"`                                       // Cantidad del producto en stock, la suma de su stock en todas las ubicaciones (StockLevel). Se modifica a través de movimientos de stock (StockMovement)
+	Reserved         float64         `json:"reserved" gorm:"not null;default:0"`                           // Cantidad del stock comprometida en reservas pendientes (Reservation)
+	ReorderThreshold float64         `json:"reorder_threshold" gorm:"not null;default:0" validate:"gte=0"` // Umbral de reposición: con el stock disponible en este valor o por debajo, el producto tiene stock bajo. 0 si no se controla
+	ReorderQuantity  float64         `json:"reorder_quantity" gorm:"not null;default:0" validate:"gte=0"`  // Cantidad sugerida a reponer cuando el producto tiene stock bajo
+	IsActive         bool            `json:"is_active"`                                                    // Indica si el producto está activo. Sólo para utilizar algún atributo de tipo boolean ;-)
+	Version          uint            `json:"version" gorm:"not null;default:1"`                            // Versión del producto, se incrementa con cada modificación (control de concurrencia optimista)
+	DeletedAt        *time.Time      `json:"deleted_at,omitempty" gorm:"index"`                            // Momento en que se eliminó el producto (baja lógica), nil si no está eliminado
+	ResolvedPrice    *ResolvedPrice  `json:"resolved_price,omitempty" gorm:"-"`                            // Precio efectivo en una lista de precios. Sólo en las consultas que indican la lista, no se almacena
+}
+
+// NormalizeName devuelve la forma normalizada de un nombre de producto: en minúsculas, sin espacios al principio ni al final
+// y con un único espacio entre palabras. Dos productos no pueden tener el mismo nombre normalizado.
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Available devuelve el stock disponible del producto, es decir, el stock que no está reservado
+func (p *Product) Available() float64 {
+	return p.Stock - p.Reserved
+}
+
+// IsOutOfStock indica si el producto no tiene stock disponible
+func (p *Product) IsOutOfStock() bool {
+	return p.Available() <= 0
+}
+
+// IsLowStock indica si el stock disponible del producto llegó a su umbral de reposición
+func (p *Product) IsLowStock() bool {
+	return p.ReorderThreshold > 0 && p.Available() <= p.ReorderThreshold
+}
+
+// MarshalJSON agrega al producto el stock disponible, es decir, el stock que no está reservado
+func (p Product) MarshalJSON() ([]byte, error) {
+	type alias Product
+	return json.Marshal(struct {
+		alias
+		Available float64 `json:"available"`
+	}{
+		alias:     alias(p),
+		Available: p.Available(),
+	})
 }
 
 // Repository representa el repositorio permanente de los productos.
@@ -19,10 +68,31 @@ type Product struct {
 // Los métodos son los básicos de un ABM. Luego, en los usecases, quizás aparezcan otros métodos que se agregan y "extienden" esta interface.
 // En general, los métodos devuelven los datos del producto afectado, en los casos de alta, consulta y actualización exitosos. Y un error en caso de falla.
 type Repository interface {
-	Create(product *Product) (*Product, error) // Create permite agregar un producto nuevo al repositorio
-	GetByID(id uint) (*Product, error)         // GetByID permite recuperar un único producto, si existe, del repositorio
-	GetByName(name string) (*Product, error)   // GetByName permite recuperar un único producto por nombre
-	GetAll() ([]*Product, error)               // GetAll permite recuperar, en un slice, todos los productos existentes en el repositorio
-	Update(product *Product) (*Product, error) // Update permite actualizar los datos de un producto
-	Delete(product *Product) error             // Delete elmimina un producto del repositorio
+	Create(product *Product) (*Product, error)       // Create permite agregar un producto nuevo al repositorio. Si ya existe otro con el mismo nombre normalizado, devuelve AlreadyExistsError
+	GetByID(id uint) (*Product, error)               // GetByID permite recuperar un único producto, si existe y no está eliminado, del repositorio
+	GetByIDIncludeDeleted(id uint) (*Product, error) // GetByIDIncludeDeleted permite recuperar un único producto, aunque esté eliminado
+	GetByName(name string) (*Product, error)         // GetByName permite recuperar un único producto por nombre, comparando los nombres normalizados
+	GetAll(query *Query) (*Page, error)              // GetAll permite recuperar una página de los productos que cumplen con query. Excluye los eliminados, salvo que se indique query.IncludeDeleted
+	Update(product *Product) (*Product, error)       // Update permite actualizar los datos de un producto, salvo el stock (ver StockRepository). Si se informa Version y no coincide con la actual, devuelve ConflictError. Si el nombre ya lo usa otro producto, AlreadyExistsError
+	Delete(product *Product) error                   // Delete elmimina un producto del repositorio. Es una baja lógica: el producto deja de estar visible, pero puede restaurarse
+	Restore(product *Product) (*Product, error)      // Restore restaura un producto eliminado. Si entretanto otro producto tomó su nombre, devuelve AlreadyExistsError
+	Purge(product *Product) error                    // Purge elimina definitivamente un producto, esté o no eliminado, junto con sus variantes, sus niveles de stock, sus asignaciones a categorías, sus precios en listas y sus precios programados, y libera sus reservas pendientes
+}
+
+// Store agrupa todos los repositorios que utilizan los usecases y permite operar sobre ellos dentro de una transacción,
+// de modo que, por ejemplo, la modificación de un producto y el registro del evento correspondiente se confirmen o descarten juntos.
+type Store interface {
+	Repository
+	OutboxRepository
+	StockRepository
+	ReservationRepository
+	AuditRepository
+	PriceRepository
+	PriceListRepository
+	CategoryRepository
+	VariantRepository
+	LocationRepository
+	// Transaction ejecuta fn dentro de una transacción. fn debe operar sobre el Store que recibe.
+	// Si fn devuelve un error, todos los cambios se descartan y se devuelve dicho error.
+	Transaction(fn func(store Store) error) error
 }
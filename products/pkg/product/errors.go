@@ -0,0 +1,168 @@
+package product
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Códigos de error que viajan en las respuestas del servicio, para que los clientes puedan identificar el tipo de error
+const (
+	CodeNotFound        = "not_found"        // No existe la entidad buscada
+	CodeAlreadyExists   = "already_exists"   // Ya existe una entidad con el mismo valor en un atributo único
+	CodeValidation      = "validation"       // Los datos informados no son válidos
+	CodeConflict        = "conflict"         // El estado actual de la entidad no permite la operación
+	CodeVersionMismatch = "version_mismatch" // La versión informada no es la actual: otro cliente modificó la entidad (ver ConflictError)
+	CodeInternal        = "internal"         // Falla interna del servicio
+)
+
+// NotFoundError indica que no existe la entidad buscada
+type NotFoundError struct {
+	Entity string      // Tipo de entidad (product, reservation, etc.)
+	Key    interface{} // Valor por el que se buscó la entidad
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %v not found", e.Entity, e.Key)
+}
+
+// AlreadyExistsError indica que ya existe una entidad con el mismo valor en un atributo que debe ser único
+type AlreadyExistsError struct {
+	Entity string      // Tipo de entidad
+	Field  string      // Atributo único
+	Value  interface{} // Valor repetido
+}
+
+func (e *AlreadyExistsError) Error() string {
+	return fmt.Sprintf("%s with %s %v already exists", e.Entity, e.Field, e.Value)
+}
+
+// ValidationError indica que los datos informados no son válidos.
+// Fields detalla, para cada atributo inválido (con su nombre json), la regla que no cumple.
+type ValidationError struct {
+	Message string
+	Fields  map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	fields := make([]string, 0, len(e.Fields))
+	for field, rule := range e.Fields {
+		fields = append(fields, field+": "+rule)
+	}
+	sort.Strings(fields)
+
+	return e.Message + " (" + strings.Join(fields, ", ") + ")"
+}
+
+// ConflictError indica que no se pudo modificar un producto porque la versión informada
+// no coincide con la versión actual, es decir, otro cliente lo modificó en el medio
+type ConflictError struct {
+	ID      uint // ID del producto
+	Version uint // Versión informada por el cliente
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("product %d was modified by another client, version %d is not the current version", e.ID, e.Version)
+}
+
+// ErrorCode devuelve el código correspondiente a err.
+// Los errores que no son de alguno de los tipos anteriores se consideran fallas internas.
+func ErrorCode(err error) string {
+	var (
+		notFoundError      *NotFoundError
+		alreadyExistsError *AlreadyExistsError
+		validationError    *ValidationError
+		conflictError      *ConflictError
+	)
+
+	switch {
+	case errors.As(err, &notFoundError):
+		return CodeNotFound
+	case errors.As(err, &alreadyExistsError):
+		return CodeAlreadyExists
+	case errors.As(err, &validationError):
+		return CodeValidation
+	case errors.As(err, &conflictError):
+		return CodeVersionMismatch
+	case errors.Is(err, ErrInsufficientStock),
+		errors.Is(err, ErrReservationClosed),
+		errors.Is(err, ErrReservationExpired),
+		errors.Is(err, ErrNotDeleted),
+		errors.Is(err, ErrPriceNotScheduled),
+		errors.Is(err, ErrCategoryNotEmpty),
+		errors.Is(err, ErrVariantHasStock):
+		return CodeConflict
+	default:
+		return CodeInternal
+	}
+}
+
+// ErrorFields devuelve el detalle por atributo de un error de validación, o nil si err no lo es
+func ErrorFields(err error) map[string]string {
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		return validationError.Fields
+	}
+
+	return nil
+}
+
+// validate valida las entidades según los tags validate de sus atributos.
+// Los errores se informan con el nombre json de cada atributo.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	// Los decimales se validan por su valor numérico (required, gte, etc.)
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if d, ok := field.Interface().(decimal.Decimal); ok {
+			return d.InexactFloat64()
+		}
+		return nil
+	}, decimal.Decimal{})
+
+	return v
+}
+
+// validateStruct valida s y, si no es válido, devuelve un ValidationError con el detalle por atributo
+func validateStruct(s interface{}) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return &ValidationError{Message: err.Error()}
+	}
+
+	validationError := &ValidationError{
+		Message: "invalid data",
+		Fields:  map[string]string{},
+	}
+	for _, fieldError := range validationErrors {
+		rule := fieldError.Tag()
+		if fieldError.Param() != "" {
+			rule += "=" + fieldError.Param()
+		}
+		validationError.Fields[fieldError.Field()] = rule
+	}
+
+	return validationError
+}
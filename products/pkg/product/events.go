@@ -0,0 +1,59 @@
+package product
+
+import (
+	"time"
+
+	"github.com/nats-io/nuid"
+)
+
+// Tipos de eventos del ciclo de vida de un producto.
+// El tipo se usa también como subject con el que se publica el evento.
+const (
+	EventCreated      = "product.created"
+	EventUpdated      = "product.updated"
+	EventDeleted      = "product.deleted"
+	EventRestored     = "product.restored"
+	EventPurged       = "product.purged"
+	EventStockChanged = "product.stock_changed"
+	EventStockLow     = "product.stock_low" // El stock disponible llegó al umbral de reposición (Product.ReorderThreshold)
+	EventStockOut     = "product.stock_out" // El stock disponible se agotó
+)
+
+// Event describe un cambio ocurrido en un producto.
+// Incluye el estado del producto antes y después del cambio para que los consumidores no necesiten consultarlo.
+type Event struct {
+	ID         string    `json:"id"`               // Identificador único del evento, permite deduplicarlo
+	Type       string    `json:"type"`             // Tipo de evento (EventCreated, EventUpdated, etc.)
+	ProductID  uint      `json:"product_id"`       // Producto afectado
+	Before     *Product  `json:"before,omitempty"` // Estado anterior del producto. nil en el alta
+	After      *Product  `json:"after,omitempty"`  // Estado posterior del producto. nil en la baja
+	OccurredAt time.Time `json:"occurred_at"`      // Momento en que ocurrió el cambio
+}
+
+// EventPublisher publica los eventos de productos para que otros servicios puedan reaccionar a ellos.
+// Se utiliza el concepto de interface para desacoplar el outbox del mecanismo de mensajería.
+type EventPublisher interface {
+	Publish(event *Event) error
+}
+
+// newEvent arma un evento a partir de copias de los estados anterior y posterior del producto
+func newEvent(eventType string, before, after *Product) *Event {
+	event := &Event{
+		ID:         nuid.Next(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+	}
+
+	if before != nil {
+		snapshot := *before
+		event.Before = &snapshot
+		event.ProductID = before.ID
+	}
+	if after != nil {
+		snapshot := *after
+		event.After = &snapshot
+		event.ProductID = after.ID
+	}
+
+	return event
+}
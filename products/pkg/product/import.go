@@ -0,0 +1,277 @@
+package product
+
+import (
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// MaxImportBatch es la cantidad máxima de filas de un lote de importación. Cada lote se importa en una transacción.
+const MaxImportBatch = 500
+
+// Resultados de la importación de una fila
+const (
+	ImportCreated = "created" // Se creó el producto o la variante de la fila
+	ImportUpdated = "updated" // Se actualizó el producto (y la variante) existente
+	ImportFailed  = "failed"  // La fila no se importó
+)
+
+// errImportDryRun descarta la transacción de un lote importado en modo de prueba
+var errImportDryRun = errors.New("import dry run")
+
+// ImportRow es una fila de una importación masiva de productos.
+// Si indica SKU, la fila corresponde a una variante: si existe una variante con ese SKU se actualizan ella y su producto,
+// y si no, se crea en el producto con el nombre de la fila (que se crea si tampoco existe).
+// Sin SKU, se actualiza el producto con el nombre de la fila, o se crea si no existe.
+// Al actualizar sólo se modifican los atributos que informa la fila; los nil conservan su valor.
+type ImportRow struct {
+	Line             int               `json:"line"`                        // Número de línea en el archivo importado, para el reporte
+	Name             string            `json:"name"`                        // Nombre del producto. En las filas con SKU de una variante existente, si no se informa no se modifica
+	Description      *string           `json:"description,omitempty"`       // Descripción del producto
+	Unit             *string           `json:"unit,omitempty"`              // Unidad de medida del producto, obligatoria al crearlo
+	Price            *decimal.Decimal  `json:"price,omitempty"`             // Precio del producto, obligatorio al crearlo, o de la variante si indica SKU
+	Currency         *string           `json:"currency,omitempty"`          // Moneda del precio del producto, por defecto DefaultCurrency
+	Stock            *float64          `json:"stock,omitempty"`             // Stock del producto, o de la variante si indica SKU
+	IsActive         *bool             `json:"is_active,omitempty"`         // Si es nil, los productos nuevos se crean activos
+	ReorderThreshold *float64          `json:"reorder_threshold,omitempty"` // Umbral de reposición
+	ReorderQuantity  *float64          `json:"reorder_quantity,omitempty"`  // Cantidad de reposición
+	SKU              string            `json:"sku,omitempty"`               // SKU de la variante, no obligatorio
+	Options          map[string]string `json:"options,omitempty"`           // Opciones de la variante
+}
+
+// ImportRequest es un lote de filas de una importación masiva de productos
+type ImportRequest struct {
+	Rows   []*ImportRow `json:"rows" validate:"required,lte=500"` // Filas del lote
+	DryRun bool         `json:"dry_run,omitempty"`                // Modo de prueba: se valida e informa el resultado de cada fila sin modificar nada
+}
+
+// ImportResult es el resultado de la importación de una fila
+type ImportResult struct {
+	Line      int               `json:"line"`                 // Número de línea de la fila
+	Status    string            `json:"status"`               // Resultado: ImportCreated, ImportUpdated o ImportFailed
+	ProductID uint              `json:"product_id,omitempty"` // Producto creado o actualizado. No se informa en modo de prueba
+	VariantID uint              `json:"variant_id,omitempty"` // Variante creada o actualizada. No se informa en modo de prueba
+	Code      string            `json:"code,omitempty"`       // Código del error si la fila falló (ver ErrorCode)
+	Message   string            `json:"message,omitempty"`    // Mensaje del error si la fila falló
+	Fields    map[string]string `json:"fields,omitempty"`     // Detalle por atributo de los errores de validación
+}
+
+// ImportReport es el reporte de una importación masiva de productos, con el resultado de cada fila
+type ImportReport struct {
+	DryRun  bool            `json:"dry_run"` // Indica si fue una importación de prueba
+	Created int             `json:"created"` // Cantidad de filas que crearon un producto o una variante
+	Updated int             `json:"updated"` // Cantidad de filas que actualizaron un producto existente
+	Failed  int             `json:"failed"`  // Cantidad de filas que no se importaron
+	Results []*ImportResult `json:"results"` // Resultado de cada fila, en el orden de las filas
+}
+
+// Add agrega al reporte el resultado de una fila
+func (r *ImportReport) Add(result *ImportResult) {
+	switch result.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	default:
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// NewImportFailure arma el resultado de una fila que no se importó por el error err
+func NewImportFailure(line int, err error) *ImportResult {
+	return &ImportResult{
+		Line:    line,
+		Status:  ImportFailed,
+		Code:    ErrorCode(err),
+		Message: err.Error(),
+		Fields:  ErrorFields(err),
+	}
+}
+
+// ImportProducts importa un lote de filas en una transacción. Cada fila se importa con los mismos casos de uso y validaciones
+// que el alta y la modificación individuales, y en una transacción anidada: si falla, se descartan sólo sus cambios
+// y se informa en el reporte. En modo de prueba se descarta el lote completo.
+func (u *usecase) ImportProducts(request *ImportRequest) (*ImportReport, error) {
+	if err := validateStruct(request); err != nil {
+		return nil, errors.Wrap(err, "UC - ImportProducts - Error during import validation")
+	}
+
+	var report *ImportReport
+	err := u.repository.Transaction(func(tx Store) error {
+		report = &ImportReport{DryRun: request.DryRun, Results: []*ImportResult{}}
+		for _, row := range request.Rows {
+			var result *ImportResult
+			err := tx.Transaction(func(rowTx Store) error {
+				var err error
+				result, err = (&usecase{repository: rowTx, actor: u.actor}).importRow(row)
+				return err
+			})
+			if err != nil {
+				result = NewImportFailure(row.Line, err)
+			}
+			if request.DryRun {
+				result.ProductID, result.VariantID = 0, 0
+			}
+			report.Add(result)
+		}
+
+		if request.DryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		return nil, errors.Wrap(err, "UC - ImportProducts - Error importing products")
+	}
+
+	return report, nil
+}
+
+// importRow crea o actualiza el producto, y la variante si la indica, de una fila de una importación.
+// u opera dentro de la transacción de la fila.
+func (u *usecase) importRow(row *ImportRow) (*ImportResult, error) {
+	result := &ImportResult{Line: row.Line, Status: ImportUpdated}
+
+	var variant *Variant
+	var existing *Product
+	if row.SKU != "" {
+		v, err := u.repository.GetVariantBySKU(NormalizeSKU(row.SKU))
+		if err := ignoreNotFound(err); err != nil {
+			return nil, err
+		}
+		if err == nil {
+			variant = v
+			if existing, err = u.GetByID(variant.ProductID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if existing == nil {
+		p, err := u.repository.GetByName(row.Name)
+		if err := ignoreNotFound(err); err != nil {
+			return nil, err
+		}
+		if err == nil {
+			existing = p
+		}
+	}
+
+	var err error
+	var p *Product
+	if existing == nil {
+		p = &Product{Name: row.Name, IsActive: true}
+		// Un producto nuevo toma el precio de la fila, aunque sea la de una variante
+		if row.Price != nil {
+			p.Price = *row.Price
+		}
+		// El stock de una fila con SKU es el de la variante
+		if row.Stock != nil && row.SKU == "" {
+			p.Stock = *row.Stock
+		}
+		setImportedAttributes(p, row)
+		if p, err = u.Create(p); err != nil {
+			return nil, err
+		}
+		result.Status = ImportCreated
+	} else {
+		updated := *existing
+		p = &updated
+		if row.Name != "" {
+			p.Name = row.Name
+		}
+		// El precio de una fila con SKU es el de la variante
+		if row.Price != nil && row.SKU == "" {
+			p.Price = *row.Price
+		}
+		setImportedAttributes(p, row)
+		if p, err = u.Update(p); err != nil {
+			return nil, err
+		}
+		// El stock se modifica con UpdateStock, que exige indicar la variante si el producto tiene variantes
+		if row.Stock != nil && row.SKU == "" && *row.Stock != p.Stock {
+			if p, err = u.UpdateStock(p.ID, *row.Stock); err != nil {
+				return nil, err
+			}
+		}
+	}
+	result.ProductID = p.ID
+
+	if row.SKU == "" {
+		return result, nil
+	}
+
+	if variant == nil {
+		variant = &Variant{ProductID: p.ID, SKU: row.SKU, Options: row.Options}
+		// Si la fila creó el producto, el precio ya es el del producto
+		if row.Price != nil && result.Status != ImportCreated {
+			variant.Price = row.Price
+		}
+		if row.Stock != nil {
+			variant.Stock = *row.Stock
+		}
+		if variant, err = u.CreateVariant(variant); err != nil {
+			return nil, err
+		}
+		result.Status = ImportCreated
+		result.VariantID = variant.ID
+		return result, nil
+	}
+
+	if row.Options != nil || row.Price != nil {
+		if row.Options != nil {
+			variant.Options = row.Options
+		}
+		if row.Price != nil {
+			variant.Price = row.Price
+		}
+		if variant, err = u.UpdateVariant(variant); err != nil {
+			return nil, err
+		}
+	}
+	if row.Stock != nil && *row.Stock != variant.Stock {
+		_, err = u.AddStockMovement(&StockMovement{
+			ProductID: p.ID,
+			VariantID: &variant.ID,
+			Quantity:  *row.Stock - variant.Stock,
+			Reason:    ReasonAdjustment,
+			Reference: "import",
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.VariantID = variant.ID
+
+	return result, nil
+}
+
+// setImportedAttributes asigna al producto los atributos que informa una fila, salvo el nombre, el precio y el stock
+func setImportedAttributes(p *Product, row *ImportRow) {
+	if row.Description != nil {
+		p.Description = *row.Description
+	}
+	if row.Unit != nil {
+		p.Unit = *row.Unit
+	}
+	if row.Currency != nil {
+		p.Currency = *row.Currency
+	}
+	if row.IsActive != nil {
+		p.IsActive = *row.IsActive
+	}
+	if row.ReorderThreshold != nil {
+		p.ReorderThreshold = *row.ReorderThreshold
+	}
+	if row.ReorderQuantity != nil {
+		p.ReorderQuantity = *row.ReorderQuantity
+	}
+}
+
+// ignoreNotFound devuelve nil si err indica que no existe la entidad buscada, y err en cualquier otro caso
+func ignoreNotFound(err error) error {
+	if ErrorCode(err) == CodeNotFound {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,192 @@
+package product
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultLocationCode es el código de la ubicación por defecto, que crea la migración del esquema.
+// Los movimientos de stock que no indican una ubicación se registran en ella.
+const DefaultLocationCode = "default"
+
+// ReasonTransfer es el motivo de los movimientos de stock que registran una transferencia entre ubicaciones.
+// Sólo los genera TransferStock: no se puede informar en un movimiento de stock.
+const ReasonTransfer = "transfer"
+
+// Location es una ubicación en la que se guarda stock: un depósito, una tienda, etc.
+type Location struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`                                       // Identificador de la ubicación
+	Code      string    `json:"code" gorm:"size:32;uniqueIndex" validate:"required,lte=32"` // Código de la ubicación, único, en minúsculas
+	Name      string    `json:"name" gorm:"size:60" validate:"required,lte=60"`             // Nombre de la ubicación
+	Address   string    `json:"address,omitempty" gorm:"size:250" validate:"lte=250"`       // Dirección de la ubicación, no obligatoria
+	CreatedAt time.Time `json:"created_at"`                                                 // Momento en que se creó la ubicación
+}
+
+// StockLevel es el stock de un producto, o de una de sus variantes, en una ubicación.
+// El stock de un producto (Product.Stock) es la suma de sus niveles de stock en todas las ubicaciones.
+type StockLevel struct {
+	LocationID uint      `json:"location_id" gorm:"primaryKey;autoIncrement:false"`          // Ubicación
+	ProductID  uint      `json:"product_id" gorm:"primaryKey;autoIncrement:false;index"`     // Producto
+	VariantID  uint      `json:"variant_id,omitempty" gorm:"primaryKey;autoIncrement:false"` // Variante, 0 si el stock no corresponde a una variante
+	Quantity   float64   `json:"quantity"`                                                   // Cantidad en stock en la ubicación
+	Reserved   float64   `json:"reserved" gorm:"not null;default:0"`                         // Cantidad reservada por reservas pendientes en la ubicación, que no puede egresar
+	UpdatedAt  time.Time `json:"updated_at"`                                                 // Momento de la última modificación
+}
+
+// StockLevelQuery es el pedido de consulta de niveles de stock, de un producto, de una ubicación, o de ambos
+type StockLevelQuery struct {
+	ProductID  uint `json:"product_id,omitempty"`  // Producto, 0 para todos
+	LocationID uint `json:"location_id,omitempty"` // Ubicación, 0 para todas
+}
+
+// StockTransfer es el pedido de transferencia de stock de un producto entre dos ubicaciones
+type StockTransfer struct {
+	ProductID      uint    `json:"product_id" validate:"required"`                            // Producto
+	VariantID      *uint   `json:"variant_id,omitempty"`                                      // Variante. Obligatoria si el producto tiene variantes
+	FromLocationID uint    `json:"from_location_id" validate:"required"`                      // Ubicación de origen
+	ToLocationID   uint    `json:"to_location_id" validate:"required,nefield=FromLocationID"` // Ubicación de destino
+	Quantity       float64 `json:"quantity" validate:"gt=0"`                                  // Cantidad a transferir
+	Reference      string  `json:"reference,omitempty" validate:"lte=100"`                    // Referencia externa (remito, orden de transferencia, etc.), no obligatoria
+}
+
+// LocationRepository representa el repositorio de ubicaciones y niveles de stock.
+// Los niveles de stock se modifican a través de los movimientos de stock (ver StockRepository).
+type LocationRepository interface {
+	CreateLocation(location *Location) error                      // CreateLocation agrega una ubicación. Si ya existe otra con el mismo código, devuelve AlreadyExistsError
+	GetLocation(id uint) (*Location, error)                       // GetLocation recupera una ubicación por ID
+	GetLocationByCode(code string) (*Location, error)             // GetLocationByCode recupera una ubicación por código
+	GetLocations() ([]*Location, error)                           // GetLocations recupera todas las ubicaciones, ordenadas por código
+	GetStockLevels(query *StockLevelQuery) ([]*StockLevel, error) // GetStockLevels recupera los niveles de stock que cumplen query, ordenados por producto, variante y ubicación
+	// TransferStock transfiere stock entre dos ubicaciones en forma atómica, registrando un egreso en el origen y un ingreso en el destino.
+	// El stock del producto y de la variante no cambia. Si el stock no reservado del origen no alcanza, no registra nada y devuelve ErrInsufficientStock.
+	TransferStock(transfer *StockTransfer) ([]*StockMovement, error)
+}
+
+// CreateLocation crea una ubicación
+func (u *usecase) CreateLocation(location *Location) (*Location, error) {
+	location.Code = strings.ToLower(strings.TrimSpace(location.Code))
+	location.Name = strings.TrimSpace(location.Name)
+
+	if err := validateStruct(location); err != nil {
+		return nil, errors.Wrap(err, "UC - CreateLocation - Error during location validation")
+	}
+
+	location.ID = 0
+	if err := u.repository.CreateLocation(location); err != nil {
+		return nil, errors.Wrapf(err, "UC - CreateLocation - Error creating location %s", location.Code)
+	}
+
+	return location, nil
+}
+
+// GetLocations recupera todas las ubicaciones
+func (u *usecase) GetLocations() ([]*Location, error) {
+	locations, err := u.repository.GetLocations()
+	if err != nil {
+		return nil, errors.Wrap(err, "UC - GetLocations - Error fetching locations")
+	}
+
+	return locations, nil
+}
+
+// GetStockLevels recupera el stock por ubicación de un producto o de una ubicación
+func (u *usecase) GetStockLevels(query *StockLevelQuery) ([]*StockLevel, error) {
+	if query.ProductID == 0 && query.LocationID == 0 {
+		validationError := &ValidationError{Message: "invalid data", Fields: map[string]string{"product_id": "required_without=LocationID"}}
+		return nil, errors.Wrap(validationError, "UC - GetStockLevels - Product or location required")
+	}
+	if query.ProductID != 0 {
+		if _, err := u.GetByID(query.ProductID); err != nil {
+			return nil, errors.Wrapf(err, "UC - GetStockLevels - Product with id %d does not exist", query.ProductID)
+		}
+	}
+	if query.LocationID != 0 {
+		if _, err := u.repository.GetLocation(query.LocationID); err != nil {
+			return nil, errors.Wrapf(err, "UC - GetStockLevels - Location with id %d does not exist", query.LocationID)
+		}
+	}
+
+	levels, err := u.repository.GetStockLevels(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "UC - GetStockLevels - Error fetching stock levels")
+	}
+
+	return levels, nil
+}
+
+// TransferStock transfiere stock de un producto entre dos ubicaciones. Devuelve los movimientos registrados.
+// El stock total del producto no cambia, por lo que no se registra un evento.
+func (u *usecase) TransferStock(transfer *StockTransfer) ([]*StockMovement, error) {
+	if err := validateStruct(transfer); err != nil {
+		return nil, errors.Wrap(err, "UC - TransferStock - Error during transfer validation")
+	}
+
+	var movements []*StockMovement
+	err := u.repository.Transaction(func(tx Store) error {
+		if _, err := tx.GetByID(transfer.ProductID); err != nil {
+			return err
+		}
+		if err := checkVariant(tx, transfer.ProductID, transfer.VariantID); err != nil {
+			return err
+		}
+		for _, id := range []uint{transfer.FromLocationID, transfer.ToLocationID} {
+			if _, err := tx.GetLocation(id); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		movements, err = tx.TransferStock(transfer)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - TransferStock - Error transferring stock of product with id %d", transfer.ProductID)
+	}
+
+	return movements, nil
+}
+
+// resolveLocation verifica, dentro de la transacción tx, que exista la ubicación locationID.
+// Si locationID es nil, devuelve la ubicación por defecto.
+func resolveLocation(tx Store, locationID *uint) (*uint, error) {
+	if locationID != nil {
+		_, err := tx.GetLocation(*locationID)
+		return locationID, err
+	}
+
+	location, err := tx.GetLocationByCode(DefaultLocationCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &location.ID, nil
+}
+
+// locateReservation asigna, dentro de la transacción tx, la ubicación por defecto a una reserva que no indica ubicación,
+// y verifica que el stock disponible del producto (o de la variante) en la ubicación, descontando las reservas pendientes,
+// alcance para la reserva. El repositorio vuelve a verificarlo al reservar, en la misma operación que lo modifica.
+func locateReservation(tx Store, reservation *Reservation) error {
+	locationID, err := resolveLocation(tx, reservation.LocationID)
+	if err != nil {
+		return err
+	}
+	reservation.LocationID = locationID
+
+	levels, err := tx.GetStockLevels(&StockLevelQuery{ProductID: reservation.ProductID, LocationID: *reservation.LocationID})
+	if err != nil {
+		return err
+	}
+	variantID := uint(0)
+	if reservation.VariantID != nil {
+		variantID = *reservation.VariantID
+	}
+	for _, level := range levels {
+		if level.VariantID == variantID && level.Quantity-level.Reserved >= reservation.Quantity {
+			return nil
+		}
+	}
+
+	return ErrInsufficientStock
+}
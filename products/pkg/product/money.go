@@ -0,0 +1,32 @@
+package product
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultCurrency es la moneda que se asigna a los precios que no la informan
+const DefaultCurrency = "USD"
+
+// PriceScale es la cantidad máxima de decimales de un precio. Es la escala de las columnas de precio
+// de la base de datos, de modo que un precio válido se almacena sin redondeos.
+const PriceScale = 4
+
+// normalizeCurrency devuelve el código de moneda en mayúsculas, sin espacios
+func normalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// checkPrice verifica que price no sea negativo ni tenga más de PriceScale decimales
+func checkPrice(price decimal.Decimal) error {
+	if price.IsNegative() {
+		return &ValidationError{Message: "invalid data", Fields: map[string]string{"price": "gte=0"}}
+	}
+	if !price.Equal(price.Round(PriceScale)) {
+		return &ValidationError{Message: "invalid data", Fields: map[string]string{"price": fmt.Sprintf("scale=%d", PriceScale)}}
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package product
+
+import "time"
+
+// OutboxRepository persiste los eventos de productos hasta que son publicados (patrón transactional outbox).
+// Los eventos se agregan en la misma transacción que el cambio que los origina, y un proceso aparte (outbox.Relay)
+// los publica y los marca como enviados. Así, un cambio confirmado nunca pierde su evento.
+type OutboxRepository interface {
+	AddEvent(event *Event) error                      // AddEvent agrega un evento pendiente de publicación
+	GetPendingEvents(limit int) ([]*Event, error)     // GetPendingEvents recupera, en el orden en que fueron agregados, hasta limit eventos no publicados
+	MarkEventSent(event *Event) error                 // MarkEventSent marca un evento como publicado
+	DeleteSentEvents(before time.Time) (int64, error) // DeleteSentEvents elimina los eventos publicados antes de before. Devuelve la cantidad eliminada
+}
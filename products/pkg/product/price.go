@@ -0,0 +1,223 @@
+package product
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Estados de un precio
+const (
+	PriceScheduled = "scheduled" // Programado: entra en vigencia en EffectiveFrom
+	PriceApplied   = "applied"   // Aplicado: estuvo vigente desde EffectiveFrom hasta EffectiveTo, o lo está si EffectiveTo es nil
+	PriceCancelled = "cancelled" // Cancelado antes de entrar en vigencia
+)
+
+const applyPricesBatchSize = 100 // Cantidad de precios programados que se recuperan por consulta
+
+// SchedulerActor es el actor que se registra en el historial de auditoría cuando se aplica un precio programado
+const SchedulerActor = "price-scheduler"
+
+// ErrPriceNotScheduled indica que el precio ya fue aplicado o cancelado
+var ErrPriceNotScheduled = errors.New("price is not scheduled")
+
+// Price registra un precio de un producto y su período de vigencia.
+// Cada cambio del precio del producto (alta, modificación o cambio programado) queda registrado, de modo que
+// los precios aplicados de un producto forman su historial de precios.
+type Price struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`                                // Identificador del precio
+	ProductID     uint            `json:"product_id" gorm:"index" validate:"required"`         // Producto
+	Price         decimal.Decimal `json:"price" validate:"required"`                           // Precio
+	Currency      string          `json:"currency" gorm:"size:3" validate:"omitempty,iso4217"` // Moneda del precio. Al programar un precio, por defecto la del producto
+	Status        string          `json:"status" gorm:"size:16;index"`                         // Estado del precio
+	EffectiveFrom time.Time       `json:"effective_from" gorm:"index" validate:"required"`     // Inicio de la vigencia. En los precios programados, momento en que debe aplicarse
+	EffectiveTo   *time.Time      `json:"effective_to,omitempty"`                              // Fin de la vigencia, nil si es el precio vigente
+	CreatedAt     time.Time       `json:"created_at"`                                          // Momento en que se registró el precio
+}
+
+// PriceHistory reúne los precios de un producto: los aplicados, del más reciente al más antiguo,
+// y los programados, del más próximo al más lejano
+type PriceHistory struct {
+	Past     []*Price `json:"past"`     // Precios aplicados. El primero es el vigente
+	Upcoming []*Price `json:"upcoming"` // Precios programados
+}
+
+// PriceRepository representa el repositorio de precios
+type PriceRepository interface {
+	// AddPrice registra un precio. Si se registra aplicado, cierra en price.EffectiveFrom la vigencia del precio vigente del producto.
+	AddPrice(price *Price) error
+	GetPrice(id uint) (*Price, error) // GetPrice recupera un precio por ID
+	// ApplyPrice aplica un precio programado desde price.EffectiveFrom, cerrando la vigencia del precio vigente del producto.
+	// Si el precio ya no está programado, no modifica nada y devuelve ErrPriceNotScheduled.
+	ApplyPrice(price *Price) error
+	// CancelPrice cancela un precio programado. Si ya no está programado, no modifica nada y devuelve ErrPriceNotScheduled.
+	CancelPrice(price *Price) error
+	GetPrices(productID uint) ([]*Price, error)              // GetPrices recupera los precios de un producto, ordenados por inicio de vigencia
+	GetDuePrices(now time.Time, limit int) ([]*Price, error) // GetDuePrices recupera hasta limit precios programados que deben aplicarse a now
+}
+
+// SchedulePrice programa un cambio de precio de un producto, que se aplica en price.EffectiveFrom.
+// Si no se informa la moneda, se mantiene la del producto.
+func (u *usecase) SchedulePrice(price *Price) (*Price, error) {
+	price.Currency = normalizeCurrency(price.Currency)
+
+	if err := validateStruct(price); err != nil {
+		return nil, errors.Wrap(err, "UC - SchedulePrice - Error during price validation")
+	}
+	if err := checkPrice(price.Price); err != nil {
+		return nil, errors.Wrap(err, "UC - SchedulePrice - Error during price validation")
+	}
+	if !price.EffectiveFrom.After(time.Now()) {
+		validationError := &ValidationError{Message: "invalid data", Fields: map[string]string{"effective_from": "future"}}
+		return nil, errors.Wrap(validationError, "UC - SchedulePrice - Price must be scheduled in the future")
+	}
+
+	product, err := u.GetByID(price.ProductID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - SchedulePrice - Product with id %d does not exist", price.ProductID)
+	}
+	if price.Currency == "" {
+		price.Currency = product.Currency
+	}
+
+	price.ID = 0
+	price.Status = PriceScheduled
+	price.EffectiveTo = nil
+	if err := u.repository.AddPrice(price); err != nil {
+		return nil, errors.Wrapf(err, "UC - SchedulePrice - Error scheduling price of product with id %d", price.ProductID)
+	}
+
+	return price, nil
+}
+
+// CancelPrice cancela un cambio de precio programado
+func (u *usecase) CancelPrice(id uint) (*Price, error) {
+	price, err := u.repository.GetPrice(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - CancelPrice - Price with id %d does not exist", id)
+	}
+
+	if err := u.repository.CancelPrice(price); err != nil {
+		return nil, errors.Wrapf(err, "UC - CancelPrice - Error cancelling price with id %d", id)
+	}
+
+	price.Status = PriceCancelled
+	return price, nil
+}
+
+// GetPrices recupera los precios aplicados y programados de un producto
+func (u *usecase) GetPrices(productID uint) (*PriceHistory, error) {
+	if _, err := u.GetByID(productID); err != nil {
+		return nil, errors.Wrapf(err, "UC - GetPrices - Product with id %d does not exist", productID)
+	}
+
+	prices, err := u.repository.GetPrices(productID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - GetPrices - Error fetching prices of product with id %d", productID)
+	}
+
+	history := &PriceHistory{
+		Past:     []*Price{},
+		Upcoming: []*Price{},
+	}
+	for _, price := range prices {
+		switch price.Status {
+		case PriceApplied:
+			history.Past = append([]*Price{price}, history.Past...)
+		case PriceScheduled:
+			history.Upcoming = append(history.Upcoming, price)
+		}
+	}
+
+	return history, nil
+}
+
+// ApplyScheduledPrices aplica los cambios de precio programados cuyo momento ya llegó. Devuelve la cantidad de precios aplicados.
+// Lo ejecuta periódicamente un worker del servicio. Los precios programados de productos eliminados se cancelan.
+func (u *usecase) ApplyScheduledPrices() (int, error) {
+	applied := 0
+	for {
+		prices, err := u.repository.GetDuePrices(time.Now(), applyPricesBatchSize)
+		if err != nil {
+			return applied, errors.Wrap(err, "UC - ApplyScheduledPrices - Error fetching scheduled prices")
+		}
+
+		for _, price := range prices {
+			ok, err := u.applyPrice(price)
+			if errors.Is(err, ErrPriceNotScheduled) {
+				// Otra instancia del servicio lo aplicó, o se canceló, en simultáneo
+				continue
+			}
+			if err != nil {
+				return applied, errors.Wrapf(err, "UC - ApplyScheduledPrices - Error applying price with id %d", price.ID)
+			}
+			if ok {
+				applied++
+			}
+		}
+
+		if len(prices) < applyPricesBatchSize {
+			return applied, nil
+		}
+	}
+}
+
+// applyPrice aplica un precio programado, en una transacción que incluye la modificación del producto,
+// el evento y la entrada del historial de auditoría. Si el producto ya no existe, cancela el precio y devuelve false.
+func (u *usecase) applyPrice(price *Price) (bool, error) {
+	applied := false
+	err := u.repository.Transaction(func(tx Store) error {
+		formerProduct, err := tx.GetByID(price.ProductID)
+		var notFoundError *NotFoundError
+		if errors.As(err, &notFoundError) {
+			return tx.CancelPrice(price)
+		}
+		if err != nil {
+			return err
+		}
+
+		price.EffectiveFrom = time.Now()
+		if err := tx.ApplyPrice(price); err != nil {
+			return err
+		}
+		applied = true
+		if formerProduct.Price.Equal(price.Price) && formerProduct.Currency == price.Currency {
+			return nil
+		}
+
+		product := *formerProduct
+		product.Price = price.Price
+		product.Currency = price.Currency
+		productUpdated, err := tx.Update(&product)
+		if err != nil {
+			return err
+		}
+
+		if err := recordEvent(tx, EventUpdated, formerProduct, productUpdated); err != nil {
+			return err
+		}
+		return recordAudit(tx, SchedulerActor, OperationApplyPrice, formerProduct, productUpdated)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return applied, nil
+}
+
+// recordPrice registra, dentro de la transacción tx, el precio actual de un producto como precio aplicado desde ahora
+func recordPrice(tx Store, product *Product) error {
+	price := &Price{
+		ProductID:     product.ID,
+		Price:         product.Price,
+		Currency:      product.Currency,
+		Status:        PriceApplied,
+		EffectiveFrom: time.Now(),
+	}
+	if err := tx.AddPrice(price); err != nil {
+		return errors.Wrap(err, "Can't record price")
+	}
+
+	return nil
+}
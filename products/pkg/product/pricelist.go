@@ -0,0 +1,267 @@
+package product
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Origen de un precio resuelto
+const (
+	PriceSourceList    = "price_list" // El producto tiene un precio propio en la lista
+	PriceSourceProduct = "product"    // La lista no tiene precio para el producto, pero su moneda coincide con la del precio base
+)
+
+// MaxListPricesUpdate es la cantidad máxima de precios que se agregan o quitan de una lista en un mismo pedido
+const MaxListPricesUpdate = 1000
+
+// PriceList es una lista de precios con nombre (por ejemplo, retail-ARS o wholesale-USD), con precios propios
+// por producto en una única moneda. Permite vender el mismo producto a distintos precios según el mercado.
+type PriceList struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`                                       // Identificador de la lista
+	Name        string    `json:"name" gorm:"size:60;uniqueIndex" validate:"required,lte=60"` // Nombre de la lista, único
+	Currency    string    `json:"currency" gorm:"size:3" validate:"required,iso4217"`         // Moneda de todos los precios de la lista, código ISO 4217
+	Description string    `json:"description,omitempty" gorm:"size:250" validate:"lte=250"`   // Descripción de la lista, no obligatoria
+	CreatedAt   time.Time `json:"created_at"`                                                 // Momento en que se creó la lista
+}
+
+// ListPrice es el precio de un producto en una lista de precios
+type ListPrice struct {
+	PriceListID uint            `json:"-" gorm:"primaryKey;autoIncrement:false"`                              // Lista de precios
+	ProductID   uint            `json:"product_id" gorm:"primaryKey;autoIncrement:false" validate:"required"` // Producto
+	Price       decimal.Decimal `json:"price" validate:"required"`                                            // Precio del producto en la moneda de la lista
+	UpdatedAt   time.Time       `json:"updated_at"`                                                           // Momento de la última modificación del precio
+}
+
+// ListPricesUpdate es un pedido de modificación masiva de los precios de una lista.
+// Los cambios se aplican juntos: si alguno no es válido, no se aplica ninguno.
+type ListPricesUpdate struct {
+	PriceList  string       `json:"price_list" validate:"required"`                     // Nombre de la lista
+	Prices     []*ListPrice `json:"prices,omitempty" validate:"lte=1000,dive,required"` // Precios a agregar o reemplazar
+	ProductIDs []uint       `json:"product_ids,omitempty" validate:"lte=1000"`          // Productos a quitar de la lista
+}
+
+// ListPricesResult informa el resultado de una modificación masiva de los precios de una lista
+type ListPricesResult struct {
+	Updated int   `json:"updated"` // Cantidad de precios agregados o reemplazados
+	Removed int64 `json:"removed"` // Cantidad de precios quitados
+}
+
+// ListPricesQuery es el pedido de una página de los precios de una lista, ordenados por producto
+type ListPricesQuery struct {
+	PriceList string `json:"price_list" validate:"required"`           // Nombre de la lista
+	Limit     int    `json:"limit,omitempty" validate:"gte=0,lte=500"` // Cantidad máxima de precios a devolver, por defecto DefaultLimit
+	Offset    int    `json:"offset,omitempty" validate:"gte=0"`        // Cantidad de precios a saltear
+}
+
+// ListPricesPage es una página de los precios de una lista
+type ListPricesPage struct {
+	PriceList *PriceList   `json:"price_list"` // Lista de precios
+	Prices    []*ListPrice `json:"prices"`     // Precios de la página
+	Total     int64        `json:"total"`      // Cantidad total de precios de la lista
+}
+
+// ResolvedPrice es el precio efectivo de un producto en una lista de precios
+type ResolvedPrice struct {
+	ProductID uint            `json:"product_id"` // Producto
+	PriceList string          `json:"price_list"` // Nombre de la lista
+	Price     decimal.Decimal `json:"price"`      // Precio efectivo
+	Currency  string          `json:"currency"`   // Moneda del precio, la de la lista
+	Source    string          `json:"source"`     // Origen del precio (PriceSourceList o PriceSourceProduct)
+}
+
+// ResolvePriceQuery es el pedido de resolución del precio de un producto en una lista de precios
+type ResolvePriceQuery struct {
+	ProductID uint   `json:"product_id"` // Producto
+	PriceList string `json:"price_list"` // Nombre de la lista
+}
+
+// PriceListRepository representa el repositorio de listas de precios
+type PriceListRepository interface {
+	CreatePriceList(list *PriceList) error                                      // CreatePriceList agrega una lista. Si ya existe otra con el mismo nombre, devuelve AlreadyExistsError
+	GetPriceList(name string) (*PriceList, error)                               // GetPriceList recupera una lista por nombre
+	GetPriceLists() ([]*PriceList, error)                                       // GetPriceLists recupera todas las listas, ordenadas por nombre
+	DeletePriceList(list *PriceList) error                                      // DeletePriceList elimina una lista junto con sus precios
+	SetListPrices(prices []*ListPrice) error                                    // SetListPrices agrega los precios, o los reemplaza si el producto ya tiene precio en la lista
+	RemoveListPrices(listID uint, productIDs []uint) (int64, error)             // RemoveListPrices quita de una lista los precios de los productos indicados. Devuelve la cantidad quitada
+	GetListPrice(listID, productID uint) (*ListPrice, error)                    // GetListPrice recupera el precio de un producto en una lista
+	GetListPrices(listID uint, query *ListPricesQuery) (*ListPricesPage, error) // GetListPrices recupera una página de los precios de una lista, ordenados por producto
+}
+
+// CreatePriceList crea una lista de precios
+func (u *usecase) CreatePriceList(list *PriceList) (*PriceList, error) {
+	list.Name = strings.TrimSpace(list.Name)
+	list.Currency = normalizeCurrency(list.Currency)
+
+	if err := validateStruct(list); err != nil {
+		return nil, errors.Wrap(err, "UC - CreatePriceList - Error during price list validation")
+	}
+
+	list.ID = 0
+	if err := u.repository.CreatePriceList(list); err != nil {
+		return nil, errors.Wrapf(err, "UC - CreatePriceList - Error creating price list %s", list.Name)
+	}
+
+	return list, nil
+}
+
+// GetPriceList recupera una lista de precios por nombre
+func (u *usecase) GetPriceList(name string) (*PriceList, error) {
+	list, err := u.repository.GetPriceList(strings.TrimSpace(name))
+	if err != nil {
+		return nil, errors.Wrap(err, "UC - GetPriceList - Error fetching a price list")
+	}
+
+	return list, nil
+}
+
+// GetPriceLists recupera todas las listas de precios
+func (u *usecase) GetPriceLists() ([]*PriceList, error) {
+	lists, err := u.repository.GetPriceLists()
+	if err != nil {
+		return nil, errors.Wrap(err, "UC - GetPriceLists - Error fetching price lists")
+	}
+
+	return lists, nil
+}
+
+// DeletePriceList elimina una lista de precios junto con sus precios
+func (u *usecase) DeletePriceList(name string) error {
+	list, err := u.GetPriceList(name)
+	if err != nil {
+		return errors.Wrapf(err, "UC - DeletePriceList - Price list %s does not exist", name)
+	}
+
+	if err := u.repository.DeletePriceList(list); err != nil {
+		return errors.Wrapf(err, "UC - DeletePriceList - Error deleting price list %s", name)
+	}
+
+	return nil
+}
+
+// UpdateListPrices agrega, reemplaza y quita precios de una lista en una única transacción.
+// Los productos deben existir, y un mismo producto no puede aparecer más de una vez en el pedido.
+func (u *usecase) UpdateListPrices(update *ListPricesUpdate) (*ListPricesResult, error) {
+	if err := validateStruct(update); err != nil {
+		return nil, errors.Wrap(err, "UC - UpdateListPrices - Error during price list update validation")
+	}
+
+	seen := map[uint]bool{}
+	for i, price := range update.Prices {
+		if err := checkPrice(price.Price); err != nil {
+			validationError := &ValidationError{Message: "invalid data", Fields: map[string]string{fmt.Sprintf("prices[%d].price", i): ErrorFields(err)["price"]}}
+			return nil, errors.Wrap(validationError, "UC - UpdateListPrices - Error during price list update validation")
+		}
+		if seen[price.ProductID] {
+			validationError := &ValidationError{Message: "invalid data", Fields: map[string]string{fmt.Sprintf("prices[%d].product_id", i): "unique"}}
+			return nil, errors.Wrapf(validationError, "UC - UpdateListPrices - Product with id %d appears more than once", price.ProductID)
+		}
+		seen[price.ProductID] = true
+	}
+	for i, productID := range update.ProductIDs {
+		if seen[productID] {
+			validationError := &ValidationError{Message: "invalid data", Fields: map[string]string{fmt.Sprintf("product_ids[%d]", i): "unique"}}
+			return nil, errors.Wrapf(validationError, "UC - UpdateListPrices - Product with id %d appears more than once", productID)
+		}
+		seen[productID] = true
+	}
+
+	list, err := u.GetPriceList(update.PriceList)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - UpdateListPrices - Price list %s does not exist", update.PriceList)
+	}
+
+	result := &ListPricesResult{}
+	err = u.repository.Transaction(func(tx Store) error {
+		for _, price := range update.Prices {
+			if _, err := tx.GetByID(price.ProductID); err != nil {
+				return err
+			}
+			price.PriceListID = list.ID
+		}
+		if len(update.Prices) > 0 {
+			if err := tx.SetListPrices(update.Prices); err != nil {
+				return err
+			}
+		}
+		result.Updated = len(update.Prices)
+
+		if len(update.ProductIDs) > 0 {
+			removed, err := tx.RemoveListPrices(list.ID, update.ProductIDs)
+			if err != nil {
+				return err
+			}
+			result.Removed = removed
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - UpdateListPrices - Error updating prices of price list %s", list.Name)
+	}
+
+	return result, nil
+}
+
+// GetListPrices recupera una página de los precios de una lista
+func (u *usecase) GetListPrices(query *ListPricesQuery) (*ListPricesPage, error) {
+	if err := validateStruct(query); err != nil {
+		return nil, errors.Wrap(err, "UC - GetListPrices - Error during query validation")
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultLimit
+	}
+
+	list, err := u.GetPriceList(query.PriceList)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - GetListPrices - Price list %s does not exist", query.PriceList)
+	}
+
+	page, err := u.repository.GetListPrices(list.ID, query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - GetListPrices - Error fetching prices of price list %s", list.Name)
+	}
+	page.PriceList = list
+
+	return page, nil
+}
+
+// ResolvePrice resuelve el precio efectivo de un producto en una lista de precios: el precio propio del producto en la lista
+// o, si no lo tiene, su precio base, siempre que esté en la moneda de la lista. Si no, devuelve NotFoundError.
+func (u *usecase) ResolvePrice(productID uint, priceList string) (*ResolvedPrice, error) {
+	product, err := u.GetByID(productID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - ResolvePrice - Product with id %d does not exist", productID)
+	}
+
+	list, err := u.GetPriceList(priceList)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - ResolvePrice - Price list %s does not exist", priceList)
+	}
+
+	resolved := &ResolvedPrice{
+		ProductID: product.ID,
+		PriceList: list.Name,
+		Currency:  list.Currency,
+	}
+
+	listPrice, err := u.repository.GetListPrice(list.ID, product.ID)
+	var notFoundError *NotFoundError
+	switch {
+	case err == nil:
+		resolved.Price = listPrice.Price
+		resolved.Source = PriceSourceList
+	case errors.As(err, &notFoundError) && product.Currency == list.Currency:
+		resolved.Price = product.Price
+		resolved.Source = PriceSourceProduct
+	case errors.As(err, &notFoundError):
+		return nil, errors.Wrapf(err, "UC - ResolvePrice - Product with id %d has no price in %s", product.ID, list.Currency)
+	default:
+		return nil, errors.Wrapf(err, "UC - ResolvePrice - Error fetching price of product with id %d in price list %s", product.ID, list.Name)
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,172 @@
+package product
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Campos por los que se puede ordenar un listado de productos
+const (
+	SortByID    = "id"
+	SortByName  = "name"
+	SortByPrice = "price"
+	SortByStock = "stock"
+)
+
+// NameSortKey devuelve la clave por la que se ordenan los productos por nombre: el nombre en minúsculas,
+// como lower(name) en la base de datos, para que el orden no dependa de la collation. Los cursores la usan como valor.
+func NameSortKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// Sentidos de ordenamiento
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+const (
+	DefaultLimit = 50  // Cantidad de productos por página si no se indica otra
+	MaxLimit     = 500 // Cantidad máxima de productos por página
+)
+
+// ExportPageSize es la cantidad de productos de cada página de una exportación, si no se indica otra
+const ExportPageSize = MaxLimit
+
+// Query describe un pedido de listado de productos: paginación, orden y filtros.
+// La paginación puede hacerse por offset (Limit/Offset) o por cursor (Limit/Cursor). Si se informa Cursor, Offset se ignora.
+// Los filtros son opcionales, los punteros en nil indican que no se filtra por ese atributo.
+type Query struct {
+	Limit          int              `json:"limit,omitempty" validate:"gte=0,lte=500"`                         // Cantidad máxima de productos a devolver
+	Offset         int              `json:"offset,omitempty" validate:"gte=0"`                                // Cantidad de productos a saltear
+	Cursor         string           `json:"cursor,omitempty"`                                                 // Cursor devuelto en Page.NextCursor por la página anterior
+	SortBy         string           `json:"sort_by,omitempty" validate:"omitempty,oneof=id name price stock"` // Campo de orden, por defecto id
+	SortOrder      string           `json:"sort_order,omitempty" validate:"omitempty,oneof=asc desc"`         // Sentido del orden, por defecto asc
+	IsActive       *bool            `json:"is_active,omitempty"`                                              // Sólo productos activos (o inactivos)
+	MinPrice       *decimal.Decimal `json:"min_price,omitempty"`                                              // Precio mínimo, inclusive
+	MaxPrice       *decimal.Decimal `json:"max_price,omitempty"`                                              // Precio máximo, inclusive
+	MinStock       *float64         `json:"min_stock,omitempty"`                                              // Stock mínimo, inclusive
+	MaxStock       *float64         `json:"max_stock,omitempty"`                                              // Stock máximo, inclusive
+	NamePrefix     string           `json:"name_prefix,omitempty"`                                            // Sólo productos cuyo nombre comienza con este prefijo
+	IncludeDeleted bool             `json:"include_deleted,omitempty"`                                        // Incluir los productos eliminados
+	CategoryID     *uint            `json:"category_id,omitempty"`                                            // Sólo productos asignados a esta categoría o a alguna de sus subcategorías
+	LowStock       bool             `json:"low_stock,omitempty"`                                              // Sólo productos con stock bajo: con el stock disponible en su umbral de reposición o por debajo (ver Product.IsLowStock)
+
+	After        *Cursor `json:"-"` // Posición a partir de la cual continuar, decodificada de Cursor por el usecase
+	CategoryPath string  `json:"-"` // Camino de la categoría CategoryID, lo asigna el usecase
+	SkipTotal    bool    `json:"-"` // No calcular Page.Total, lo asigna el usecase en las exportaciones
+}
+
+// ByIDQuery es el pedido de consulta de un producto por ID
+type ByIDQuery struct {
+	ID             uint `json:"id"`                        // ID del producto
+	IncludeDeleted bool `json:"include_deleted,omitempty"` // Devolver el producto aunque esté eliminado
+}
+
+// Page es una página de un listado de productos
+type Page struct {
+	Products   []*Product `json:"products"`              // Productos de la página
+	Total      int64      `json:"total"`                 // Cantidad total de productos que cumplen los filtros. No se calcula en las exportaciones
+	NextCursor string     `json:"next_cursor,omitempty"` // Cursor para recuperar la página siguiente. Vacío si no hay más productos
+}
+
+// Cursor identifica al último producto de una página, según el orden del listado
+type Cursor struct {
+	SortBy    string      `json:"sort_by"`
+	SortOrder string      `json:"sort_order"`
+	ID        uint        `json:"id"`              // ID del último producto, desempata cuando el campo de orden se repite
+	Value     interface{} `json:"value,omitempty"` // Valor del campo de orden del último producto. Los precios viajan como string y se decodifican a decimal.Decimal
+}
+
+// normalize completa los valores por defecto del query y decodifica el cursor
+func (q *Query) normalize() error {
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.SortBy == "" {
+		q.SortBy = SortByID
+	}
+	if q.SortOrder == "" {
+		q.SortOrder = SortAsc
+	}
+
+	q.After = nil
+	if q.Cursor == "" {
+		return nil
+	}
+
+	cursor, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return err
+	}
+	if cursor.SortBy != q.SortBy || cursor.SortOrder != q.SortOrder {
+		return errors.New("cursor does not match the requested sort")
+	}
+	switch value := cursor.Value.(type) {
+	case string:
+		switch q.SortBy {
+		case SortByName:
+		case SortByPrice:
+			price, err := decimal.NewFromString(value)
+			if err != nil {
+				return errors.New("invalid cursor")
+			}
+			cursor.Value = price
+		default:
+			return errors.New("invalid cursor")
+		}
+	case float64:
+		if q.SortBy != SortByStock {
+			return errors.New("invalid cursor")
+		}
+	case nil:
+		if q.SortBy != SortByID {
+			return errors.New("invalid cursor")
+		}
+	default:
+		return errors.New("invalid cursor")
+	}
+	q.After = cursor
+	q.Offset = 0
+
+	return nil
+}
+
+// nextCursor arma el cursor que apunta a continuación del producto p
+func (q *Query) nextCursor(p *Product) string {
+	cursor := &Cursor{
+		SortBy:    q.SortBy,
+		SortOrder: q.SortOrder,
+		ID:        p.ID,
+	}
+
+	switch q.SortBy {
+	case SortByName:
+		cursor.Value = NameSortKey(p.Name)
+	case SortByPrice:
+		cursor.Value = p.Price
+	case SortByStock:
+		cursor.Value = p.Stock
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cursor")
+	}
+
+	cursor := &Cursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, errors.Wrap(err, "invalid cursor")
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,243 @@
+package product
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nuid"
+	"github.com/pkg/errors"
+)
+
+// Estados de una reserva
+const (
+	ReservationPending   = "pending"   // Stock reservado, a la espera de confirmación
+	ReservationConfirmed = "confirmed" // Confirmada: el stock reservado se descontó del stock del producto
+	ReservationReleased  = "released"  // Liberada por el cliente: el stock reservado volvió a estar disponible
+	ReservationExpired   = "expired"   // Vencida: se liberó automáticamente al cumplirse su TTL
+)
+
+const (
+	DefaultReservationTTL = 15 * time.Minute // Duración de una reserva si no se indica otra
+	MaxReservationTTL     = 24 * time.Hour   // Duración máxima de una reserva
+
+	expireBatchSize = 100 // Cantidad de reservas vencidas que se recuperan por consulta
+)
+
+var (
+	// ErrReservationClosed indica que la reserva ya fue confirmada, liberada o venció
+	ErrReservationClosed = errors.New("reservation is not pending")
+	// ErrReservationExpired indica que la reserva venció antes de ser confirmada
+	ErrReservationExpired = errors.New("reservation expired")
+)
+
+// Reservation representa stock de un producto retenido mientras se completa una operación (por ejemplo, el pago de una orden).
+// Mientras está pendiente, la cantidad reservada no está disponible para otras reservas ni egresos de stock.
+type Reservation struct {
+	ID         string    `json:"id" gorm:"primaryKey;size:64" validate:"lte=64"` // Identificador de la reserva. Si no se informa, se genera uno
+	ProductID  uint      `json:"product_id" gorm:"index" validate:"required"`    // Producto reservado
+	VariantID  *uint     `json:"variant_id,omitempty"`                           // Variante reservada. Obligatoria si el producto tiene variantes
+	LocationID *uint     `json:"location_id,omitempty"`                          // Ubicación de la que sale el stock al confirmar. Si no se informa, la ubicación por defecto
+	Quantity   float64   `json:"quantity" validate:"gt=0"`                       // Cantidad reservada
+	TTL        int64     `json:"ttl,omitempty" gorm:"-" validate:"gte=0"`        // Duración de la reserva en segundos, sólo al reservar. Por defecto DefaultReservationTTL
+	Status     string    `json:"status" gorm:"size:16;index"`                    // Estado de la reserva
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index"`                        // Momento en que vence la reserva si no se confirma
+	CreatedAt  time.Time `json:"created_at"`                                     // Momento en que se reservó
+	UpdatedAt  time.Time `json:"updated_at"`                                     // Momento del último cambio de estado
+}
+
+// ReservationRepository representa el repositorio de reservas de stock
+type ReservationRepository interface {
+	// CreateReservation registra una reserva y aumenta en forma atómica el stock reservado del producto, de la variante si la indica,
+	// y del nivel de stock de su ubicación. Si el stock disponible no alcanza, no registra nada y devuelve ErrInsufficientStock.
+	CreateReservation(reservation *Reservation) (*Product, error)
+	GetReservation(id string) (*Reservation, error) // GetReservation recupera una reserva por ID
+	// CloseReservation pasa una reserva pendiente al estado status y libera el stock reservado del producto, de la variante
+	// y del nivel de stock de su ubicación.
+	// Si la reserva ya no está pendiente, no modifica nada y devuelve ErrReservationClosed.
+	CloseReservation(reservation *Reservation, status string) (*Product, error)
+	GetExpiredReservations(now time.Time, limit int) ([]*Reservation, error) // GetExpiredReservations recupera hasta limit reservas pendientes vencidas a now
+}
+
+// Reserve reserva stock de un producto
+func (u *usecase) Reserve(reservation *Reservation) (*Reservation, error) {
+	reservation.ID = strings.TrimSpace(reservation.ID)
+	if reservation.ID == "" {
+		reservation.ID = nuid.Next()
+	}
+
+	if err := validateStruct(reservation); err != nil {
+		return nil, errors.Wrap(err, "UC - Reserve - Error during reservation validation")
+	}
+
+	ttl := time.Duration(reservation.TTL) * time.Second
+	if ttl == 0 {
+		ttl = DefaultReservationTTL
+	}
+	if ttl > MaxReservationTTL {
+		validationError := &ValidationError{Message: "invalid data", Fields: map[string]string{"ttl": fmt.Sprintf("lte=%d", int64(MaxReservationTTL/time.Second))}}
+		return nil, errors.Wrapf(validationError, "UC - Reserve - Reservation TTL can't exceed %s", MaxReservationTTL)
+	}
+	reservation.Status = ReservationPending
+	reservation.ExpiresAt = time.Now().Add(ttl)
+
+	err := u.repository.Transaction(func(tx Store) error {
+		if err := checkVariant(tx, reservation.ProductID, reservation.VariantID); err != nil {
+			return err
+		}
+		if err := locateReservation(tx, reservation); err != nil {
+			return err
+		}
+
+		product, err := tx.CreateReservation(reservation)
+		if err != nil {
+			return err
+		}
+
+		formerProduct := *product
+		formerProduct.Reserved -= reservation.Quantity
+		if err := recordEvent(tx, EventStockChanged, &formerProduct, product); err != nil {
+			return err
+		}
+
+		return recordStockAlert(tx, &formerProduct, product)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - Reserve - Error reserving stock of product with id %d", reservation.ProductID)
+	}
+
+	return reservation, nil
+}
+
+// GetReservation recupera una reserva por ID
+func (u *usecase) GetReservation(id string) (*Reservation, error) {
+	reservation, err := u.repository.GetReservation(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "UC - GetReservation - Error fetching a reservation")
+	}
+
+	return reservation, nil
+}
+
+// ConfirmReservation confirma una reserva pendiente: el stock reservado se descuenta del stock del producto,
+// registrando un movimiento de venta
+func (u *usecase) ConfirmReservation(id string) (*Reservation, error) {
+	reservation, err := u.GetReservation(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - ConfirmReservation - Reservation with id %s does not exist", id)
+	}
+
+	if reservation.Status == ReservationPending && time.Now().After(reservation.ExpiresAt) {
+		if _, err := u.closeReservation(reservation, ReservationExpired); err != nil {
+			return nil, errors.Wrapf(err, "UC - ConfirmReservation - Error expiring reservation with id %s", id)
+		}
+		return nil, errors.Wrapf(ErrReservationExpired, "UC - ConfirmReservation - Can't confirm reservation with id %s", id)
+	}
+
+	reservation, err = u.closeReservation(reservation, ReservationConfirmed)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - ConfirmReservation - Error confirming reservation with id %s", id)
+	}
+
+	return reservation, nil
+}
+
+// ReleaseReservation libera una reserva pendiente: el stock reservado vuelve a estar disponible
+func (u *usecase) ReleaseReservation(id string) (*Reservation, error) {
+	reservation, err := u.GetReservation(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - ReleaseReservation - Reservation with id %s does not exist", id)
+	}
+
+	reservation, err = u.closeReservation(reservation, ReservationReleased)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - ReleaseReservation - Error releasing reservation with id %s", id)
+	}
+
+	return reservation, nil
+}
+
+// ExpireReservations libera las reservas pendientes vencidas. Devuelve la cantidad de reservas liberadas.
+// Lo ejecuta periódicamente un worker del servicio. Si no puede liberar una reserva continúa con las demás,
+// y al terminar devuelve un error con las que fallaron, para que el worker lo registre.
+func (u *usecase) ExpireReservations() (int, error) {
+	expired := 0
+	failed := map[string]bool{}
+	var failedIDs []string
+	var firstErr error
+	for {
+		// Las reservas que fallaron siguen pendientes y vuelven a recuperarse, por lo que se piden además de las del lote
+		limit := expireBatchSize + len(failed)
+		reservations, err := u.repository.GetExpiredReservations(time.Now(), limit)
+		if err != nil {
+			return expired, errors.Wrap(err, "UC - ExpireReservations - Error fetching expired reservations")
+		}
+
+		for _, reservation := range reservations {
+			if failed[reservation.ID] {
+				continue
+			}
+			_, err := u.closeReservation(reservation, ReservationExpired)
+			if errors.Is(err, ErrReservationClosed) {
+				// Otra instancia del servicio la confirmó, liberó o venció en simultáneo
+				continue
+			}
+			if err != nil {
+				failed[reservation.ID] = true
+				failedIDs = append(failedIDs, reservation.ID)
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			expired++
+		}
+
+		if len(reservations) < limit {
+			break
+		}
+	}
+
+	if firstErr != nil {
+		return expired, errors.Wrapf(firstErr, "UC - ExpireReservations - Error expiring %d reservations (%s)",
+			len(failedIDs), strings.Join(failedIDs, ", "))
+	}
+
+	return expired, nil
+}
+
+// closeReservation cierra una reserva pendiente con el estado indicado, en una transacción que incluye
+// la liberación del stock reservado, el movimiento de venta si se confirma, y los eventos correspondientes
+func (u *usecase) closeReservation(reservation *Reservation, status string) (*Reservation, error) {
+	err := u.repository.Transaction(func(tx Store) error {
+		product, err := tx.CloseReservation(reservation, status)
+		if err != nil {
+			return err
+		}
+
+		formerProduct := *product
+		formerProduct.Reserved += reservation.Quantity
+		if err := recordEvent(tx, EventStockChanged, &formerProduct, product); err != nil {
+			return err
+		}
+
+		if status != ReservationConfirmed {
+			return nil
+		}
+		_, err = addStockMovement(tx, &StockMovement{
+			ProductID:  reservation.ProductID,
+			VariantID:  reservation.VariantID,
+			LocationID: reservation.LocationID,
+			Quantity:   -reservation.Quantity,
+			Reason:     ReasonSale,
+			Reference:  "reservation " + reservation.ID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reservation.Status = status
+	return reservation, nil
+}
@@ -0,0 +1,125 @@
+package product
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Motivos de un movimiento de stock
+const (
+	ReasonPurchase   = "purchase"   // Ingreso por compra
+	ReasonSale       = "sale"       // Egreso por venta
+	ReasonAdjustment = "adjustment" // Ajuste manual (inventario, alta inicial, etc.)
+	ReasonReturn     = "return"     // Ingreso por devolución
+)
+
+// ErrInsufficientStock indica que el stock disponible de un producto (el que no está reservado) no alcanza para una operación
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// StockMovement registra una variación del stock de un producto.
+// El stock de un producto es la suma de sus movimientos: nunca se sobreescribe, sino que se registra un movimiento
+// con la diferencia. Así queda constancia de quién, por qué y en cuánto se modificó.
+type StockMovement struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`                                                            // Identificador del movimiento
+	ProductID  uint      `json:"product_id" gorm:"index" validate:"required"`                                     // Producto afectado
+	VariantID  *uint     `json:"variant_id,omitempty" gorm:"index"`                                               // Variante afectada. Obligatoria si el producto tiene variantes
+	LocationID *uint     `json:"location_id,omitempty" gorm:"index"`                                              // Ubicación afectada. Si no se informa, la ubicación por defecto (DefaultLocationCode)
+	Quantity   float64   `json:"quantity" validate:"required"`                                                    // Cantidad, positiva para ingresos y negativa para egresos
+	Reason     string    `json:"reason" gorm:"size:16" validate:"required,oneof=purchase sale adjustment return"` // Motivo del movimiento
+	Reference  string    `json:"reference,omitempty" gorm:"size:100" validate:"lte=100"`                          // Referencia externa (factura, remito, orden, etc.), no obligatoria
+	CreatedAt  time.Time `json:"created_at"`                                                                      // Momento en que se registró el movimiento
+}
+
+// StockRepository representa el repositorio de movimientos de stock
+type StockRepository interface {
+	// AddStockMovement registra un movimiento y aplica su cantidad al stock del producto en forma atómica.
+	// Si indica una variante, aplica la cantidad también al stock de la variante, y siempre al nivel de stock de la ubicación que indica.
+	// Si el stock resultante (del producto o de la variante) fuese menor al stock reservado, o el de la ubicación fuese menor al stock reservado en ella,
+	// no registra nada y devuelve ErrInsufficientStock.
+	// Devuelve el producto con el stock actualizado.
+	AddStockMovement(movement *StockMovement) (*Product, error)
+	GetStockMovements(productID uint) ([]*StockMovement, error) // GetStockMovements recupera los movimientos de un producto, del más antiguo al más reciente
+}
+
+// AddStockMovement registra un ingreso (cantidad positiva) o egreso (cantidad negativa) de stock de un producto
+func (u *usecase) AddStockMovement(movement *StockMovement) (*Product, error) {
+	if err := validateStruct(movement); err != nil {
+		return nil, errors.Wrap(err, "UC - AddStockMovement - Error during stock movement validation")
+	}
+
+	var product *Product
+	err := u.repository.Transaction(func(tx Store) error {
+		if err := checkVariant(tx, movement.ProductID, movement.VariantID); err != nil {
+			return err
+		}
+
+		var err error
+		product, err = addStockMovement(tx, movement)
+		if err != nil {
+			return err
+		}
+
+		formerProduct := *product
+		formerProduct.Stock -= movement.Quantity
+		return recordAudit(tx, u.actor, OperationStockMovement, &formerProduct, product)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - AddStockMovement - Error updating stock of product with id %d", movement.ProductID)
+	}
+
+	return product, nil
+}
+
+// GetStockMovements recupera los movimientos de stock de un producto
+func (u *usecase) GetStockMovements(productID uint) ([]*StockMovement, error) {
+	_, err := u.GetByID(productID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - GetStockMovements - Product with id %d does not exist", productID)
+	}
+
+	movements, err := u.repository.GetStockMovements(productID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - GetStockMovements - Error fetching stock movements of product with id %d", productID)
+	}
+
+	return movements, nil
+}
+
+// addStockMovement registra, dentro de la transacción tx, un movimiento de stock y el evento correspondiente
+func addStockMovement(tx Store, movement *StockMovement) (*Product, error) {
+	locationID, err := resolveLocation(tx, movement.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	movement.LocationID = locationID
+
+	product, err := tx.AddStockMovement(movement)
+	if err != nil {
+		return nil, err
+	}
+
+	formerProduct := *product
+	formerProduct.Stock -= movement.Quantity
+	if err := recordEvent(tx, EventStockChanged, &formerProduct, product); err != nil {
+		return nil, err
+	}
+	if err := recordStockAlert(tx, &formerProduct, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// recordStockAlert registra, dentro de la transacción tx, una alerta si el stock disponible de un producto se agotó (EventStockOut)
+// o llegó a su umbral de reposición (EventStockLow). Sólo se alerta cuando se cruza el límite, no mientras el producto sigue por debajo.
+func recordStockAlert(tx Store, before, after *Product) error {
+	switch {
+	case after.IsOutOfStock() && !before.IsOutOfStock():
+		return recordEvent(tx, EventStockOut, before, after)
+	case after.IsLowStock() && !before.IsLowStock() && !after.IsOutOfStock():
+		return recordEvent(tx, EventStockLow, before, after)
+	}
+
+	return nil
+}
@@ -3,52 +3,184 @@ package product
 import (
 	"strings"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
 )
 
-var (
-	validate *validator.Validate
-)
-
 // Usecase representa los casos de uso de productos
 // Extiende la interface Product
 type Usecase interface {
 	Repository
-	// UpdateStock permite modificar el stock de un producto
-	// Por supuesto, se podría lograr el mismo resultado llamando al método Update de la interface Repository
-	// pero lo agregamos sólo para extender dicha interface.
+	// UpdateStock lleva el stock de un producto al valor informado, registrando un movimiento de ajuste por la diferencia.
+	// El stock sólo cambia mediante movimientos de stock: Update no lo modifica.
 	UpdateStock(id uint, stock float64) (*Product, error)
+	// AddStockMovement registra un ingreso o egreso de stock de un producto
+	AddStockMovement(movement *StockMovement) (*Product, error)
+	// GetStockMovements recupera los movimientos de stock de un producto
+	GetStockMovements(productID uint) ([]*StockMovement, error)
+	// ExportProducts recupera una página de una exportación del catálogo, paginada por cursor y sin calcular el total
+	ExportProducts(query *Query) (*Page, error)
+	// Reserve reserva stock de un producto por un tiempo limitado
+	Reserve(reservation *Reservation) (*Reservation, error)
+	// GetReservation recupera una reserva
+	GetReservation(id string) (*Reservation, error)
+	// ConfirmReservation confirma una reserva, descontando el stock reservado
+	ConfirmReservation(id string) (*Reservation, error)
+	// ReleaseReservation libera una reserva, devolviendo el stock reservado
+	ReleaseReservation(id string) (*Reservation, error)
+	// ExpireReservations libera las reservas vencidas
+	ExpireReservations() (int, error)
+	// SchedulePrice programa un cambio de precio de un producto
+	SchedulePrice(price *Price) (*Price, error)
+	// CancelPrice cancela un cambio de precio programado
+	CancelPrice(id uint) (*Price, error)
+	// GetPrices recupera los precios pasados y programados de un producto
+	GetPrices(productID uint) (*PriceHistory, error)
+	// ApplyScheduledPrices aplica los cambios de precio programados cuyo momento ya llegó
+	ApplyScheduledPrices() (int, error)
+	// CreatePriceList crea una lista de precios
+	CreatePriceList(list *PriceList) (*PriceList, error)
+	// GetPriceList recupera una lista de precios por nombre
+	GetPriceList(name string) (*PriceList, error)
+	// GetPriceLists recupera todas las listas de precios
+	GetPriceLists() ([]*PriceList, error)
+	// DeletePriceList elimina una lista de precios
+	DeletePriceList(name string) error
+	// UpdateListPrices agrega, reemplaza y quita precios de una lista
+	UpdateListPrices(update *ListPricesUpdate) (*ListPricesResult, error)
+	// GetListPrices recupera una página de los precios de una lista
+	GetListPrices(query *ListPricesQuery) (*ListPricesPage, error)
+	// ResolvePrice resuelve el precio efectivo de un producto en una lista de precios
+	ResolvePrice(productID uint, priceList string) (*ResolvedPrice, error)
+	// CreateCategory crea una categoría
+	CreateCategory(category *Category) (*Category, error)
+	// GetCategory recupera una categoría por ID
+	GetCategory(id uint) (*Category, error)
+	// GetCategories recupera todas las categorías
+	GetCategories() ([]*Category, error)
+	// UpdateCategory modifica una categoría, incluso su categoría padre
+	UpdateCategory(category *Category) (*Category, error)
+	// DeleteCategory elimina una categoría sin subcategorías
+	DeleteCategory(id uint) error
+	// SetProductCategories reemplaza las categorías de un producto
+	SetProductCategories(assignment *ProductCategories) ([]*Category, error)
+	// GetProductCategories recupera las categorías de un producto
+	GetProductCategories(productID uint) ([]*Category, error)
+	// CreateVariant agrega una variante a un producto
+	CreateVariant(variant *Variant) (*Variant, error)
+	// GetVariant recupera una variante de un producto
+	GetVariant(productID, id uint) (*Variant, error)
+	// GetVariantBySKU recupera una variante por SKU
+	GetVariantBySKU(sku string) (*Variant, error)
+	// GetVariants recupera las variantes de un producto
+	GetVariants(productID uint) ([]*Variant, error)
+	// UpdateVariant modifica una variante, salvo su stock
+	UpdateVariant(variant *Variant) (*Variant, error)
+	// DeleteVariant elimina una variante sin stock
+	DeleteVariant(productID, id uint) error
+	// CreateLocation crea una ubicación de stock
+	CreateLocation(location *Location) (*Location, error)
+	// GetLocations recupera todas las ubicaciones de stock
+	GetLocations() ([]*Location, error)
+	// GetStockLevels recupera el stock por ubicación de un producto o de una ubicación
+	GetStockLevels(query *StockLevelQuery) ([]*StockLevel, error)
+	// TransferStock transfiere stock de un producto entre dos ubicaciones
+	TransferStock(transfer *StockTransfer) ([]*StockMovement, error)
+	// ImportProducts crea o actualiza en forma masiva un lote de productos, informando el resultado de cada fila
+	ImportProducts(request *ImportRequest) (*ImportReport, error)
+	// Batch ejecuta un lote de altas, modificaciones, bajas y modificaciones de stock, informando el resultado de cada operación
+	Batch(request *BatchRequest) (*BatchReport, error)
+	// History recupera una página del historial de auditoría de un producto
+	History(query *HistoryQuery) (*HistoryPage, error)
+	// WithActor devuelve los mismos casos de uso, registrando a actor como autor de los cambios en el historial de auditoría
+	WithActor(actor string) Usecase
 }
 
+// ErrNotDeleted indica que se intentó restaurar un producto que no está eliminado
+var ErrNotDeleted = errors.New("product is not deleted")
+
 type usecase struct {
-	repository Repository
+	repository Store
+	actor      string // Autor de los cambios, para el historial de auditoría
 }
 
 // NewUsecase creates a new usecase. Implements the Usecase interface
-func NewUsecase(repo Repository) Usecase {
+func NewUsecase(store Store) Usecase {
+	return &usecase{
+		repository: store,
+	}
+}
+
+// WithActor devuelve una copia del usecase que registra a actor como autor de los cambios
+func (u *usecase) WithActor(actor string) Usecase {
 	return &usecase{
-		repository: repo,
+		repository: u.repository,
+		actor:      actor,
+	}
+}
+
+// recordEvent registra, dentro de la transacción tx, el evento correspondiente a un cambio en un producto.
+// El evento se publica luego desde el outbox.
+func recordEvent(tx Store, eventType string, before, after *Product) error {
+	event := newEvent(eventType, before, after)
+	if err := tx.AddEvent(event); err != nil {
+		return errors.Wrapf(err, "Can't record event %s", eventType)
 	}
+
+	return nil
 }
 
 // Create agrega un nuevo producto
 func (u *usecase) Create(product *Product) (*Product, error) {
-	// Verify name uniqueness
+	// Trim spaces
+	// La unicidad del nombre la garantiza el repositorio, que devuelve AlreadyExistsError
 	product.Name = strings.TrimSpace(product.Name)
-	_, err := u.GetByName(product.Name)
-	if err == nil {
-		return nil, errors.Errorf("UC - Create - Product with name %s already exists", product.Name)
+	product.Currency = normalizeCurrency(product.Currency)
+	if product.Currency == "" {
+		product.Currency = DefaultCurrency
 	}
 
-	validate := validator.New()
-	err = validate.Struct(product)
-	if err != nil {
-		validationErrors := err.(validator.ValidationErrors)
-		return nil, errors.Wrap(validationErrors, "UC - Create - Error during product data validation")
+	if err := validateStruct(product); err != nil {
+		return nil, errors.Wrap(err, "UC - Create - Error during product data validation")
+	}
+	if err := checkPrice(product.Price); err != nil {
+		return nil, errors.Wrap(err, "UC - Create - Error during product data validation")
 	}
 
-	product, err = u.repository.Create(product)
+	// El stock inicial se registra como un movimiento de ajuste
+	initialStock := product.Stock
+	product.Stock = 0
+	product.Reserved = 0
+	product.Version = 1
+
+	err := u.repository.Transaction(func(tx Store) error {
+		productCreated, err := tx.Create(product)
+		if err != nil {
+			return err
+		}
+		product = productCreated
+
+		if err := recordEvent(tx, EventCreated, nil, product); err != nil {
+			return err
+		}
+
+		if err := recordPrice(tx, product); err != nil {
+			return err
+		}
+
+		if initialStock != 0 {
+			product, err = addStockMovement(tx, &StockMovement{
+				ProductID: product.ID,
+				Quantity:  initialStock,
+				Reason:    ReasonAdjustment,
+				Reference: "initial stock",
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, u.actor, OperationCreate, nil, product)
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "UC - Create - Error creating a new product")
 	}
@@ -66,6 +198,16 @@ func (u *usecase) GetByID(id uint) (*Product, error) {
 	return product, nil
 }
 
+// GetByIDIncludeDeleted recupera un producto por ID, aunque esté eliminado
+func (u *usecase) GetByIDIncludeDeleted(id uint) (*Product, error) {
+	product, err := u.repository.GetByIDIncludeDeleted(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "UC - GetByIDIncludeDeleted - Error fetching a product")
+	}
+
+	return product, nil
+}
+
 // GetByName recupera un producto por su nombre
 func (u *usecase) GetByName(name string) (*Product, error) {
 	product, err := u.repository.GetByName(name)
@@ -76,41 +218,112 @@ func (u *usecase) GetByName(name string) (*Product, error) {
 	return product, nil
 }
 
-// GetAll recupera todos los productos
-func (u *usecase) GetAll() ([]*Product, error) {
-	products, err := u.repository.GetAll()
+// GetAll recupera una página de productos, filtrados y ordenados según query
+func (u *usecase) GetAll(query *Query) (*Page, error) {
+	if query == nil {
+		query = &Query{}
+	}
+
+	if err := validateStruct(query); err != nil {
+		return nil, errors.Wrap(err, "UC - GetAll - Error during query validation")
+	}
+
+	if err := query.normalize(); err != nil {
+		return nil, errors.Wrap(&ValidationError{Message: err.Error()}, "UC - GetAll - Error during query validation")
+	}
+
+	query.CategoryPath = ""
+	if query.CategoryID != nil {
+		category, err := u.repository.GetCategory(*query.CategoryID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "UC - GetAll - Category with id %d does not exist", *query.CategoryID)
+		}
+		query.CategoryPath = category.Path
+	}
+
+	page, err := u.repository.GetAll(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "UC - GetAll - Error fetching products")
+	}
+
+	if len(page.Products) == query.Limit {
+		page.NextCursor = query.nextCursor(page.Products[len(page.Products)-1])
+	}
+
+	return page, nil
+}
+
+// ExportProducts recupera una página de una exportación de productos, filtrados y ordenados según query.
+// A diferencia de GetAll, se pagina siempre por cursor (Offset se ignora) y no se calcula el total, para que
+// recorrer el catálogo completo no dependa de su tamaño. La exportación termina con la página sin NextCursor.
+func (u *usecase) ExportProducts(query *Query) (*Page, error) {
+	if query == nil {
+		query = &Query{}
+	}
+	query.Offset = 0
+	if query.Limit == 0 {
+		query.Limit = ExportPageSize
+	}
+	query.SkipTotal = true
+
+	page, err := u.GetAll(query)
 	if err != nil {
-		return nil, errors.Wrap(err, "UC - GetAll - Error fetching all products")
+		return nil, errors.Wrap(err, "UC - ExportProducts - Error exporting products")
 	}
 
-	return products, nil
+	return page, nil
 }
 
-// Update modifica un producto existente
+// Update modifica un producto existente, salvo su stock, que se modifica con UpdateStock o AddStockMovement.
+// Si se informa la versión del producto, la modificación sólo se realiza si coincide con la versión actual.
 func (u *usecase) Update(product *Product) (*Product, error) {
 	// Trim spaces
+	// La unicidad del nombre la garantiza el repositorio, que devuelve AlreadyExistsError
 	product.Name = strings.TrimSpace(product.Name)
-
-	formerProduct := &Product{}
-
-	// Verificar la unicidad del nombre
-	formerProduct, err := u.GetByName(product.Name)
-	if (err == nil) && (formerProduct.ID != product.ID) {
-		return nil, errors.Errorf("UC - Update - Product with name %s already exists", product.Name)
+	product.Currency = normalizeCurrency(product.Currency)
+	if product.Currency == "" {
+		product.Currency = DefaultCurrency
 	}
 
-	validate = validator.New()
-	if err := validate.Struct(product); err != nil {
-		validationErrors := err.(validator.ValidationErrors)
-		return nil, errors.Wrap(validationErrors, "UC - Update - Error during product data validation")
+	if err := validateStruct(product); err != nil {
+		return nil, errors.Wrap(err, "UC - Update - Error during product data validation")
+	}
+	if err := checkPrice(product.Price); err != nil {
+		return nil, errors.Wrap(err, "UC - Update - Error during product data validation")
 	}
 
-	formerProduct, err = u.GetByID(product.ID)
+	formerProduct, err := u.GetByID(product.ID)
 	if err != nil {
 		return nil, errors.Wrapf(err, "UC - Update - Product with id %d does not exist", product.ID)
 	}
 
-	product, err = u.repository.Update(product)
+	// El stock sólo se modifica con movimientos de stock (UpdateStock, AddStockMovement): el informado se ignora
+	product.Stock = formerProduct.Stock
+	product.Reserved = formerProduct.Reserved
+
+	err = u.repository.Transaction(func(tx Store) error {
+		productUpdated, err := tx.Update(product)
+		if err != nil {
+			return err
+		}
+		product = productUpdated
+
+		if err := recordEvent(tx, EventUpdated, formerProduct, product); err != nil {
+			return err
+		}
+		// Un cambio del umbral de reposición puede dejar al producto con stock bajo
+		if err := recordStockAlert(tx, formerProduct, product); err != nil {
+			return err
+		}
+
+		if !product.Price.Equal(formerProduct.Price) || product.Currency != formerProduct.Currency {
+			if err := recordPrice(tx, product); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, u.actor, OperationUpdate, formerProduct, product)
+	})
 	if err != nil {
 		return nil, errors.Wrapf(err, "UC - Update - Error updating product with id %d", product.ID)
 	}
@@ -118,21 +331,37 @@ func (u *usecase) Update(product *Product) (*Product, error) {
 	return product, nil
 }
 
-// Delete elimina un producto
+// Delete elimina un producto. Es una baja lógica: el producto puede restaurarse (Restore) o eliminarse definitivamente (Purge).
+// Mientras está eliminado, su nombre puede usarse para otro producto.
 func (u *usecase) Delete(product *Product) error {
-	_, err := u.GetByID(product.ID)
+	formerProduct, err := u.GetByID(product.ID)
 	if err != nil {
 		return errors.Wrapf(err, "UC - Delete - Product with id %d does not exist", product.ID)
 	}
 
-	if err := u.repository.Delete(product); err != nil {
+	err = u.repository.Transaction(func(tx Store) error {
+		if err := tx.Delete(product); err != nil {
+			return err
+		}
+
+		if err := recordEvent(tx, EventDeleted, formerProduct, nil); err != nil {
+			return err
+		}
+
+		productDeleted, err := tx.GetByIDIncludeDeleted(product.ID)
+		if err != nil {
+			return err
+		}
+		return recordAudit(tx, u.actor, OperationDelete, formerProduct, productDeleted)
+	})
+	if err != nil {
 		return errors.Wrapf(err, "UC - Delete - Error deleting product with id %d", product.ID)
 	}
 
 	return nil
 }
 
-// UpdateStock permite modificar el stock de un producto
+// UpdateStock lleva el stock de un producto al valor informado mediante un movimiento de ajuste
 func (u *usecase) UpdateStock(id uint, stock float64) (*Product, error) {
 	product, err := u.GetByID(id)
 	if err != nil {
@@ -140,11 +369,89 @@ func (u *usecase) UpdateStock(id uint, stock float64) (*Product, error) {
 	}
 
 	if stock < 0 {
-		return nil, errors.New("UC - UpdateStock - Stock can't be negative")
+		return nil, errors.Wrap(&ValidationError{Message: "invalid data", Fields: map[string]string{"stock": "gte=0"}}, "UC - UpdateStock - Stock can't be negative")
+	}
+
+	// Se registra un movimiento de ajuste por la diferencia con el stock actual
+	err = u.repository.Transaction(func(tx Store) error {
+		product, err = tx.GetByID(id)
+		if err != nil || product.Stock == stock {
+			return err
+		}
+		// El stock de los productos con variantes se modifica por variante
+		if err := checkVariant(tx, id, nil); err != nil {
+			return err
+		}
+		formerProduct := product
+
+		product, err = addStockMovement(tx, &StockMovement{
+			ProductID: id,
+			Quantity:  stock - product.Stock,
+			Reason:    ReasonAdjustment,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(tx, u.actor, OperationUpdateStock, formerProduct, product)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - UpdateStock - Error updating stock of product with id %d", id)
+	}
+
+	return product, nil
+}
+
+// Restore restaura un producto eliminado
+func (u *usecase) Restore(product *Product) (*Product, error) {
+	formerProduct, err := u.GetByIDIncludeDeleted(product.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - Restore - Product with id %d does not exist", product.ID)
+	}
+	if formerProduct.DeletedAt == nil {
+		return nil, errors.Wrapf(ErrNotDeleted, "UC - Restore - Product with id %d is not deleted", product.ID)
+	}
+
+	err = u.repository.Transaction(func(tx Store) error {
+		productRestored, err := tx.Restore(product)
+		if err != nil {
+			return err
+		}
+		product = productRestored
+
+		if err := recordEvent(tx, EventRestored, formerProduct, product); err != nil {
+			return err
+		}
+		return recordAudit(tx, u.actor, OperationRestore, formerProduct, product)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - Restore - Error restoring product with id %d", product.ID)
 	}
 
-	product.Stock = stock
-	_, err = u.Update(product)
+	return product, nil
+}
+
+// Purge elimina definitivamente un producto, esté o no eliminado, con sus categorías, sus precios en listas y sus precios programados.
+// Sus movimientos de stock, reservas e historial de precios se conservan, y las reservas pendientes se liberan.
+func (u *usecase) Purge(product *Product) error {
+	formerProduct, err := u.GetByIDIncludeDeleted(product.ID)
+	if err != nil {
+		return errors.Wrapf(err, "UC - Purge - Product with id %d does not exist", product.ID)
+	}
 
-	return product, err
+	err = u.repository.Transaction(func(tx Store) error {
+		if err := tx.Purge(product); err != nil {
+			return err
+		}
+
+		if err := recordEvent(tx, EventPurged, formerProduct, nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, u.actor, OperationPurge, formerProduct, nil)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "UC - Purge - Error purging product with id %d", product.ID)
+	}
+
+	return nil
 }
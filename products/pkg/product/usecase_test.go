@@ -0,0 +1,1364 @@
+package product_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/marceloaguero/go-nats-products/products/pkg/repository/memory"
+	"github.com/shopspring/decimal"
+)
+
+func newUsecase(t *testing.T) (product.Usecase, product.Store) {
+	t.Helper()
+
+	store := memory.NewRepo()
+	return product.NewUsecase(store), store
+}
+
+func newProduct(name string, stock float64) *product.Product {
+	return &product.Product{
+		Name:     name,
+		Unit:     "unit",
+		Price:    decimal.NewFromInt(10),
+		Stock:    stock,
+		IsActive: true,
+	}
+}
+
+func mustCreate(t *testing.T, u product.Usecase, p *product.Product) *product.Product {
+	t.Helper()
+
+	created, err := u.Create(p)
+	if err != nil {
+		t.Fatalf("Create(%q): unexpected error: %v", p.Name, err)
+	}
+
+	return created
+}
+
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	if got := product.ErrorCode(err); got != code {
+		t.Fatalf("expected error code %q, got %q (error: %v)", code, got, err)
+	}
+}
+
+func eventTypes(t *testing.T, store product.Store) []string {
+	t.Helper()
+
+	events, err := store.GetPendingEvents(100)
+	if err != nil {
+		t.Fatalf("GetPendingEvents: unexpected error: %v", err)
+	}
+
+	types := []string{}
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+
+	return types
+}
+
+func assertEventTypes(t *testing.T, store product.Store, expected ...string) {
+	t.Helper()
+
+	got := eventTypes(t, store)
+	if len(got) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("expected events %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	u, store := newUsecase(t)
+
+	p := mustCreate(t, u, newProduct("  product  ", 5))
+	if p.ID == 0 || p.Name != "product" || p.Stock != 5 || p.Version == 0 {
+		t.Fatalf("unexpected product %+v", p)
+	}
+
+	movements, err := u.GetStockMovements(p.ID)
+	if err != nil {
+		t.Fatalf("GetStockMovements: unexpected error: %v", err)
+	}
+	if len(movements) != 1 || movements[0].Quantity != 5 || movements[0].Reason != product.ReasonAdjustment {
+		t.Fatalf("expected the initial stock to be recorded as an adjustment, got %+v", movements)
+	}
+
+	assertEventTypes(t, store, product.EventCreated, product.EventStockChanged)
+}
+
+func TestCreateDuplicateName(t *testing.T) {
+	u, _ := newUsecase(t)
+	mustCreate(t, u, newProduct("product", 0))
+
+	_, err := u.Create(newProduct(" PRODUCT ", 0))
+	assertCode(t, err, product.CodeAlreadyExists)
+}
+
+func TestCreateConcurrentDuplicateName(t *testing.T) {
+	u, _ := newUsecase(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := u.Create(newProduct("product", 1))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assertCode(t, err, product.CodeAlreadyExists)
+	}
+	if created != 1 {
+		t.Fatalf("expected exactly one product to be created, got %d", created)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	u, store := newUsecase(t)
+
+	p := newProduct("p", -1)
+	p.Unit = ""
+	_, err := u.Create(p)
+	assertCode(t, err, product.CodeValidation)
+
+	fields := product.ErrorFields(err)
+	for field, tag := range map[string]string{"name": "gte=2", "unit": "required", "stock": "gte=0"} {
+		if fields[field] != tag {
+			t.Fatalf("expected field %q to fail %q, got %v", field, tag, fields)
+		}
+	}
+
+	assertEventTypes(t, store)
+}
+
+func TestCreatePriceAndCurrency(t *testing.T) {
+	u, _ := newUsecase(t)
+
+	p := mustCreate(t, u, newProduct("default", 0))
+	if p.Currency != product.DefaultCurrency {
+		t.Fatalf("expected currency %s, got %s", product.DefaultCurrency, p.Currency)
+	}
+
+	p = newProduct("exact", 0)
+	p.Price = decimal.RequireFromString("0.1").Add(decimal.RequireFromString("0.2"))
+	p.Currency = " eur "
+	p = mustCreate(t, u, p)
+	if p.Currency != "EUR" {
+		t.Fatalf("expected currency EUR, got %s", p.Currency)
+	}
+
+	// El precio viaja en JSON como string, sin pérdida de precisión
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	decoded := &product.Product{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"price":"0.3"`) || !decoded.Price.Equal(decimal.RequireFromString("0.3")) {
+		t.Fatalf("expected price 0.3, got %s", data)
+	}
+
+	p = newProduct("invalid currency", 0)
+	p.Currency = "XYZ"
+	_, err = u.Create(p)
+	assertCode(t, err, product.CodeValidation)
+	if fields := product.ErrorFields(err); fields["currency"] != "iso4217" {
+		t.Fatalf("expected currency to fail iso4217, got %v", fields)
+	}
+
+	p = newProduct("too many decimals", 0)
+	p.Price = decimal.RequireFromString("1.23456")
+	_, err = u.Create(p)
+	assertCode(t, err, product.CodeValidation)
+
+	p = newProduct("zero price", 0)
+	p.Price = decimal.Zero
+	_, err = u.Create(p)
+	assertCode(t, err, product.CodeValidation)
+
+	p = newProduct("negative price", 0)
+	p.Price = decimal.RequireFromString("-1.5")
+	_, err = u.Create(p)
+	assertCode(t, err, product.CodeValidation)
+	if fields := product.ErrorFields(err); fields["price"] != "gte=0" {
+		t.Fatalf("expected price to fail gte=0, got %v", fields)
+	}
+
+	p = mustCreate(t, u, newProduct("negative update", 0))
+	p.Price = decimal.RequireFromString("-1.5")
+	_, err = u.Update(p)
+	assertCode(t, err, product.CodeValidation)
+	if fields := product.ErrorFields(err); fields["price"] != "gte=0" {
+		t.Fatalf("expected price to fail gte=0, got %v", fields)
+	}
+}
+
+func TestGetAllCursor(t *testing.T) {
+	u, _ := newUsecase(t)
+	for _, name := range []string{"a1", "a2", "a3", "a4", "a5"} {
+		mustCreate(t, u, newProduct(name, 0))
+	}
+
+	got := []string{}
+	query := &product.Query{Limit: 2, SortBy: product.SortByName, SortOrder: product.SortDesc}
+	for {
+		page, err := u.GetAll(query)
+		if err != nil {
+			t.Fatalf("GetAll: unexpected error: %v", err)
+		}
+		if page.Total != 5 {
+			t.Fatalf("expected total 5, got %d", page.Total)
+		}
+		for _, p := range page.Products {
+			got = append(got, p.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	if len(got) != 5 || got[0] != "a5" || got[4] != "a1" {
+		t.Fatalf("expected all products in descending order, got %v", got)
+	}
+
+	_, err := u.GetAll(&product.Query{Cursor: "invalid"})
+	assertCode(t, err, product.CodeValidation)
+}
+
+func TestExportProducts(t *testing.T) {
+	u, _ := newUsecase(t)
+	for _, name := range []string{"b1", "b2", "b3", "other"} {
+		mustCreate(t, u, newProduct(name, 0))
+	}
+
+	// El offset se ignora: se recorren todas las páginas por cursor
+	got := []string{}
+	query := &product.Query{Limit: 2, Offset: 10, NamePrefix: "b"}
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatalf("expected the export to end, got %v", got)
+		}
+		page, err := u.ExportProducts(query)
+		if err != nil {
+			t.Fatalf("ExportProducts: unexpected error: %v", err)
+		}
+		if page.Total != 0 {
+			t.Fatalf("expected no total, got %d", page.Total)
+		}
+		for _, p := range page.Products {
+			got = append(got, p.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	if fmt.Sprint(got) != "[b1 b2 b3]" {
+		t.Fatalf("expected products b1, b2 and b3, got %v", got)
+	}
+
+	page, err := u.ExportProducts(nil)
+	if err != nil {
+		t.Fatalf("ExportProducts: unexpected error: %v", err)
+	}
+	if len(page.Products) != 4 || page.NextCursor != "" {
+		t.Fatalf("expected all products in one page, got %d (next cursor %q)", len(page.Products), page.NextCursor)
+	}
+
+	_, err = u.ExportProducts(&product.Query{SortBy: "invalid"})
+	assertCode(t, err, product.CodeValidation)
+}
+
+func TestUpdate(t *testing.T) {
+	u, store := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 5))
+	other := mustCreate(t, u, newProduct("other", 0))
+
+	// El stock informado se ignora: un pedido sin stock no lo pone en cero
+	p.Description = "description"
+	p.Stock = 0
+	updated, err := u.Update(p)
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if updated.Description != "description" || updated.Stock != 5 {
+		t.Fatalf("unexpected product %+v", updated)
+	}
+
+	movements, err := u.GetStockMovements(p.ID)
+	if err != nil {
+		t.Fatalf("GetStockMovements: unexpected error: %v", err)
+	}
+	if len(movements) != 1 {
+		t.Fatalf("expected no stock movement for the update, got %+v", movements)
+	}
+
+	other.Name = "product"
+	_, err = u.Update(other)
+	assertCode(t, err, product.CodeAlreadyExists)
+
+	assertEventTypes(t, store,
+		product.EventCreated, product.EventStockChanged, // product
+		product.EventCreated, // other
+		product.EventUpdated, // update
+	)
+}
+
+func TestUpdateConflict(t *testing.T) {
+	u, _ := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 0))
+
+	stale := *p
+	p.Price = decimal.NewFromInt(20)
+	if _, err := u.Update(p); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	stale.Price = decimal.NewFromInt(30)
+	_, err := u.Update(&stale)
+	assertCode(t, err, product.CodeVersionMismatch)
+
+	current, err := u.GetByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if !current.Price.Equal(decimal.NewFromInt(20)) {
+		t.Fatalf("expected price 20, got %v", current.Price)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	u, store := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 0))
+
+	if err := u.Delete(p); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	_, err := u.GetByID(p.ID)
+	assertCode(t, err, product.CodeNotFound)
+
+	err = u.Delete(p)
+	assertCode(t, err, product.CodeNotFound)
+
+	assertEventTypes(t, store, product.EventCreated, product.EventDeleted)
+}
+
+func TestRestorePurge(t *testing.T) {
+	u, store := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 0))
+
+	_, err := u.Restore(p)
+	assertCode(t, err, product.CodeConflict)
+
+	if err := u.Delete(p); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	restored, err := u.Restore(&product.Product{ID: p.ID})
+	if err != nil {
+		t.Fatalf("Restore: unexpected error: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Fatalf("expected the product to be restored, got %+v", restored)
+	}
+
+	if err := u.Purge(restored); err != nil {
+		t.Fatalf("Purge: unexpected error: %v", err)
+	}
+	_, err = u.GetByIDIncludeDeleted(p.ID)
+	assertCode(t, err, product.CodeNotFound)
+
+	err = u.Purge(restored)
+	assertCode(t, err, product.CodeNotFound)
+
+	assertEventTypes(t, store, product.EventCreated, product.EventDeleted, product.EventRestored, product.EventPurged)
+}
+
+func TestUpdateStock(t *testing.T) {
+	u, _ := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 5))
+
+	p, err := u.UpdateStock(p.ID, 2)
+	if err != nil {
+		t.Fatalf("UpdateStock: unexpected error: %v", err)
+	}
+	if p.Stock != 2 {
+		t.Fatalf("expected stock 2, got %v", p.Stock)
+	}
+
+	_, err = u.UpdateStock(p.ID, -1)
+	assertCode(t, err, product.CodeValidation)
+
+	_, err = u.UpdateStock(p.ID+100, 1)
+	assertCode(t, err, product.CodeNotFound)
+}
+
+func TestAddStockMovement(t *testing.T) {
+	u, _ := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 5))
+
+	_, err := u.AddStockMovement(&product.StockMovement{ProductID: p.ID, Quantity: -6, Reason: product.ReasonSale})
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+	assertCode(t, err, product.CodeConflict)
+
+	_, err = u.AddStockMovement(&product.StockMovement{ProductID: p.ID, Quantity: 1, Reason: "gift"})
+	assertCode(t, err, product.CodeValidation)
+
+	p, err = u.AddStockMovement(&product.StockMovement{ProductID: p.ID, Quantity: -5, Reason: product.ReasonSale})
+	if err != nil {
+		t.Fatalf("AddStockMovement: unexpected error: %v", err)
+	}
+	if p.Stock != 0 {
+		t.Fatalf("expected stock 0, got %v", p.Stock)
+	}
+}
+
+func TestReservations(t *testing.T) {
+	u, _ := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 10))
+
+	reservation, err := u.Reserve(&product.Reservation{ProductID: p.ID, Quantity: 4})
+	if err != nil {
+		t.Fatalf("Reserve: unexpected error: %v", err)
+	}
+	if reservation.ID == "" || reservation.Status != product.ReservationPending {
+		t.Fatalf("unexpected reservation %+v", reservation)
+	}
+
+	_, err = u.Reserve(&product.Reservation{ProductID: p.ID, Quantity: 7})
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+
+	reservation, err = u.ConfirmReservation(reservation.ID)
+	if err != nil {
+		t.Fatalf("ConfirmReservation: unexpected error: %v", err)
+	}
+	if reservation.Status != product.ReservationConfirmed {
+		t.Fatalf("expected status %q, got %q", product.ReservationConfirmed, reservation.Status)
+	}
+
+	p, err = u.GetByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Stock != 6 || p.Reserved != 0 {
+		t.Fatalf("expected stock 6 and nothing reserved, got %+v", p)
+	}
+
+	_, err = u.ReleaseReservation(reservation.ID)
+	assertCode(t, err, product.CodeConflict)
+
+	_, err = u.GetReservation("missing")
+	assertCode(t, err, product.CodeNotFound)
+}
+
+func TestExpireReservations(t *testing.T) {
+	u, store := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 10))
+
+	expired := &product.Reservation{
+		ID:        "expired",
+		ProductID: p.ID,
+		Quantity:  3,
+		Status:    product.ReservationPending,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	if _, err := store.CreateReservation(expired); err != nil {
+		t.Fatalf("CreateReservation: unexpected error: %v", err)
+	}
+	if _, err := u.Reserve(&product.Reservation{ID: "pending", ProductID: p.ID, Quantity: 2}); err != nil {
+		t.Fatalf("Reserve: unexpected error: %v", err)
+	}
+
+	n, err := u.ExpireReservations()
+	if err != nil {
+		t.Fatalf("ExpireReservations: unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 expired reservation, got %d", n)
+	}
+
+	_, err = u.ConfirmReservation("expired")
+	assertCode(t, err, product.CodeConflict)
+
+	p, err = u.GetByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Reserved != 2 {
+		t.Fatalf("expected 2 reserved, got %v", p.Reserved)
+	}
+}
+
+func TestExpireReservationsOfRemovedProducts(t *testing.T) {
+	u, store := newUsecase(t)
+	deleted := mustCreate(t, u, newProduct("deleted", 10))
+	purged := mustCreate(t, u, newProduct("purged", 10))
+
+	expiresAt := time.Now().Add(-time.Minute)
+	for id, productID := range map[string]uint{"deleted": deleted.ID, "purged": purged.ID} {
+		reservation := &product.Reservation{ID: id, ProductID: productID, Quantity: 1, Status: product.ReservationPending, ExpiresAt: expiresAt}
+		if _, err := store.CreateReservation(reservation); err != nil {
+			t.Fatalf("CreateReservation: unexpected error: %v", err)
+		}
+	}
+
+	if err := u.Delete(deleted); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if err := u.Purge(purged); err != nil {
+		t.Fatalf("Purge: unexpected error: %v", err)
+	}
+
+	// La reserva del producto eliminado vence; la del producto purgado ya se liberó
+	n, err := u.ExpireReservations()
+	if err != nil {
+		t.Fatalf("ExpireReservations: unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 expired reservation, got %d", n)
+	}
+
+	for id, status := range map[string]string{"deleted": product.ReservationExpired, "purged": product.ReservationReleased} {
+		reservation, err := u.GetReservation(id)
+		if err != nil {
+			t.Fatalf("GetReservation: unexpected error: %v", err)
+		}
+		if reservation.Status != status {
+			t.Fatalf("expected reservation %s with status %q, got %q", id, status, reservation.Status)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	u, _ := newUsecase(t)
+
+	p := mustCreate(t, u.WithActor("alice"), newProduct("product", 5))
+
+	p.Price = decimal.NewFromInt(12)
+	p, err := u.WithActor("bob").Update(p)
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	// Una modificación sin cambios no se registra
+	if _, err := u.WithActor("bob").Update(p); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	if _, err := u.UpdateStock(p.ID, 3); err != nil {
+		t.Fatalf("UpdateStock: unexpected error: %v", err)
+	}
+	if err := u.WithActor("alice").Delete(p); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	page, err := u.History(&product.HistoryQuery{ProductID: p.ID})
+	if err != nil {
+		t.Fatalf("History: unexpected error: %v", err)
+	}
+	if page.Total != 4 || len(page.Entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d of %d", len(page.Entries), page.Total)
+	}
+
+	expected := []struct {
+		actor     string
+		operation string
+		field     string
+	}{
+		{"alice", product.OperationDelete, "deleted_at"},
+		{product.UnknownActor, product.OperationUpdateStock, "stock"},
+		{"bob", product.OperationUpdate, "price"},
+		{"alice", product.OperationCreate, "name"},
+	}
+	for i, e := range expected {
+		entry := page.Entries[i]
+		if entry.Actor != e.actor || entry.Operation != e.operation {
+			t.Fatalf("entry %d: expected %s by %s, got %s by %s", i, e.operation, e.actor, entry.Operation, entry.Actor)
+		}
+
+		found := false
+		for _, change := range entry.Changes {
+			found = found || change.Field == e.field
+		}
+		if !found {
+			t.Fatalf("entry %d: expected a change in %s, got %+v", i, e.field, entry.Changes)
+		}
+	}
+
+	update := page.Entries[2]
+	if len(update.Changes) != 1 || update.Changes[0].Old != "10" || update.Changes[0].New != "12" {
+		t.Fatalf("expected only the price to change from 10 to 12, got %+v", update.Changes)
+	}
+
+	_, err = u.History(&product.HistoryQuery{})
+	assertCode(t, err, product.CodeValidation)
+}
+
+func TestScheduledPrices(t *testing.T) {
+	u, store := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 0))
+
+	p.Price = decimal.NewFromInt(12)
+	p, err := u.Update(p)
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	_, err = u.SchedulePrice(&product.Price{ProductID: p.ID, Price: decimal.NewFromInt(20), EffectiveFrom: time.Now().Add(-time.Minute)})
+	assertCode(t, err, product.CodeValidation)
+	_, err = u.SchedulePrice(&product.Price{ProductID: p.ID, Price: decimal.NewFromInt(-20), EffectiveFrom: time.Now().Add(time.Hour)})
+	assertCode(t, err, product.CodeValidation)
+	if fields := product.ErrorFields(err); fields["price"] != "gte=0" {
+		t.Fatalf("expected price to fail gte=0, got %v", fields)
+	}
+
+	later, err := u.SchedulePrice(&product.Price{ProductID: p.ID, Price: decimal.NewFromInt(20), EffectiveFrom: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("SchedulePrice: unexpected error: %v", err)
+	}
+	cancelled, err := u.SchedulePrice(&product.Price{ProductID: p.ID, Price: decimal.NewFromInt(30), EffectiveFrom: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("SchedulePrice: unexpected error: %v", err)
+	}
+	if _, err := u.CancelPrice(cancelled.ID); err != nil {
+		t.Fatalf("CancelPrice: unexpected error: %v", err)
+	}
+	_, err = u.CancelPrice(cancelled.ID)
+	assertCode(t, err, product.CodeConflict)
+
+	// Un precio que ya debe aplicarse, como si el worker lo encontrara vencido
+	due := &product.Price{ProductID: p.ID, Price: decimal.NewFromInt(15), Status: product.PriceScheduled, EffectiveFrom: time.Now().Add(-time.Second)}
+	if err := store.AddPrice(due); err != nil {
+		t.Fatalf("AddPrice: unexpected error: %v", err)
+	}
+
+	n, err := u.ApplyScheduledPrices()
+	if err != nil {
+		t.Fatalf("ApplyScheduledPrices: unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 applied price, got %d", n)
+	}
+
+	p, err = u.GetByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if !p.Price.Equal(decimal.NewFromInt(15)) {
+		t.Fatalf("expected price 15, got %v", p.Price)
+	}
+	assertEventTypes(t, store, product.EventCreated, product.EventUpdated, product.EventUpdated)
+
+	history, err := u.GetPrices(p.ID)
+	if err != nil {
+		t.Fatalf("GetPrices: unexpected error: %v", err)
+	}
+	past := []string{}
+	for _, price := range history.Past {
+		past = append(past, price.Price.String())
+	}
+	if fmt.Sprint(past) != "[15 12 10]" {
+		t.Fatalf("expected past prices [15 12 10], got %v", past)
+	}
+	if history.Past[0].EffectiveTo != nil || history.Past[1].EffectiveTo == nil {
+		t.Fatalf("expected only the current price to be open")
+	}
+	if len(history.Upcoming) != 1 || history.Upcoming[0].ID != later.ID {
+		t.Fatalf("expected upcoming price %d, got %+v", later.ID, history.Upcoming)
+	}
+
+	page, err := u.History(&product.HistoryQuery{ProductID: p.ID, Limit: 1})
+	if err != nil {
+		t.Fatalf("History: unexpected error: %v", err)
+	}
+	if entry := page.Entries[0]; entry.Operation != product.OperationApplyPrice || entry.Actor != product.SchedulerActor {
+		t.Fatalf("expected the price change by %s, got %+v", product.SchedulerActor, entry)
+	}
+}
+
+func TestScheduledPriceOfDeletedProduct(t *testing.T) {
+	u, store := newUsecase(t)
+	p := mustCreate(t, u, newProduct("product", 0))
+
+	due := &product.Price{ProductID: p.ID, Price: decimal.NewFromInt(15), Status: product.PriceScheduled, EffectiveFrom: time.Now().Add(-time.Second)}
+	if err := store.AddPrice(due); err != nil {
+		t.Fatalf("AddPrice: unexpected error: %v", err)
+	}
+	if err := u.Delete(p); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	n, err := u.ApplyScheduledPrices()
+	if err != nil {
+		t.Fatalf("ApplyScheduledPrices: unexpected error: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected no applied prices, got %d", n)
+	}
+
+	price, err := store.GetPrice(due.ID)
+	if err != nil {
+		t.Fatalf("GetPrice: unexpected error: %v", err)
+	}
+	if price.Status != product.PriceCancelled {
+		t.Fatalf("expected the price to be cancelled, got %s", price.Status)
+	}
+}
+
+func TestPriceLists(t *testing.T) {
+	u, _ := newUsecase(t)
+	listed := mustCreate(t, u, newProduct("listed", 0))
+	unlisted := mustCreate(t, u, newProduct("unlisted", 0))
+
+	_, err := u.CreatePriceList(&product.PriceList{Name: "retail-XYZ", Currency: "XYZ"})
+	assertCode(t, err, product.CodeValidation)
+
+	for _, list := range []*product.PriceList{{Name: " retail-ARS ", Currency: "ars"}, {Name: "wholesale-USD", Currency: "USD"}} {
+		if _, err := u.CreatePriceList(list); err != nil {
+			t.Fatalf("CreatePriceList: unexpected error: %v", err)
+		}
+	}
+
+	// Un producto inexistente descarta toda la modificación
+	_, err = u.UpdateListPrices(&product.ListPricesUpdate{
+		PriceList: "retail-ARS",
+		Prices: []*product.ListPrice{
+			{ProductID: listed.ID, Price: decimal.NewFromInt(1500)},
+			{ProductID: 1000, Price: decimal.NewFromInt(1)},
+		},
+	})
+	assertCode(t, err, product.CodeNotFound)
+
+	_, err = u.UpdateListPrices(&product.ListPricesUpdate{
+		PriceList:  "retail-ARS",
+		Prices:     []*product.ListPrice{{ProductID: listed.ID, Price: decimal.NewFromInt(1500)}},
+		ProductIDs: []uint{listed.ID},
+	})
+	assertCode(t, err, product.CodeValidation)
+
+	_, err = u.UpdateListPrices(&product.ListPricesUpdate{
+		PriceList: "retail-ARS",
+		Prices:    []*product.ListPrice{{ProductID: listed.ID, Price: decimal.NewFromInt(-1500)}},
+	})
+	assertCode(t, err, product.CodeValidation)
+	if fields := product.ErrorFields(err); fields["prices[0].price"] != "gte=0" {
+		t.Fatalf("expected prices[0].price to fail gte=0, got %v", fields)
+	}
+
+	result, err := u.UpdateListPrices(&product.ListPricesUpdate{
+		PriceList: "retail-ARS",
+		Prices:    []*product.ListPrice{{ProductID: listed.ID, Price: decimal.NewFromInt(1500)}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateListPrices: unexpected error: %v", err)
+	}
+	if result.Updated != 1 || result.Removed != 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	page, err := u.GetListPrices(&product.ListPricesQuery{PriceList: "retail-ARS"})
+	if err != nil {
+		t.Fatalf("GetListPrices: unexpected error: %v", err)
+	}
+	if page.PriceList.Currency != "ARS" || page.Total != 1 || page.Prices[0].ProductID != listed.ID {
+		t.Fatalf("unexpected page %+v", page)
+	}
+
+	tests := []struct {
+		name      string
+		productID uint
+		priceList string
+		price     string
+		source    string
+		code      string
+	}{
+		{"list price", listed.ID, "retail-ARS", "1500", product.PriceSourceList, ""},
+		{"base price in the list currency", unlisted.ID, "wholesale-USD", "10", product.PriceSourceProduct, ""},
+		{"no price in the list currency", unlisted.ID, "retail-ARS", "", "", product.CodeNotFound},
+		{"missing list", listed.ID, "missing", "", "", product.CodeNotFound},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := u.ResolvePrice(tt.productID, tt.priceList)
+			if tt.code != "" {
+				assertCode(t, err, tt.code)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolvePrice: unexpected error: %v", err)
+			}
+			if resolved.Price.String() != tt.price || resolved.Source != tt.source {
+				t.Fatalf("expected price %s from %s, got %+v", tt.price, tt.source, resolved)
+			}
+		})
+	}
+
+	result, err = u.UpdateListPrices(&product.ListPricesUpdate{PriceList: "retail-ARS", ProductIDs: []uint{listed.ID}})
+	if err != nil {
+		t.Fatalf("UpdateListPrices: unexpected error: %v", err)
+	}
+	if result.Removed != 1 {
+		t.Fatalf("expected 1 removed price, got %+v", result)
+	}
+
+	if err := u.DeletePriceList("retail-ARS"); err != nil {
+		t.Fatalf("DeletePriceList: unexpected error: %v", err)
+	}
+	lists, err := u.GetPriceLists()
+	if err != nil {
+		t.Fatalf("GetPriceLists: unexpected error: %v", err)
+	}
+	if len(lists) != 1 || lists[0].Name != "wholesale-USD" {
+		t.Fatalf("expected only wholesale-USD, got %+v", lists)
+	}
+}
+
+func TestCategories(t *testing.T) {
+	u, _ := newUsecase(t)
+
+	mustCreateCategory := func(name string, parent *product.Category) *product.Category {
+		t.Helper()
+
+		category := &product.Category{Name: name}
+		if parent != nil {
+			category.ParentID = &parent.ID
+		}
+		created, err := u.CreateCategory(category)
+		if err != nil {
+			t.Fatalf("CreateCategory(%q): unexpected error: %v", name, err)
+		}
+		return created
+	}
+
+	_, err := u.CreateCategory(&product.Category{Name: " "})
+	assertCode(t, err, product.CodeValidation)
+	missing := uint(1000)
+	_, err = u.CreateCategory(&product.Category{Name: "Orphan", ParentID: &missing})
+	assertCode(t, err, product.CodeNotFound)
+
+	tools := mustCreateCategory(" Tools ", nil)
+	power := mustCreateCategory("Power tools", tools)
+	drills := mustCreateCategory("Drills", power)
+	garden := mustCreateCategory("Garden", nil)
+	if tools.Name != "Tools" || drills.Depth() != 3 {
+		t.Fatalf("unexpected categories %+v, %+v", tools, drills)
+	}
+
+	// Una categoría no puede moverse debajo de sí misma ni de sus subcategorías
+	for _, parent := range []*product.Category{tools, drills} {
+		_, err = u.UpdateCategory(&product.Category{ID: tools.ID, Name: "Tools", ParentID: &parent.ID})
+		assertCode(t, err, product.CodeValidation)
+	}
+
+	// Ni superar la profundidad máxima
+	deepest := garden
+	for i := 1; i < product.MaxCategoryDepth; i++ {
+		deepest = mustCreateCategory(fmt.Sprintf("Level %d", i+1), deepest)
+	}
+	_, err = u.CreateCategory(&product.Category{Name: "Too deep", ParentID: &deepest.ID})
+	assertCode(t, err, product.CodeValidation)
+	_, err = u.UpdateCategory(&product.Category{ID: power.ID, Name: "Power tools", ParentID: &deepest.ID})
+	assertCode(t, err, product.CodeValidation)
+
+	drill := mustCreate(t, u, newProduct("drill", 0))
+	hammer := mustCreate(t, u, newProduct("hammer", 0))
+	mustCreate(t, u, newProduct("rake", 0))
+
+	_, err = u.SetProductCategories(&product.ProductCategories{ProductID: drill.ID, CategoryIDs: []uint{drills.ID, missing}})
+	assertCode(t, err, product.CodeNotFound)
+	assigned, err := u.SetProductCategories(&product.ProductCategories{ProductID: drill.ID, CategoryIDs: []uint{drills.ID, drills.ID}})
+	if err != nil {
+		t.Fatalf("SetProductCategories: unexpected error: %v", err)
+	}
+	if len(assigned) != 1 || assigned[0].ID != drills.ID {
+		t.Fatalf("unexpected categories %+v", assigned)
+	}
+	if _, err := u.SetProductCategories(&product.ProductCategories{ProductID: hammer.ID, CategoryIDs: []uint{tools.ID}}); err != nil {
+		t.Fatalf("SetProductCategories: unexpected error: %v", err)
+	}
+
+	// El listado por categoría incluye los productos de sus subcategorías
+	page, err := u.GetAll(&product.Query{CategoryID: &tools.ID})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	if page.Total != 2 {
+		t.Fatalf("expected 2 products in %s, got %d", tools.Name, page.Total)
+	}
+	_, err = u.GetAll(&product.Query{CategoryID: &missing})
+	assertCode(t, err, product.CodeNotFound)
+
+	moved, err := u.UpdateCategory(&product.Category{ID: power.ID, Name: "Power tools", ParentID: &garden.ID})
+	if err != nil {
+		t.Fatalf("UpdateCategory: unexpected error: %v", err)
+	}
+	if moved.Depth() != 2 {
+		t.Fatalf("unexpected depth %d", moved.Depth())
+	}
+	page, err = u.GetAll(&product.Query{CategoryID: &garden.ID})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	if page.Total != 1 || page.Products[0].ID != drill.ID {
+		t.Fatalf("expected the drill in %s, got %+v", garden.Name, page.Products)
+	}
+
+	err = u.DeleteCategory(power.ID)
+	assertCode(t, err, product.CodeConflict)
+	if err := u.DeleteCategory(drills.ID); err != nil {
+		t.Fatalf("DeleteCategory: unexpected error: %v", err)
+	}
+	categories, err := u.GetProductCategories(drill.ID)
+	if err != nil {
+		t.Fatalf("GetProductCategories: unexpected error: %v", err)
+	}
+	if len(categories) != 0 {
+		t.Fatalf("expected no categories, got %+v", categories)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	u, store := newUsecase(t)
+	shirt := mustCreate(t, u, newProduct("shirt", 0))
+	other := mustCreate(t, u, newProduct("other", 0))
+
+	negative := decimal.NewFromInt(-1)
+	_, err := u.CreateVariant(&product.Variant{ProductID: shirt.ID, SKU: "shirt-m", Price: &negative})
+	assertCode(t, err, product.CodeValidation)
+	_, err = u.CreateVariant(&product.Variant{ProductID: 1000, SKU: "shirt-m"})
+	assertCode(t, err, product.CodeNotFound)
+
+	medium, err := u.CreateVariant(&product.Variant{ProductID: shirt.ID, SKU: " shirt-m ", Options: map[string]string{"size": "M"}, Stock: 4})
+	if err != nil {
+		t.Fatalf("CreateVariant: unexpected error: %v", err)
+	}
+	if medium.SKU != "SHIRT-M" || medium.Stock != 4 {
+		t.Fatalf("unexpected variant %+v", medium)
+	}
+	if _, err := u.GetVariantBySKU("shirt-m"); err != nil {
+		t.Fatalf("GetVariantBySKU: unexpected error: %v", err)
+	}
+	_, err = u.GetVariant(other.ID, medium.ID)
+	assertCode(t, err, product.CodeNotFound)
+
+	// El stock inicial de la variante se suma al del producto
+	p, err := u.GetByID(shirt.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Stock != 4 {
+		t.Fatalf("expected product stock 4, got %v", p.Stock)
+	}
+
+	// Las operaciones de stock de un producto con variantes deben indicar la variante
+	_, err = u.AddStockMovement(&product.StockMovement{ProductID: shirt.ID, Quantity: 1, Reason: product.ReasonPurchase})
+	assertCode(t, err, product.CodeValidation)
+	_, err = u.UpdateStock(shirt.ID, 10)
+	assertCode(t, err, product.CodeValidation)
+	_, err = u.Reserve(&product.Reservation{ProductID: shirt.ID, Quantity: 1})
+	assertCode(t, err, product.CodeValidation)
+
+	reservation, err := u.Reserve(&product.Reservation{ProductID: shirt.ID, VariantID: &medium.ID, Quantity: 3})
+	if err != nil {
+		t.Fatalf("Reserve: unexpected error: %v", err)
+	}
+	if _, err := u.ConfirmReservation(reservation.ID); err != nil {
+		t.Fatalf("ConfirmReservation: unexpected error: %v", err)
+	}
+	variant, err := u.GetVariant(shirt.ID, medium.ID)
+	if err != nil {
+		t.Fatalf("GetVariant: unexpected error: %v", err)
+	}
+	if variant.Stock != 1 || variant.Reserved != 0 {
+		t.Fatalf("expected variant stock 1 and reserved 0, got %v and %v", variant.Stock, variant.Reserved)
+	}
+	movements, err := store.GetStockMovements(shirt.ID)
+	if err != nil {
+		t.Fatalf("GetStockMovements: unexpected error: %v", err)
+	}
+	last := movements[len(movements)-1]
+	if last.VariantID == nil || *last.VariantID != medium.ID || last.Quantity != -3 {
+		t.Fatalf("expected a sale movement of the variant, got %+v", last)
+	}
+
+	price := decimal.RequireFromString("12.5")
+	updated, err := u.UpdateVariant(&product.Variant{ID: medium.ID, ProductID: shirt.ID, SKU: "shirt-m-blue", Options: map[string]string{"size": "M", "color": "blue"}, Price: &price})
+	if err != nil {
+		t.Fatalf("UpdateVariant: unexpected error: %v", err)
+	}
+	if updated.SKU != "SHIRT-M-BLUE" || updated.Stock != 1 || !updated.Price.Equal(price) {
+		t.Fatalf("unexpected variant %+v", updated)
+	}
+	_, err = u.UpdateVariant(&product.Variant{ID: medium.ID, ProductID: other.ID, SKU: "x"})
+	assertCode(t, err, product.CodeNotFound)
+
+	err = u.DeleteVariant(shirt.ID, medium.ID)
+	assertCode(t, err, product.CodeConflict)
+	if _, err := u.AddStockMovement(&product.StockMovement{ProductID: shirt.ID, VariantID: &medium.ID, Quantity: -1, Reason: product.ReasonSale}); err != nil {
+		t.Fatalf("AddStockMovement: unexpected error: %v", err)
+	}
+	if err := u.DeleteVariant(shirt.ID, medium.ID); err != nil {
+		t.Fatalf("DeleteVariant: unexpected error: %v", err)
+	}
+	variants, err := u.GetVariants(shirt.ID)
+	if err != nil {
+		t.Fatalf("GetVariants: unexpected error: %v", err)
+	}
+	if len(variants) != 0 {
+		t.Fatalf("expected no variants, got %+v", variants)
+	}
+}
+
+func TestLocations(t *testing.T) {
+	u, store := newUsecase(t)
+
+	warehouse, err := u.CreateLocation(&product.Location{Code: " Warehouse ", Name: "Main warehouse"})
+	if err != nil {
+		t.Fatalf("CreateLocation: unexpected error: %v", err)
+	}
+	if warehouse.Code != "warehouse" {
+		t.Fatalf("expected a normalized code, got %q", warehouse.Code)
+	}
+	_, err = u.CreateLocation(&product.Location{Code: "WAREHOUSE", Name: "Other"})
+	assertCode(t, err, product.CodeAlreadyExists)
+	_, err = u.CreateLocation(&product.Location{Code: "store"})
+	assertCode(t, err, product.CodeValidation)
+
+	defaultLocation, err := store.GetLocationByCode(product.DefaultLocationCode)
+	if err != nil {
+		t.Fatalf("GetLocationByCode: unexpected error: %v", err)
+	}
+
+	// El stock inicial se registra en la ubicación por defecto
+	created := mustCreate(t, u, newProduct("product", 10))
+	if _, err := u.AddStockMovement(&product.StockMovement{ProductID: created.ID, LocationID: &warehouse.ID, Quantity: 2, Reason: product.ReasonPurchase}); err != nil {
+		t.Fatalf("AddStockMovement: unexpected error: %v", err)
+	}
+	missing := warehouse.ID + 100
+	_, err = u.AddStockMovement(&product.StockMovement{ProductID: created.ID, LocationID: &missing, Quantity: 2, Reason: product.ReasonPurchase})
+	assertCode(t, err, product.CodeNotFound)
+
+	// Las transferencias sólo se registran con TransferStock
+	_, err = u.AddStockMovement(&product.StockMovement{ProductID: created.ID, Quantity: 2, Reason: product.ReasonTransfer})
+	assertCode(t, err, product.CodeValidation)
+	_, err = u.TransferStock(&product.StockTransfer{ProductID: created.ID, FromLocationID: warehouse.ID, ToLocationID: warehouse.ID, Quantity: 1})
+	assertCode(t, err, product.CodeValidation)
+	_, err = u.TransferStock(&product.StockTransfer{ProductID: created.ID, FromLocationID: warehouse.ID, ToLocationID: missing, Quantity: 1})
+	assertCode(t, err, product.CodeNotFound)
+	_, err = u.TransferStock(&product.StockTransfer{ProductID: created.ID, FromLocationID: warehouse.ID, ToLocationID: defaultLocation.ID, Quantity: 3})
+	assertCode(t, err, product.CodeConflict)
+
+	movements, err := u.TransferStock(&product.StockTransfer{ProductID: created.ID, FromLocationID: defaultLocation.ID, ToLocationID: warehouse.ID, Quantity: 7, Reference: "transfer 1"})
+	if err != nil {
+		t.Fatalf("TransferStock: unexpected error: %v", err)
+	}
+	if len(movements) != 2 || *movements[0].LocationID != defaultLocation.ID || *movements[1].LocationID != warehouse.ID {
+		t.Fatalf("unexpected transfer movements %+v", movements)
+	}
+
+	levels, err := u.GetStockLevels(&product.StockLevelQuery{ProductID: created.ID})
+	if err != nil {
+		t.Fatalf("GetStockLevels: unexpected error: %v", err)
+	}
+	total := 0.0
+	for _, level := range levels {
+		total += level.Quantity
+	}
+	p, err := u.GetByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if len(levels) != 2 || levels[0].Quantity != 3 || levels[1].Quantity != 9 || p.Stock != total {
+		t.Fatalf("expected stock 3 and 9 adding up to the product stock %v, got %+v", p.Stock, levels)
+	}
+	_, err = u.GetStockLevels(&product.StockLevelQuery{})
+	assertCode(t, err, product.CodeValidation)
+
+	// Al confirmar una reserva, el stock sale de la ubicación de la reserva
+	_, err = u.Reserve(&product.Reservation{ProductID: created.ID, Quantity: 4})
+	assertCode(t, err, product.CodeConflict)
+	reservation, err := u.Reserve(&product.Reservation{ProductID: created.ID, LocationID: &warehouse.ID, Quantity: 4})
+	if err != nil {
+		t.Fatalf("Reserve: unexpected error: %v", err)
+	}
+
+	// El stock reservado en la ubicación no puede volver a reservarse ni transferirse, aunque el producto tenga stock disponible
+	_, err = u.Reserve(&product.Reservation{ProductID: created.ID, LocationID: &warehouse.ID, Quantity: 6})
+	assertCode(t, err, product.CodeConflict)
+	_, err = u.TransferStock(&product.StockTransfer{ProductID: created.ID, FromLocationID: warehouse.ID, ToLocationID: defaultLocation.ID, Quantity: 6})
+	assertCode(t, err, product.CodeConflict)
+
+	if _, err := u.ConfirmReservation(reservation.ID); err != nil {
+		t.Fatalf("ConfirmReservation: unexpected error: %v", err)
+	}
+	levels, err = u.GetStockLevels(&product.StockLevelQuery{LocationID: warehouse.ID})
+	if err != nil {
+		t.Fatalf("GetStockLevels: unexpected error: %v", err)
+	}
+	if len(levels) != 1 || levels[0].Quantity != 5 || levels[0].Reserved != 0 {
+		t.Fatalf("expected 5 left in the warehouse and nothing reserved, got %+v", levels)
+	}
+}
+
+func TestStockAlerts(t *testing.T) {
+	u, store := newUsecase(t)
+	p := newProduct("product", 10)
+	p.ReorderThreshold = 3
+	p.ReorderQuantity = 20
+	p = mustCreate(t, u, p)
+
+	_, err := u.Update(&product.Product{ID: p.ID, Name: p.Name, Unit: p.Unit, Price: p.Price, Stock: p.Stock, ReorderThreshold: -1})
+	assertCode(t, err, product.CodeValidation)
+
+	// Sólo se alerta al cruzar el umbral, no mientras el stock sigue por debajo
+	for _, quantity := range []float64{-6, -1, -1} {
+		if _, err := u.AddStockMovement(&product.StockMovement{ProductID: p.ID, Quantity: quantity, Reason: product.ReasonSale}); err != nil {
+			t.Fatalf("AddStockMovement: unexpected error: %v", err)
+		}
+	}
+	reservation, err := u.Reserve(&product.Reservation{ProductID: p.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("Reserve: unexpected error: %v", err)
+	}
+	if _, err := u.ReleaseReservation(reservation.ID); err != nil {
+		t.Fatalf("ReleaseReservation: unexpected error: %v", err)
+	}
+	assertEventTypes(t, store,
+		product.EventCreated, product.EventStockChanged,
+		product.EventStockChanged, product.EventStockChanged, product.EventStockLow, product.EventStockChanged,
+		product.EventStockChanged, product.EventStockOut, product.EventStockChanged)
+
+	// Al subir el umbral, el producto repuesto vuelve a tener stock bajo
+	if _, err := u.UpdateStock(p.ID, 5); err != nil {
+		t.Fatalf("UpdateStock: unexpected error: %v", err)
+	}
+	p, err = u.GetByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	p.ReorderThreshold = 5
+	if _, err := u.Update(p); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	types := eventTypes(t, store)
+	if last := types[len(types)-1]; last != product.EventStockLow {
+		t.Fatalf("expected a stock_low alert after raising the threshold, got %v", types)
+	}
+
+	page, err := u.GetAll(&product.Query{LowStock: true})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	if page.Total != 1 || page.Products[0].ID != p.ID || page.Products[0].ReorderQuantity != 20 {
+		t.Fatalf("expected the product in the low stock report, got %+v", page)
+	}
+}
+
+func TestImportProducts(t *testing.T) {
+	u, store := newUsecase(t)
+	existing := newProduct("Existing", 5)
+	existing.Description = "description"
+	existing = mustCreate(t, u, existing)
+
+	stock := func(f float64) *float64 { return &f }
+	text := func(s string) *string { return &s }
+	price := func(i int64) *decimal.Decimal { d := decimal.NewFromInt(i); return &d }
+	rows := []*product.ImportRow{
+		{Line: 2, Name: "new product", Unit: text("unit"), Price: price(3), Stock: stock(7)},
+		{Line: 3, Name: "EXISTING", Unit: text("box"), Stock: stock(9)},
+		{Line: 4, Name: "x", Unit: text("unit"), Price: price(1)},
+		{Line: 5, Name: "shirt", Unit: text("unit"), Price: price(20), SKU: "shirt-m", Options: map[string]string{"size": "M"}, Stock: stock(2)},
+		{Line: 6, Name: "negative", Unit: text("unit"), Price: price(1), Stock: stock(-1)},
+	}
+
+	// En modo de prueba se informa el resultado sin modificar nada
+	report, err := u.ImportProducts(&product.ImportRequest{Rows: rows, DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportProducts dry run: unexpected error: %v", err)
+	}
+	if !report.DryRun || report.Created != 2 || report.Updated != 1 || report.Failed != 2 || report.Results[0].ProductID != 0 {
+		t.Fatalf("unexpected dry run report %+v", report)
+	}
+	if _, err := u.GetByName("new product"); product.ErrorCode(err) != product.CodeNotFound {
+		t.Fatalf("expected the dry run not to create products, got %v", err)
+	}
+
+	report, err = u.ImportProducts(&product.ImportRequest{Rows: rows})
+	if err != nil {
+		t.Fatalf("ImportProducts: unexpected error: %v", err)
+	}
+	statuses := []string{}
+	for _, result := range report.Results {
+		statuses = append(statuses, result.Status)
+	}
+	expected := []string{product.ImportCreated, product.ImportUpdated, product.ImportFailed, product.ImportCreated, product.ImportFailed}
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected statuses %v, got %v", expected, statuses)
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Fatalf("expected statuses %v, got %v", expected, statuses)
+		}
+	}
+	if failed := report.Results[2]; failed.Line != 4 || failed.Code != product.CodeValidation || failed.Fields["name"] == "" {
+		t.Fatalf("unexpected failed row %+v", failed)
+	}
+
+	updated, err := u.GetByID(existing.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if updated.Unit != "box" || updated.Stock != 9 {
+		t.Fatalf("expected the existing product to be updated, got %+v", updated)
+	}
+	// Los atributos que no informa la fila conservan su valor
+	if updated.Description != "description" || !updated.Price.Equal(existing.Price) {
+		t.Fatalf("expected the existing product to keep its description and price, got %+v", updated)
+	}
+	variant, err := u.GetVariantBySKU("SHIRT-M")
+	if err != nil {
+		t.Fatalf("GetVariantBySKU: unexpected error: %v", err)
+	}
+	if variant.ProductID != report.Results[3].ProductID || variant.Stock != 2 {
+		t.Fatalf("unexpected imported variant %+v", variant)
+	}
+
+	// Una fila que falla no deja cambios parciales
+	if _, err := u.GetByName("negative"); product.ErrorCode(err) != product.CodeNotFound {
+		t.Fatalf("expected the failed row not to create a product, got %v", err)
+	}
+
+	// La variante se actualiza por SKU, y el precio de la fila es el de la variante
+	report, err = u.ImportProducts(&product.ImportRequest{Rows: []*product.ImportRow{
+		{Line: 2, Name: "shirt", Price: price(25), SKU: "SHIRT-M", Stock: stock(6)},
+	}})
+	if err != nil {
+		t.Fatalf("ImportProducts: unexpected error: %v", err)
+	}
+	if report.Updated != 1 || report.Results[0].VariantID != variant.ID {
+		t.Fatalf("unexpected report %+v", report.Results[0])
+	}
+	p, err := store.GetByID(variant.ProductID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Stock != 6 {
+		t.Fatalf("expected the variant stock to be adjusted to 6, got product stock %v", p.Stock)
+	}
+	if !p.Price.Equal(decimal.NewFromInt(20)) || p.Unit != "unit" {
+		t.Fatalf("expected the product to keep its price and unit, got %+v", p)
+	}
+	variant, err = u.GetVariantBySKU("SHIRT-M")
+	if err != nil {
+		t.Fatalf("GetVariantBySKU: unexpected error: %v", err)
+	}
+	if variant.Price == nil || !variant.Price.Equal(decimal.NewFromInt(25)) {
+		t.Fatalf("expected the variant price to be 25, got %v", variant.Price)
+	}
+
+	_, err = u.ImportProducts(&product.ImportRequest{})
+	assertCode(t, err, product.CodeValidation)
+}
+
+func TestBatch(t *testing.T) {
+	u, store := newUsecase(t)
+	existing := mustCreate(t, u, newProduct("existing", 5))
+	other := mustCreate(t, u, newProduct("other", 0))
+
+	stock := func(f float64) *float64 { return &f }
+	operations := func() []*product.BatchOperation {
+		update := newProduct("existing", 0)
+		update.Unit = "box"
+		return []*product.BatchOperation{
+			{Op: product.BatchCreate, Product: newProduct("new product", 3)},
+			{Op: product.BatchUpdate, ID: existing.ID, Product: update},
+			{Op: product.BatchStock, ID: existing.ID, Stock: stock(8)},
+			{Op: product.BatchStock, ID: 999, Stock: stock(1)},
+			{Op: product.BatchDelete, ID: other.ID},
+			{Op: "rename", ID: other.ID},
+		}
+	}
+
+	// Lote atómico: la operación fallida descarta todo el lote
+	events := len(eventTypes(t, store))
+	report, err := u.Batch(&product.BatchRequest{Operations: operations()})
+	if err != nil {
+		t.Fatalf("Batch: unexpected error: %v", err)
+	}
+	if report.Mode != product.BatchAtomic || report.Succeeded != 0 || report.Failed != 1 || report.Aborted != 5 {
+		t.Fatalf("unexpected atomic report %+v", report)
+	}
+	if failed := report.Results[3]; failed.Status != product.BatchFailed || failed.Code != product.CodeNotFound {
+		t.Fatalf("unexpected failed operation %+v", failed)
+	}
+	if _, err := u.GetByName("new product"); product.ErrorCode(err) != product.CodeNotFound {
+		t.Fatalf("expected the aborted batch not to create products, got %v", err)
+	}
+	if got := len(eventTypes(t, store)); got != events {
+		t.Fatalf("expected the aborted batch not to record events, got %d new", got-events)
+	}
+
+	// Lote best effort: cada operación se aplica o falla por separado
+	report, err = u.Batch(&product.BatchRequest{Mode: product.BatchBestEffort, Operations: operations()})
+	if err != nil {
+		t.Fatalf("Batch: unexpected error: %v", err)
+	}
+	if report.Succeeded != 4 || report.Failed != 2 || report.Aborted != 0 {
+		t.Fatalf("unexpected best effort report %+v", report)
+	}
+	if created := report.Results[0]; created.Status != product.BatchSucceeded || created.Product == nil || created.Product.Stock != 3 {
+		t.Fatalf("unexpected create result %+v", created)
+	}
+	if invalid := report.Results[5]; invalid.Code != product.CodeValidation || invalid.Fields["op"] == "" {
+		t.Fatalf("unexpected invalid operation result %+v", invalid)
+	}
+
+	updated, err := u.GetByID(existing.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if updated.Unit != "box" || updated.Stock != 8 {
+		t.Fatalf("expected the product to be updated, got %+v", updated)
+	}
+	if _, err := u.GetByID(other.ID); product.ErrorCode(err) != product.CodeNotFound {
+		t.Fatalf("expected the product to be deleted, got %v", err)
+	}
+
+	_, err = u.Batch(&product.BatchRequest{})
+	assertCode(t, err, product.CodeValidation)
+	_, err = u.Batch(&product.BatchRequest{Mode: "eventually", Operations: operations()})
+	assertCode(t, err, product.CodeValidation)
+}
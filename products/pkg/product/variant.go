@@ -0,0 +1,215 @@
+package product
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// ErrVariantHasStock indica que se intentó eliminar una variante con stock o con stock reservado
+var ErrVariantHasStock = errors.New("variant has stock")
+
+// Variant es una variante de un producto (por ejemplo, un talle o un color), identificada por un SKU único.
+// Cada variante tiene su propio stock, que se modifica con movimientos de stock y reservas que indican la variante.
+// El stock del producto es el total: incluye el de sus variantes, más el que se haya registrado antes de que las tuviera.
+type Variant struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`                                                                                         // Identificador de la variante
+	ProductID uint              `json:"product_id" gorm:"index" validate:"required"`                                                                  // Producto al que pertenece
+	SKU       string            `json:"sku" gorm:"size:64;uniqueIndex" validate:"required,lte=64"`                                                    // Código SKU, único, en mayúsculas
+	Options   map[string]string `json:"options,omitempty" gorm:"serializer:json" validate:"lte=10,dive,keys,required,lte=30,endkeys,required,lte=60"` // Valores de las opciones que distinguen a la variante (por ejemplo, size: M, color: red)
+	Price     *decimal.Decimal  `json:"price,omitempty"`                                                                                              // Precio propio de la variante, en la moneda del producto. Si es nil, vale el precio del producto
+	Stock     float64           `json:"stock" validate:"gte=0"`                                                                                       // Cantidad de la variante en stock. Se modifica a través de movimientos de stock
+	Reserved  float64           `json:"reserved" gorm:"not null;default:0"`                                                                           // Cantidad del stock comprometida en reservas pendientes
+	CreatedAt time.Time         `json:"created_at"`                                                                                                   // Momento en que se creó la variante
+	UpdatedAt time.Time         `json:"updated_at"`                                                                                                   // Momento de la última modificación
+}
+
+// MarshalJSON agrega a la variante el stock disponible, es decir, el stock que no está reservado
+func (v Variant) MarshalJSON() ([]byte, error) {
+	type alias Variant
+	return json.Marshal(struct {
+		alias
+		Available float64 `json:"available"`
+	}{
+		alias:     alias(v),
+		Available: v.Stock - v.Reserved,
+	})
+}
+
+// NormalizeSKU devuelve la forma normalizada de un SKU: en mayúsculas y sin espacios al principio ni al final
+func NormalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
+
+// VariantRepository representa el repositorio de variantes
+type VariantRepository interface {
+	CreateVariant(variant *Variant) error           // CreateVariant agrega una variante. Si ya existe otra con el mismo SKU, devuelve AlreadyExistsError
+	GetVariant(id uint) (*Variant, error)           // GetVariant recupera una variante por ID
+	GetVariantBySKU(sku string) (*Variant, error)   // GetVariantBySKU recupera una variante por SKU
+	GetVariants(productID uint) ([]*Variant, error) // GetVariants recupera las variantes de un producto, ordenadas por ID
+	UpdateVariant(variant *Variant) error           // UpdateVariant modifica el SKU, las opciones y el precio de una variante. Si el SKU ya lo usa otra, devuelve AlreadyExistsError
+	DeleteVariant(variant *Variant) error           // DeleteVariant elimina una variante. Si tiene stock o stock reservado, no la elimina y devuelve ErrVariantHasStock
+}
+
+// prepareVariant normaliza y valida los datos de una variante
+func prepareVariant(variant *Variant) error {
+	variant.SKU = NormalizeSKU(variant.SKU)
+
+	if err := validateStruct(variant); err != nil {
+		return err
+	}
+	if variant.Price != nil {
+		if err := checkPrice(*variant.Price); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateVariant agrega una variante a un producto. El stock inicial se registra como un movimiento de ajuste de la variante.
+func (u *usecase) CreateVariant(variant *Variant) (*Variant, error) {
+	if err := prepareVariant(variant); err != nil {
+		return nil, errors.Wrap(err, "UC - CreateVariant - Error during variant validation")
+	}
+
+	initialStock := variant.Stock
+	variant.ID = 0
+	variant.Stock = 0
+	variant.Reserved = 0
+
+	err := u.repository.Transaction(func(tx Store) error {
+		formerProduct, err := tx.GetByID(variant.ProductID)
+		if err != nil {
+			return err
+		}
+		if err := tx.CreateVariant(variant); err != nil {
+			return err
+		}
+		if initialStock == 0 {
+			return nil
+		}
+
+		product, err := addStockMovement(tx, &StockMovement{
+			ProductID: variant.ProductID,
+			VariantID: &variant.ID,
+			Quantity:  initialStock,
+			Reason:    ReasonAdjustment,
+			Reference: "initial stock of " + variant.SKU,
+		})
+		if err != nil {
+			return err
+		}
+		variant.Stock = initialStock
+
+		return recordAudit(tx, u.actor, OperationStockMovement, formerProduct, product)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - CreateVariant - Error creating variant %s of product with id %d", variant.SKU, variant.ProductID)
+	}
+
+	return variant, nil
+}
+
+// getVariant recupera una variante de un producto. Si la variante pertenece a otro producto, devuelve NotFoundError.
+func getVariant(repository VariantRepository, productID, id uint) (*Variant, error) {
+	variant, err := repository.GetVariant(id)
+	if err != nil {
+		return nil, err
+	}
+	if variant.ProductID != productID {
+		return nil, &NotFoundError{Entity: "variant", Key: id}
+	}
+
+	return variant, nil
+}
+
+// GetVariant recupera una variante de un producto
+func (u *usecase) GetVariant(productID, id uint) (*Variant, error) {
+	variant, err := getVariant(u.repository, productID, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - GetVariant - Error fetching variant with id %d of product with id %d", id, productID)
+	}
+
+	return variant, nil
+}
+
+// GetVariantBySKU recupera una variante por SKU
+func (u *usecase) GetVariantBySKU(sku string) (*Variant, error) {
+	variant, err := u.repository.GetVariantBySKU(NormalizeSKU(sku))
+	if err != nil {
+		return nil, errors.Wrap(err, "UC - GetVariantBySKU - Error fetching a variant")
+	}
+
+	return variant, nil
+}
+
+// GetVariants recupera las variantes de un producto
+func (u *usecase) GetVariants(productID uint) ([]*Variant, error) {
+	if _, err := u.GetByID(productID); err != nil {
+		return nil, errors.Wrapf(err, "UC - GetVariants - Product with id %d does not exist", productID)
+	}
+
+	variants, err := u.repository.GetVariants(productID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - GetVariants - Error fetching variants of product with id %d", productID)
+	}
+
+	return variants, nil
+}
+
+// UpdateVariant modifica el SKU, las opciones o el precio de una variante. El stock se modifica con movimientos de stock.
+func (u *usecase) UpdateVariant(variant *Variant) (*Variant, error) {
+	if err := prepareVariant(variant); err != nil {
+		return nil, errors.Wrap(err, "UC - UpdateVariant - Error during variant validation")
+	}
+
+	err := u.repository.Transaction(func(tx Store) error {
+		if _, err := getVariant(tx, variant.ProductID, variant.ID); err != nil {
+			return err
+		}
+
+		return tx.UpdateVariant(variant)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "UC - UpdateVariant - Error updating variant with id %d", variant.ID)
+	}
+
+	return variant, nil
+}
+
+// DeleteVariant elimina una variante sin stock de un producto
+func (u *usecase) DeleteVariant(productID, id uint) error {
+	variant, err := getVariant(u.repository, productID, id)
+	if err != nil {
+		return errors.Wrapf(err, "UC - DeleteVariant - Variant with id %d of product with id %d does not exist", id, productID)
+	}
+
+	if err := u.repository.DeleteVariant(variant); err != nil {
+		return errors.Wrapf(err, "UC - DeleteVariant - Error deleting variant with id %d", id)
+	}
+
+	return nil
+}
+
+// checkVariant verifica, dentro de la transacción tx, que una operación de stock sobre un producto indique una de sus variantes
+// si el producto tiene variantes, de modo que el stock de cada variante se mantenga al día
+func checkVariant(tx Store, productID uint, variantID *uint) error {
+	if variantID != nil {
+		_, err := getVariant(tx, productID, *variantID)
+		return err
+	}
+
+	variants, err := tx.GetVariants(productID)
+	if err != nil {
+		return err
+	}
+	if len(variants) > 0 {
+		return &ValidationError{Message: "invalid data", Fields: map[string]string{"variant_id": "required"}}
+	}
+
+	return nil
+}
@@ -0,0 +1,622 @@
+// Package productsclient permite a otros servicios (por ejemplo, el gateway) invocar
+// al servicio de productos a través de NATS sin reimplementar el protocolo:
+// arma los subjects, serializa los pedidos y decodifica las respuestas JSend.
+package productsclient
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/marceloaguero/go-nats-products/products/pkg/subjects"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultTimeout es el tiempo máximo de espera por una respuesta del servicio de productos
+	DefaultTimeout = time.Millisecond * 500
+	// ImportTimeout es el tiempo máximo de espera por el reporte de un lote de importación, que se procesa en una transacción
+	ImportTimeout = time.Second * 30
+	// BatchTimeout es el tiempo máximo de espera por el reporte de un lote de operaciones, que se procesa en una transacción
+	BatchTimeout = time.Second * 30
+)
+
+// Client representa las operaciones que expone el servicio de productos.
+// Las firmas replican las de product.Usecase.
+type Client interface {
+	Create(product *product.Product) (*product.Product, error)    // Create agrega un producto nuevo
+	GetByID(id uint) (*product.Product, error)                    // GetByID recupera un producto por ID
+	GetByIDIncludeDeleted(id uint) (*product.Product, error)      // GetByIDIncludeDeleted recupera un producto por ID, aunque esté eliminado
+	GetByName(name string) (*product.Product, error)              // GetByName recupera un producto por nombre
+	GetAll(query *product.Query) (*product.Page, error)           // GetAll recupera una página de productos
+	Update(product *product.Product) (*product.Product, error)    // Update modifica un producto existente
+	Delete(product *product.Product) error                        // Delete elimina un producto (baja lógica)
+	Restore(product *product.Product) (*product.Product, error)   // Restore restaura un producto eliminado
+	Purge(product *product.Product) error                         // Purge elimina definitivamente un producto
+	UpdateStock(id uint, stock float64) (*product.Product, error) // UpdateStock modifica el stock de un producto
+
+	AddStockMovement(movement *product.StockMovement) (*product.Product, error) // AddStockMovement registra un ingreso o egreso de stock
+	GetStockMovements(productID uint) ([]*product.StockMovement, error)         // GetStockMovements recupera los movimientos de stock de un producto
+
+	Reserve(reservation *product.Reservation) (*product.Reservation, error) // Reserve reserva stock de un producto por un tiempo limitado
+	GetReservation(id string) (*product.Reservation, error)                 // GetReservation recupera una reserva
+	ConfirmReservation(id string) (*product.Reservation, error)             // ConfirmReservation confirma una reserva, descontando el stock reservado
+	ReleaseReservation(id string) (*product.Reservation, error)             // ReleaseReservation libera una reserva, devolviendo el stock reservado
+
+	History(query *product.HistoryQuery) (*product.HistoryPage, error) // History recupera una página del historial de auditoría de un producto
+
+	SchedulePrice(price *product.Price) (*product.Price, error) // SchedulePrice programa un cambio de precio de un producto
+	CancelPrice(id uint) (*product.Price, error)                // CancelPrice cancela un cambio de precio programado
+	GetPrices(productID uint) (*product.PriceHistory, error)    // GetPrices recupera los precios pasados y programados de un producto
+
+	CreatePriceList(list *product.PriceList) (*product.PriceList, error)                  // CreatePriceList crea una lista de precios
+	GetPriceList(name string) (*product.PriceList, error)                                 // GetPriceList recupera una lista de precios por nombre
+	GetPriceLists() ([]*product.PriceList, error)                                         // GetPriceLists recupera todas las listas de precios
+	DeletePriceList(name string) error                                                    // DeletePriceList elimina una lista de precios
+	UpdateListPrices(update *product.ListPricesUpdate) (*product.ListPricesResult, error) // UpdateListPrices agrega, reemplaza y quita precios de una lista
+	GetListPrices(query *product.ListPricesQuery) (*product.ListPricesPage, error)        // GetListPrices recupera una página de los precios de una lista
+	ResolvePrice(productID uint, priceList string) (*product.ResolvedPrice, error)        // ResolvePrice resuelve el precio efectivo de un producto en una lista de precios
+
+	CreateCategory(category *product.Category) (*product.Category, error)                    // CreateCategory crea una categoría
+	GetCategory(id uint) (*product.Category, error)                                          // GetCategory recupera una categoría por ID
+	GetCategories() ([]*product.Category, error)                                             // GetCategories recupera todas las categorías
+	UpdateCategory(category *product.Category) (*product.Category, error)                    // UpdateCategory modifica una categoría, incluso su categoría padre
+	DeleteCategory(id uint) error                                                            // DeleteCategory elimina una categoría sin subcategorías
+	SetProductCategories(assignment *product.ProductCategories) ([]*product.Category, error) // SetProductCategories reemplaza las categorías de un producto
+	GetProductCategories(productID uint) ([]*product.Category, error)                        // GetProductCategories recupera las categorías de un producto
+
+	CreateVariant(variant *product.Variant) (*product.Variant, error) // CreateVariant agrega una variante a un producto
+	GetVariant(productID, id uint) (*product.Variant, error)          // GetVariant recupera una variante de un producto
+	GetVariantBySKU(sku string) (*product.Variant, error)             // GetVariantBySKU recupera una variante por SKU
+	GetVariants(productID uint) ([]*product.Variant, error)           // GetVariants recupera las variantes de un producto
+	UpdateVariant(variant *product.Variant) (*product.Variant, error) // UpdateVariant modifica una variante, salvo su stock
+	DeleteVariant(productID, id uint) error                           // DeleteVariant elimina una variante sin stock
+
+	CreateLocation(location *product.Location) (*product.Location, error)            // CreateLocation crea una ubicación de stock
+	GetLocations() ([]*product.Location, error)                                      // GetLocations recupera todas las ubicaciones de stock
+	GetStockLevels(query *product.StockLevelQuery) ([]*product.StockLevel, error)    // GetStockLevels recupera el stock por ubicación de un producto o de una ubicación
+	TransferStock(transfer *product.StockTransfer) ([]*product.StockMovement, error) // TransferStock transfiere stock de un producto entre dos ubicaciones
+
+	ImportProducts(request *product.ImportRequest) (*product.ImportReport, error) // ImportProducts crea o actualiza en forma masiva un lote de productos
+	ExportProducts(query *product.Query) (*product.Page, error)                   // ExportProducts recupera una página de una exportación del catálogo
+	Batch(request *product.BatchRequest) (*product.BatchReport, error)            // Batch ejecuta un lote de operaciones sobre productos
+
+	// WithActor devuelve un cliente que informa a actor como autor de los pedidos, para el historial de auditoría
+	WithActor(actor string) Client
+}
+
+type client struct {
+	nc         *nats.Conn
+	subjPrefix string
+	timeout    time.Duration
+	actor      string // Autor de los pedidos, se envía en el header subjects.ActorHeader
+}
+
+// NewClient crea un cliente del servicio de productos sobre una conexión NATS existente.
+// subjPrefix debe coincidir con el SUBJ_PREFIX del servicio. Si timeout es cero se usa DefaultTimeout.
+func NewClient(nc *nats.Conn, subjPrefix string, timeout time.Duration) Client {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &client{
+		nc:         nc,
+		subjPrefix: subjPrefix,
+		timeout:    timeout,
+	}
+}
+
+// reply es una respuesta JSend del servicio de productos
+type reply struct {
+	Status  string          `json:"status"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+}
+
+func (c *client) WithActor(actor string) Client {
+	withActor := *c
+	withActor.actor = actor
+	return &withActor
+}
+
+// request envía request (serializado como JSON) al subject indicado y decodifica
+// en data el contenido de una respuesta exitosa
+func (c *client) request(method, subj string, request interface{}, data interface{}) error {
+	return c.requestWithTimeout(method, subj, c.timeout, request, data)
+}
+
+// requestWithTimeout es request con un tiempo de espera propio, para los pedidos que demoran más que el resto
+func (c *client) requestWithTimeout(method, subj string, timeout time.Duration, request interface{}, data interface{}) error {
+	var body []byte
+	if request != nil {
+		var err error
+		body, err = json.Marshal(request)
+		if err != nil {
+			return errors.Wrapf(err, "%s - Can't marshal request", method)
+		}
+	}
+
+	requestMsg := nats.NewMsg(c.subjPrefix + subj)
+	requestMsg.Data = body
+	if c.actor != "" {
+		requestMsg.Header.Set(subjects.ActorHeader, c.actor)
+	}
+
+	msg, err := c.nc.RequestMsg(requestMsg, timeout)
+	if err != nil {
+		return errors.Wrapf(err, "%s - Request error", method)
+	}
+
+	reply := &reply{}
+	err = json.Unmarshal(msg.Data, reply)
+	if err != nil {
+		return errors.Wrapf(err, "%s - Can't unmarshal reply", method)
+	}
+
+	switch reply.Status {
+	case StatusSuccess:
+		if data == nil || len(reply.Data) == 0 {
+			return nil
+		}
+		err = json.Unmarshal(reply.Data, data)
+		if err != nil {
+			return errors.Wrapf(err, "%s - Can't unmarshal reply data", method)
+		}
+		return nil
+	case StatusFail, StatusError:
+		return newReplyError(reply)
+	default:
+		return errors.Errorf("%s - Unexpected reply status %q", method, reply.Status)
+	}
+}
+
+func (c *client) Create(p *product.Product) (*product.Product, error) {
+	productCreated := &product.Product{}
+	err := c.request("Products client - Create", subjects.Create, p, productCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	return productCreated, nil
+}
+
+func (c *client) GetByID(id uint) (*product.Product, error) {
+	productRetrieved := &product.Product{}
+	err := c.request("Products client - GetByID", subjects.GetByID, &product.ByIDQuery{ID: id}, productRetrieved)
+	if err != nil {
+		return nil, err
+	}
+
+	return productRetrieved, nil
+}
+
+func (c *client) GetByIDIncludeDeleted(id uint) (*product.Product, error) {
+	productRetrieved := &product.Product{}
+	err := c.request("Products client - GetByIDIncludeDeleted", subjects.GetByID, &product.ByIDQuery{ID: id, IncludeDeleted: true}, productRetrieved)
+	if err != nil {
+		return nil, err
+	}
+
+	return productRetrieved, nil
+}
+
+func (c *client) GetByName(name string) (*product.Product, error) {
+	productRetrieved := &product.Product{}
+	err := c.request("Products client - GetByName", subjects.GetByName, &product.Product{Name: name}, productRetrieved)
+	if err != nil {
+		return nil, err
+	}
+
+	return productRetrieved, nil
+}
+
+func (c *client) GetAll(query *product.Query) (*product.Page, error) {
+	page := &product.Page{}
+	err := c.request("Products client - GetAll", subjects.GetAll, query, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (c *client) Update(p *product.Product) (*product.Product, error) {
+	productUpdated := &product.Product{}
+	err := c.request("Products client - Update", subjects.Update, p, productUpdated)
+	if err != nil {
+		return nil, err
+	}
+
+	return productUpdated, nil
+}
+
+func (c *client) Delete(p *product.Product) error {
+	return c.request("Products client - Delete", subjects.Delete, &product.Product{ID: p.ID}, nil)
+}
+
+func (c *client) Restore(p *product.Product) (*product.Product, error) {
+	productRestored := &product.Product{}
+	err := c.request("Products client - Restore", subjects.Restore, &product.Product{ID: p.ID}, productRestored)
+	if err != nil {
+		return nil, err
+	}
+
+	return productRestored, nil
+}
+
+func (c *client) Purge(p *product.Product) error {
+	return c.request("Products client - Purge", subjects.Purge, &product.Product{ID: p.ID}, nil)
+}
+
+func (c *client) UpdateStock(id uint, stock float64) (*product.Product, error) {
+	productUpdated := &product.Product{}
+	err := c.request("Products client - UpdateStock", subjects.UpdateStock, &product.Product{ID: id, Stock: stock}, productUpdated)
+	if err != nil {
+		return nil, err
+	}
+
+	return productUpdated, nil
+}
+
+func (c *client) AddStockMovement(movement *product.StockMovement) (*product.Product, error) {
+	productUpdated := &product.Product{}
+	err := c.request("Products client - AddStockMovement", subjects.AddStockMovement, movement, productUpdated)
+	if err != nil {
+		return nil, err
+	}
+
+	return productUpdated, nil
+}
+
+func (c *client) GetStockMovements(productID uint) ([]*product.StockMovement, error) {
+	movements := []*product.StockMovement{}
+	err := c.request("Products client - GetStockMovements", subjects.GetStockMovements, &product.StockMovement{ProductID: productID}, &movements)
+	if err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+func (c *client) Reserve(reservation *product.Reservation) (*product.Reservation, error) {
+	reservationCreated := &product.Reservation{}
+	err := c.request("Products client - Reserve", subjects.Reserve, reservation, reservationCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	return reservationCreated, nil
+}
+
+func (c *client) GetReservation(id string) (*product.Reservation, error) {
+	reservation := &product.Reservation{}
+	err := c.request("Products client - GetReservation", subjects.GetReservation, &product.Reservation{ID: id}, reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+func (c *client) ConfirmReservation(id string) (*product.Reservation, error) {
+	reservation := &product.Reservation{}
+	err := c.request("Products client - ConfirmReservation", subjects.Confirm, &product.Reservation{ID: id}, reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+func (c *client) ReleaseReservation(id string) (*product.Reservation, error) {
+	reservation := &product.Reservation{}
+	err := c.request("Products client - ReleaseReservation", subjects.Release, &product.Reservation{ID: id}, reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+func (c *client) History(query *product.HistoryQuery) (*product.HistoryPage, error) {
+	page := &product.HistoryPage{}
+	err := c.request("Products client - History", subjects.History, query, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (c *client) SchedulePrice(price *product.Price) (*product.Price, error) {
+	priceScheduled := &product.Price{}
+	err := c.request("Products client - SchedulePrice", subjects.SchedulePrice, price, priceScheduled)
+	if err != nil {
+		return nil, err
+	}
+
+	return priceScheduled, nil
+}
+
+func (c *client) CancelPrice(id uint) (*product.Price, error) {
+	price := &product.Price{}
+	err := c.request("Products client - CancelPrice", subjects.CancelPrice, &product.Price{ID: id}, price)
+	if err != nil {
+		return nil, err
+	}
+
+	return price, nil
+}
+
+func (c *client) GetPrices(productID uint) (*product.PriceHistory, error) {
+	history := &product.PriceHistory{}
+	err := c.request("Products client - GetPrices", subjects.GetPrices, &product.Price{ProductID: productID}, history)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (c *client) CreatePriceList(list *product.PriceList) (*product.PriceList, error) {
+	listCreated := &product.PriceList{}
+	err := c.request("Products client - CreatePriceList", subjects.CreatePriceList, list, listCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	return listCreated, nil
+}
+
+func (c *client) GetPriceList(name string) (*product.PriceList, error) {
+	list := &product.PriceList{}
+	err := c.request("Products client - GetPriceList", subjects.GetPriceList, &product.PriceList{Name: name}, list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (c *client) GetPriceLists() ([]*product.PriceList, error) {
+	lists := []*product.PriceList{}
+	err := c.request("Products client - GetPriceLists", subjects.GetPriceLists, nil, &lists)
+	if err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+func (c *client) DeletePriceList(name string) error {
+	return c.request("Products client - DeletePriceList", subjects.DeletePriceList, &product.PriceList{Name: name}, nil)
+}
+
+func (c *client) UpdateListPrices(update *product.ListPricesUpdate) (*product.ListPricesResult, error) {
+	result := &product.ListPricesResult{}
+	err := c.request("Products client - UpdateListPrices", subjects.UpdateListPrices, update, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *client) GetListPrices(query *product.ListPricesQuery) (*product.ListPricesPage, error) {
+	page := &product.ListPricesPage{}
+	err := c.request("Products client - GetListPrices", subjects.GetListPrices, query, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (c *client) ResolvePrice(productID uint, priceList string) (*product.ResolvedPrice, error) {
+	resolved := &product.ResolvedPrice{}
+	err := c.request("Products client - ResolvePrice", subjects.ResolvePrice, &product.ResolvePriceQuery{ProductID: productID, PriceList: priceList}, resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
+func (c *client) CreateCategory(category *product.Category) (*product.Category, error) {
+	categoryCreated := &product.Category{}
+	err := c.request("Products client - CreateCategory", subjects.CreateCategory, category, categoryCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryCreated, nil
+}
+
+func (c *client) GetCategory(id uint) (*product.Category, error) {
+	category := &product.Category{}
+	err := c.request("Products client - GetCategory", subjects.GetCategory, &product.Category{ID: id}, category)
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (c *client) GetCategories() ([]*product.Category, error) {
+	categories := []*product.Category{}
+	err := c.request("Products client - GetCategories", subjects.GetCategories, nil, &categories)
+	if err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (c *client) UpdateCategory(category *product.Category) (*product.Category, error) {
+	categoryUpdated := &product.Category{}
+	err := c.request("Products client - UpdateCategory", subjects.UpdateCategory, category, categoryUpdated)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryUpdated, nil
+}
+
+func (c *client) DeleteCategory(id uint) error {
+	return c.request("Products client - DeleteCategory", subjects.DeleteCategory, &product.Category{ID: id}, nil)
+}
+
+func (c *client) SetProductCategories(assignment *product.ProductCategories) ([]*product.Category, error) {
+	categories := []*product.Category{}
+	err := c.request("Products client - SetProductCategories", subjects.SetProductCategories, assignment, &categories)
+	if err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (c *client) GetProductCategories(productID uint) ([]*product.Category, error) {
+	categories := []*product.Category{}
+	err := c.request("Products client - GetProductCategories", subjects.GetProductCategories, &product.ProductCategories{ProductID: productID}, &categories)
+	if err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (c *client) CreateVariant(variant *product.Variant) (*product.Variant, error) {
+	variantCreated := &product.Variant{}
+	err := c.request("Products client - CreateVariant", subjects.CreateVariant, variant, variantCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	return variantCreated, nil
+}
+
+func (c *client) GetVariant(productID, id uint) (*product.Variant, error) {
+	variant := &product.Variant{}
+	err := c.request("Products client - GetVariant", subjects.GetVariant, &product.Variant{ID: id, ProductID: productID}, variant)
+	if err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (c *client) GetVariantBySKU(sku string) (*product.Variant, error) {
+	variant := &product.Variant{}
+	err := c.request("Products client - GetVariantBySKU", subjects.GetVariantBySKU, &product.Variant{SKU: sku}, variant)
+	if err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (c *client) GetVariants(productID uint) ([]*product.Variant, error) {
+	variants := []*product.Variant{}
+	err := c.request("Products client - GetVariants", subjects.GetVariants, &product.Variant{ProductID: productID}, &variants)
+	if err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+func (c *client) UpdateVariant(variant *product.Variant) (*product.Variant, error) {
+	variantUpdated := &product.Variant{}
+	err := c.request("Products client - UpdateVariant", subjects.UpdateVariant, variant, variantUpdated)
+	if err != nil {
+		return nil, err
+	}
+
+	return variantUpdated, nil
+}
+
+func (c *client) DeleteVariant(productID, id uint) error {
+	return c.request("Products client - DeleteVariant", subjects.DeleteVariant, &product.Variant{ID: id, ProductID: productID}, nil)
+}
+
+func (c *client) CreateLocation(location *product.Location) (*product.Location, error) {
+	locationCreated := &product.Location{}
+	err := c.request("Products client - CreateLocation", subjects.CreateLocation, location, locationCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	return locationCreated, nil
+}
+
+func (c *client) GetLocations() ([]*product.Location, error) {
+	locations := []*product.Location{}
+	err := c.request("Products client - GetLocations", subjects.GetLocations, nil, &locations)
+	if err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+func (c *client) GetStockLevels(query *product.StockLevelQuery) ([]*product.StockLevel, error) {
+	levels := []*product.StockLevel{}
+	err := c.request("Products client - GetStockLevels", subjects.GetStockLevels, query, &levels)
+	if err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+func (c *client) TransferStock(transfer *product.StockTransfer) ([]*product.StockMovement, error) {
+	movements := []*product.StockMovement{}
+	err := c.request("Products client - TransferStock", subjects.TransferStock, transfer, &movements)
+	if err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+func (c *client) ImportProducts(request *product.ImportRequest) (*product.ImportReport, error) {
+	timeout := c.timeout
+	if timeout < ImportTimeout {
+		timeout = ImportTimeout
+	}
+
+	report := &product.ImportReport{}
+	err := c.requestWithTimeout("Products client - ImportProducts", subjects.ImportProducts, timeout, request, report)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (c *client) ExportProducts(query *product.Query) (*product.Page, error) {
+	page := &product.Page{}
+	err := c.request("Products client - ExportProducts", subjects.ExportProducts, query, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (c *client) Batch(request *product.BatchRequest) (*product.BatchReport, error) {
+	timeout := c.timeout
+	if timeout < BatchTimeout {
+		timeout = BatchTimeout
+	}
+
+	report := &product.BatchReport{}
+	err := c.requestWithTimeout("Products client - Batch", subjects.Batch, timeout, request, report)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
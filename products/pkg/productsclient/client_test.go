@@ -0,0 +1,330 @@
+package productsclient
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/marceloaguero/go-nats-products/products/pkg/subjects"
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/shopspring/decimal"
+)
+
+const testPrefix = "test"
+
+// responder reemplaza al servicio de productos: registra el último pedido recibido y lo responde con reply
+type responder struct {
+	mu      sync.Mutex
+	reply   []byte
+	request *nats.Msg
+}
+
+func (r *responder) respond(msg *nats.Msg) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.request = msg
+	msg.Respond(r.reply)
+}
+
+func (r *responder) setReply(reply []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reply = reply
+	r.request = nil
+}
+
+func (r *responder) lastRequest() *nats.Msg {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.request
+}
+
+// newTestConn levanta un servidor NATS en el proceso y devuelve una conexión a él
+func newTestConn(t *testing.T) *nats.Conn {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = server.RANDOM_PORT
+	s := natsserver.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Connect: unexpected error: %v", err)
+	}
+	t.Cleanup(nc.Close)
+
+	return nc
+}
+
+// newTestClient devuelve un cliente cuyos pedidos responde el responder devuelto
+func newTestClient(t *testing.T) (Client, *responder) {
+	nc := newTestConn(t)
+	r := &responder{}
+	if _, err := nc.Subscribe(testPrefix+".>", r.respond); err != nil {
+		t.Fatalf("Subscribe: unexpected error: %v", err)
+	}
+	if err := nc.Flush(); err != nil {
+		t.Fatalf("Flush: unexpected error: %v", err)
+	}
+
+	return NewClient(nc, testPrefix, time.Second), r
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	return data
+}
+
+func TestMethods(t *testing.T) {
+	variantID := uint(3)
+	tests := []struct {
+		name    string
+		subject string
+		request interface{} // Pedido que debe recibir el servicio, nil si el método no envía contenido
+		data    interface{} // Contenido de la respuesta exitosa, nil si el método no devuelve datos
+		call    func(c Client) (interface{}, error)
+	}{
+		{"Create", subjects.Create,
+			&product.Product{Name: "shirt", Unit: "unit", Price: decimal.RequireFromString("20.5")},
+			&product.Product{ID: 1, Name: "shirt", Unit: "unit", Price: decimal.RequireFromString("20.5"), Version: 1},
+			func(c Client) (interface{}, error) {
+				return c.Create(&product.Product{Name: "shirt", Unit: "unit", Price: decimal.RequireFromString("20.5")})
+			}},
+		{"GetByID", subjects.GetByID, &product.ByIDQuery{ID: 1}, &product.Product{ID: 1, Name: "shirt"},
+			func(c Client) (interface{}, error) { return c.GetByID(1) }},
+		{"GetByIDIncludeDeleted", subjects.GetByID, &product.ByIDQuery{ID: 1, IncludeDeleted: true}, &product.Product{ID: 1, Name: "shirt"},
+			func(c Client) (interface{}, error) { return c.GetByIDIncludeDeleted(1) }},
+		{"GetByName", subjects.GetByName, &product.Product{Name: "shirt"}, &product.Product{ID: 1, Name: "shirt"},
+			func(c Client) (interface{}, error) { return c.GetByName("shirt") }},
+		{"GetAll", subjects.GetAll, &product.Query{Limit: 10, SortBy: product.SortByName},
+			&product.Page{Products: []*product.Product{{ID: 1, Name: "shirt"}}, Total: 1, NextCursor: "next"},
+			func(c Client) (interface{}, error) {
+				return c.GetAll(&product.Query{Limit: 10, SortBy: product.SortByName})
+			}},
+		{"Update", subjects.Update, &product.Product{ID: 1, Name: "pants", Version: 2}, &product.Product{ID: 1, Name: "pants", Version: 3},
+			func(c Client) (interface{}, error) {
+				return c.Update(&product.Product{ID: 1, Name: "pants", Version: 2})
+			}},
+		{"Delete", subjects.Delete, &product.Product{ID: 1}, nil,
+			func(c Client) (interface{}, error) { return nil, c.Delete(&product.Product{ID: 1, Name: "shirt"}) }},
+		{"Restore", subjects.Restore, &product.Product{ID: 1}, &product.Product{ID: 1, Name: "shirt"},
+			func(c Client) (interface{}, error) { return c.Restore(&product.Product{ID: 1, Name: "shirt"}) }},
+		{"Purge", subjects.Purge, &product.Product{ID: 1}, nil,
+			func(c Client) (interface{}, error) { return nil, c.Purge(&product.Product{ID: 1, Name: "shirt"}) }},
+		{"UpdateStock", subjects.UpdateStock, &product.Product{ID: 1, Stock: 5}, &product.Product{ID: 1, Stock: 5},
+			func(c Client) (interface{}, error) { return c.UpdateStock(1, 5) }},
+		{"AddStockMovement", subjects.AddStockMovement, &product.StockMovement{ProductID: 1, Quantity: 2}, &product.Product{ID: 1, Stock: 7},
+			func(c Client) (interface{}, error) {
+				return c.AddStockMovement(&product.StockMovement{ProductID: 1, Quantity: 2})
+			}},
+		{"GetStockMovements", subjects.GetStockMovements, &product.StockMovement{ProductID: 1},
+			[]*product.StockMovement{{ID: 1, ProductID: 1, Quantity: 2}},
+			func(c Client) (interface{}, error) { return c.GetStockMovements(1) }},
+		{"Reserve", subjects.Reserve, &product.Reservation{ProductID: 1, Quantity: 2}, &product.Reservation{ID: "r1", ProductID: 1, Quantity: 2},
+			func(c Client) (interface{}, error) { return c.Reserve(&product.Reservation{ProductID: 1, Quantity: 2}) }},
+		{"GetReservation", subjects.GetReservation, &product.Reservation{ID: "r1"}, &product.Reservation{ID: "r1", ProductID: 1},
+			func(c Client) (interface{}, error) { return c.GetReservation("r1") }},
+		{"ConfirmReservation", subjects.Confirm, &product.Reservation{ID: "r1"}, &product.Reservation{ID: "r1", ProductID: 1},
+			func(c Client) (interface{}, error) { return c.ConfirmReservation("r1") }},
+		{"ReleaseReservation", subjects.Release, &product.Reservation{ID: "r1"}, &product.Reservation{ID: "r1", ProductID: 1},
+			func(c Client) (interface{}, error) { return c.ReleaseReservation("r1") }},
+		{"History", subjects.History, &product.HistoryQuery{ProductID: 1, Limit: 10}, &product.HistoryPage{Entries: []*product.AuditEntry{}, Total: 0},
+			func(c Client) (interface{}, error) { return c.History(&product.HistoryQuery{ProductID: 1, Limit: 10}) }},
+		{"SchedulePrice", subjects.SchedulePrice, &product.Price{ProductID: 1, Price: decimal.RequireFromString("25")},
+			&product.Price{ID: 1, ProductID: 1, Price: decimal.RequireFromString("25")},
+			func(c Client) (interface{}, error) {
+				return c.SchedulePrice(&product.Price{ProductID: 1, Price: decimal.RequireFromString("25")})
+			}},
+		{"CancelPrice", subjects.CancelPrice, &product.Price{ID: 1}, &product.Price{ID: 1, ProductID: 1},
+			func(c Client) (interface{}, error) { return c.CancelPrice(1) }},
+		{"GetPrices", subjects.GetPrices, &product.Price{ProductID: 1}, &product.PriceHistory{},
+			func(c Client) (interface{}, error) { return c.GetPrices(1) }},
+		{"CreatePriceList", subjects.CreatePriceList, &product.PriceList{Name: "retail"}, &product.PriceList{Name: "retail", Currency: "USD"},
+			func(c Client) (interface{}, error) { return c.CreatePriceList(&product.PriceList{Name: "retail"}) }},
+		{"GetPriceList", subjects.GetPriceList, &product.PriceList{Name: "retail"}, &product.PriceList{Name: "retail"},
+			func(c Client) (interface{}, error) { return c.GetPriceList("retail") }},
+		{"GetPriceLists", subjects.GetPriceLists, nil, []*product.PriceList{{Name: "retail"}},
+			func(c Client) (interface{}, error) { return c.GetPriceLists() }},
+		{"DeletePriceList", subjects.DeletePriceList, &product.PriceList{Name: "retail"}, nil,
+			func(c Client) (interface{}, error) { return nil, c.DeletePriceList("retail") }},
+		{"UpdateListPrices", subjects.UpdateListPrices, &product.ListPricesUpdate{PriceList: "retail", ProductIDs: []uint{1}},
+			&product.ListPricesResult{Removed: 1},
+			func(c Client) (interface{}, error) {
+				return c.UpdateListPrices(&product.ListPricesUpdate{PriceList: "retail", ProductIDs: []uint{1}})
+			}},
+		{"GetListPrices", subjects.GetListPrices, &product.ListPricesQuery{PriceList: "retail"},
+			&product.ListPricesPage{PriceList: &product.PriceList{Name: "retail"}, Prices: []*product.ListPrice{}},
+			func(c Client) (interface{}, error) {
+				return c.GetListPrices(&product.ListPricesQuery{PriceList: "retail"})
+			}},
+		{"ResolvePrice", subjects.ResolvePrice, &product.ResolvePriceQuery{ProductID: 1, PriceList: "retail"},
+			&product.ResolvedPrice{ProductID: 1, PriceList: "retail", Price: decimal.RequireFromString("18"), Source: product.PriceSourceList},
+			func(c Client) (interface{}, error) { return c.ResolvePrice(1, "retail") }},
+		{"CreateCategory", subjects.CreateCategory, &product.Category{Name: "clothes"}, &product.Category{ID: 1, Name: "clothes"},
+			func(c Client) (interface{}, error) { return c.CreateCategory(&product.Category{Name: "clothes"}) }},
+		{"GetCategory", subjects.GetCategory, &product.Category{ID: 1}, &product.Category{ID: 1, Name: "clothes"},
+			func(c Client) (interface{}, error) { return c.GetCategory(1) }},
+		{"GetCategories", subjects.GetCategories, nil, []*product.Category{{ID: 1, Name: "clothes"}},
+			func(c Client) (interface{}, error) { return c.GetCategories() }},
+		{"UpdateCategory", subjects.UpdateCategory, &product.Category{ID: 1, Name: "apparel"}, &product.Category{ID: 1, Name: "apparel"},
+			func(c Client) (interface{}, error) {
+				return c.UpdateCategory(&product.Category{ID: 1, Name: "apparel"})
+			}},
+		{"DeleteCategory", subjects.DeleteCategory, &product.Category{ID: 1}, nil,
+			func(c Client) (interface{}, error) { return nil, c.DeleteCategory(1) }},
+		{"SetProductCategories", subjects.SetProductCategories, &product.ProductCategories{ProductID: 1, CategoryIDs: []uint{1}},
+			[]*product.Category{{ID: 1, Name: "clothes"}},
+			func(c Client) (interface{}, error) {
+				return c.SetProductCategories(&product.ProductCategories{ProductID: 1, CategoryIDs: []uint{1}})
+			}},
+		{"GetProductCategories", subjects.GetProductCategories, &product.ProductCategories{ProductID: 1},
+			[]*product.Category{{ID: 1, Name: "clothes"}},
+			func(c Client) (interface{}, error) { return c.GetProductCategories(1) }},
+		{"CreateVariant", subjects.CreateVariant, &product.Variant{ProductID: 1, SKU: "shirt-m"}, &product.Variant{ID: 3, ProductID: 1, SKU: "shirt-m"},
+			func(c Client) (interface{}, error) {
+				return c.CreateVariant(&product.Variant{ProductID: 1, SKU: "shirt-m"})
+			}},
+		{"GetVariant", subjects.GetVariant, &product.Variant{ID: 3, ProductID: 1}, &product.Variant{ID: 3, ProductID: 1, SKU: "shirt-m"},
+			func(c Client) (interface{}, error) { return c.GetVariant(1, 3) }},
+		{"GetVariantBySKU", subjects.GetVariantBySKU, &product.Variant{SKU: "shirt-m"}, &product.Variant{ID: 3, ProductID: 1, SKU: "shirt-m"},
+			func(c Client) (interface{}, error) { return c.GetVariantBySKU("shirt-m") }},
+		{"GetVariants", subjects.GetVariants, &product.Variant{ProductID: 1}, []*product.Variant{{ID: 3, ProductID: 1, SKU: "shirt-m"}},
+			func(c Client) (interface{}, error) { return c.GetVariants(1) }},
+		{"UpdateVariant", subjects.UpdateVariant, &product.Variant{ID: 3, ProductID: 1, SKU: "shirt-l"}, &product.Variant{ID: 3, ProductID: 1, SKU: "shirt-l"},
+			func(c Client) (interface{}, error) {
+				return c.UpdateVariant(&product.Variant{ID: 3, ProductID: 1, SKU: "shirt-l"})
+			}},
+		{"DeleteVariant", subjects.DeleteVariant, &product.Variant{ID: 3, ProductID: 1}, nil,
+			func(c Client) (interface{}, error) { return nil, c.DeleteVariant(1, 3) }},
+		{"CreateLocation", subjects.CreateLocation, &product.Location{Name: "warehouse"}, &product.Location{ID: 1, Name: "warehouse"},
+			func(c Client) (interface{}, error) { return c.CreateLocation(&product.Location{Name: "warehouse"}) }},
+		{"GetLocations", subjects.GetLocations, nil, []*product.Location{{ID: 1, Name: "warehouse"}},
+			func(c Client) (interface{}, error) { return c.GetLocations() }},
+		{"GetStockLevels", subjects.GetStockLevels, &product.StockLevelQuery{ProductID: 1},
+			[]*product.StockLevel{{ProductID: 1, LocationID: 1, Quantity: 5}},
+			func(c Client) (interface{}, error) { return c.GetStockLevels(&product.StockLevelQuery{ProductID: 1}) }},
+		{"TransferStock", subjects.TransferStock,
+			&product.StockTransfer{ProductID: 1, VariantID: &variantID, FromLocationID: 1, ToLocationID: 2, Quantity: 2},
+			[]*product.StockMovement{{ID: 1, ProductID: 1, Quantity: -2}, {ID: 2, ProductID: 1, Quantity: 2}},
+			func(c Client) (interface{}, error) {
+				return c.TransferStock(&product.StockTransfer{ProductID: 1, VariantID: &variantID, FromLocationID: 1, ToLocationID: 2, Quantity: 2})
+			}},
+		{"ImportProducts", subjects.ImportProducts,
+			&product.ImportRequest{Rows: []*product.ImportRow{{Line: 2, Name: "shirt"}}, DryRun: true},
+			&product.ImportReport{DryRun: true, Created: 1, Results: []*product.ImportResult{{Line: 2, Status: product.ImportCreated}}},
+			func(c Client) (interface{}, error) {
+				return c.ImportProducts(&product.ImportRequest{Rows: []*product.ImportRow{{Line: 2, Name: "shirt"}}, DryRun: true})
+			}},
+		{"ExportProducts", subjects.ExportProducts, &product.Query{Limit: 500}, &product.Page{Products: []*product.Product{{ID: 1}}},
+			func(c Client) (interface{}, error) { return c.ExportProducts(&product.Query{Limit: 500}) }},
+		{"Batch", subjects.Batch,
+			&product.BatchRequest{Mode: product.BatchAtomic, Operations: []*product.BatchOperation{{Op: product.BatchDelete, Product: &product.Product{ID: 1}}}},
+			&product.BatchReport{Mode: product.BatchAtomic, Succeeded: 1, Results: []*product.BatchResult{{Op: product.BatchDelete, Status: product.BatchSucceeded}}},
+			func(c Client) (interface{}, error) {
+				return c.Batch(&product.BatchRequest{Mode: product.BatchAtomic, Operations: []*product.BatchOperation{{Op: product.BatchDelete, Product: &product.Product{ID: 1}}}})
+			}},
+	}
+
+	c, r := newTestClient(t)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Respuesta exitosa: el pedido llega serializado al subject del método y se decodifica el contenido de la respuesta
+			r.setReply(mustMarshal(t, map[string]interface{}{"status": StatusSuccess, "data": tt.data}))
+			result, err := tt.call(c)
+			if err != nil {
+				t.Fatalf("success: unexpected error: %v", err)
+			}
+			request := r.lastRequest()
+			if request == nil || request.Subject != testPrefix+tt.subject {
+				t.Fatalf("expected a request to %s, got %v", testPrefix+tt.subject, request)
+			}
+			expected := []byte{}
+			if tt.request != nil {
+				expected = mustMarshal(t, tt.request)
+			}
+			if string(request.Data) != string(expected) {
+				t.Fatalf("expected request %s, got %s", expected, request.Data)
+			}
+			if tt.data != nil && string(mustMarshal(t, result)) != string(mustMarshal(t, tt.data)) {
+				t.Fatalf("expected result %s, got %s", mustMarshal(t, tt.data), mustMarshal(t, result))
+			}
+
+			// Respuesta fail: pedido rechazado, con el código y el mensaje informados por el servicio
+			r.setReply([]byte(`{"status": "fail", "data": {"code": "not_found", "message": "product not found"}}`))
+			_, err = tt.call(c)
+			if !IsNotFound(err) || !IsFail(err) || err.Error() != "product not found" {
+				t.Fatalf("fail: expected a not found error, got %v", err)
+			}
+
+			// Respuesta error: falla interna del servicio, con el mensaje en message
+			r.setReply([]byte(`{"status": "error", "message": "database unavailable"}`))
+			_, err = tt.call(c)
+			if ErrorCode(err) != product.CodeInternal || IsFail(err) || err.Error() != "database unavailable" {
+				t.Fatalf("error: expected an internal error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestActor(t *testing.T) {
+	c, r := newTestClient(t)
+	r.setReply([]byte(`{"status": "success", "data": null}`))
+
+	if err := c.Delete(&product.Product{ID: 1}); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if actor := r.lastRequest().Header.Get(subjects.ActorHeader); actor != "" {
+		t.Fatalf("expected no actor, got %q", actor)
+	}
+
+	if err := c.WithActor("alice").Delete(&product.Product{ID: 1}); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if actor := r.lastRequest().Header.Get(subjects.ActorHeader); actor != "alice" {
+		t.Fatalf("expected actor alice, got %q", actor)
+	}
+}
+
+func TestInvalidReplies(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		err   string
+	}{
+		{"not JSON", `not json`, "Products client - GetByID - Can't unmarshal reply"},
+		{"unexpected status", `{"status": "ok"}`, `Products client - GetByID - Unexpected reply status "ok"`},
+		{"invalid data", `{"status": "success", "data": []}`, "Products client - GetByID - Can't unmarshal reply data"},
+	}
+	c, r := newTestClient(t)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r.setReply([]byte(tt.reply))
+			_, err := c.GetByID(1)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+			// No provienen de una respuesta del servicio: se consideran fallas internas
+			if ErrorCode(err) != product.CodeInternal || IsFail(err) {
+				t.Fatalf("expected an internal error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNoResponders(t *testing.T) {
+	c := NewClient(newTestConn(t), testPrefix, time.Second)
+
+	_, err := c.GetByID(1)
+	if err == nil || ErrorCode(err) != product.CodeInternal || IsNotFound(err) {
+		t.Fatalf("expected an internal error, got %v", err)
+	}
+}
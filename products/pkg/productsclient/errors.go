@@ -0,0 +1,113 @@
+package productsclient
+
+import (
+	"encoding/json"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/pkg/errors"
+)
+
+// Status posibles de una respuesta JSend
+const (
+	StatusSuccess = "success"
+	StatusFail    = "fail"
+	StatusError   = "error"
+)
+
+// ReplyError es el error devuelto cuando el servicio de productos responde
+// con status fail (pedido rechazado) o error (falla interna del servicio)
+type ReplyError struct {
+	Status  string            // fail o error
+	Code    string            // Código del error (ver product.ErrorCode)
+	Message string            // Mensaje de error informado por el servicio
+	Fields  map[string]string // Detalle por atributo de un error de validación
+}
+
+func (e *ReplyError) Error() string {
+	return e.Message
+}
+
+// failData es el contenido de una respuesta fail o error
+type failData struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields"`
+}
+
+// newReplyError arma un ReplyError a partir de una respuesta fail o error.
+// El código, el mensaje y el detalle por atributo viajan en data; en las respuestas error el mensaje viaja además en message.
+func newReplyError(reply *reply) *ReplyError {
+	replyError := &ReplyError{
+		Status:  reply.Status,
+		Message: reply.Message,
+	}
+
+	data := &failData{}
+	if json.Unmarshal(reply.Data, data) == nil {
+		replyError.Code = data.Code
+		replyError.Fields = data.Fields
+		if data.Message != "" {
+			replyError.Message = data.Message
+		}
+	}
+
+	if replyError.Code == "" && replyError.Status == StatusError {
+		replyError.Code = product.CodeInternal
+	}
+
+	return replyError
+}
+
+// ErrorCode devuelve el código de err (ver product.ErrorCode).
+// Los errores que no provienen de una respuesta del servicio (timeouts, fallas de conexión, etc.) se consideran fallas internas.
+func ErrorCode(err error) string {
+	var replyError *ReplyError
+	if errors.As(err, &replyError) && replyError.Code != "" {
+		return replyError.Code
+	}
+
+	return product.CodeInternal
+}
+
+// ErrorFields devuelve el detalle por atributo de un error de validación, o nil si err no lo es
+func ErrorFields(err error) map[string]string {
+	var replyError *ReplyError
+	if errors.As(err, &replyError) {
+		return replyError.Fields
+	}
+
+	return nil
+}
+
+// IsFail indica si err corresponde a un pedido rechazado por el servicio de productos (status fail)
+func IsFail(err error) bool {
+	var replyError *ReplyError
+	return errors.As(err, &replyError) && replyError.Status == StatusFail
+}
+
+// IsNotFound indica si err corresponde a una entidad inexistente
+func IsNotFound(err error) bool {
+	return ErrorCode(err) == product.CodeNotFound
+}
+
+// IsAlreadyExists indica si err corresponde a una entidad repetida
+func IsAlreadyExists(err error) bool {
+	return ErrorCode(err) == product.CodeAlreadyExists
+}
+
+// IsValidation indica si err corresponde a datos inválidos
+func IsValidation(err error) bool {
+	return ErrorCode(err) == product.CodeValidation
+}
+
+// IsConflict indica si err corresponde a una operación que el estado actual de la entidad no permite,
+// por ejemplo una modificación con una versión que no es la actual, o una reserva sin stock suficiente
+func IsConflict(err error) bool {
+	code := ErrorCode(err)
+	return code == product.CodeConflict || code == product.CodeVersionMismatch
+}
+
+// IsVersionMismatch indica si err corresponde a una modificación con una versión que no es la actual
+func IsVersionMismatch(err error) bool {
+	return ErrorCode(err) == product.CodeVersionMismatch
+}
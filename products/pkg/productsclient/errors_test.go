@@ -0,0 +1,87 @@
+package productsclient
+
+import (
+	"testing"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/pkg/errors"
+)
+
+func TestReplyError(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   *reply
+		code    string
+		message string
+		fields  map[string]string
+	}{
+		{"fail with code", &reply{Status: StatusFail, Data: []byte(`{"code": "version_mismatch", "message": "version mismatch"}`)},
+			product.CodeVersionMismatch, "version mismatch", nil},
+		{"fail with fields", &reply{Status: StatusFail, Data: []byte(`{"code": "validation", "message": "invalid data", "fields": {"name": "required"}}`)},
+			product.CodeValidation, "invalid data", map[string]string{"name": "required"}},
+		{"fail without data", &reply{Status: StatusFail},
+			"", "", nil},
+		{"error with data", &reply{Status: StatusError, Message: "database unavailable", Data: []byte(`{"code": "internal", "message": "UC - Create - database unavailable"}`)},
+			product.CodeInternal, "UC - Create - database unavailable", nil},
+		{"error without data", &reply{Status: StatusError, Message: "database unavailable"},
+			product.CodeInternal, "database unavailable", nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			replyError := newReplyError(tt.reply)
+			if replyError.Status != tt.reply.Status || replyError.Code != tt.code || replyError.Message != tt.message {
+				t.Fatalf("unexpected reply error %+v", replyError)
+			}
+			if len(replyError.Fields) != len(tt.fields) || replyError.Fields["name"] != tt.fields["name"] {
+				t.Fatalf("expected fields %v, got %v", tt.fields, replyError.Fields)
+			}
+		})
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		code          string
+		notFound      bool
+		alreadyExists bool
+		validation    bool
+		conflict      bool
+		mismatch      bool
+	}{
+		{product.CodeNotFound, true, false, false, false, false},
+		{product.CodeAlreadyExists, false, true, false, false, false},
+		{product.CodeValidation, false, false, true, false, false},
+		{product.CodeConflict, false, false, false, true, false},
+		{product.CodeVersionMismatch, false, false, false, true, true},
+		{product.CodeInternal, false, false, false, false, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.code, func(t *testing.T) {
+			// Los errores se reconocen aunque se hayan envuelto
+			err := errors.Wrap(&ReplyError{Status: StatusFail, Code: tt.code, Message: "failed"}, "Gateway - Handler")
+			if ErrorCode(err) != tt.code {
+				t.Fatalf("expected code %s, got %s", tt.code, ErrorCode(err))
+			}
+			if IsNotFound(err) != tt.notFound || IsAlreadyExists(err) != tt.alreadyExists || IsValidation(err) != tt.validation || IsConflict(err) != tt.conflict || IsVersionMismatch(err) != tt.mismatch {
+				t.Fatalf("unexpected predicates for code %s", tt.code)
+			}
+			if !IsFail(err) {
+				t.Fatalf("expected a fail reply error")
+			}
+		})
+	}
+
+	fields := map[string]string{"price": "gte=0"}
+	if ErrorFields(&ReplyError{Status: StatusFail, Code: product.CodeValidation, Fields: fields})["price"] != "gte=0" {
+		t.Fatalf("expected the validation fields")
+	}
+
+	// Un ReplyError sin código y los errores que no provienen del servicio son fallas internas
+	for _, err := range []error{&ReplyError{Status: StatusFail}, errors.New("nats: timeout")} {
+		if ErrorCode(err) != product.CodeInternal || ErrorFields(err) != nil {
+			t.Fatalf("expected an internal error, got %v", err)
+		}
+	}
+}
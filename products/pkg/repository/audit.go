@@ -0,0 +1,24 @@
+package mysql_orm
+
+import (
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+)
+
+func (r *ormRepo) AddAuditEntry(entry *product.AuditEntry) error {
+	result := r.db.Create(entry)
+	return result.Error
+}
+
+func (r *ormRepo) GetAuditEntries(query *product.HistoryQuery) (*product.HistoryPage, error) {
+	page := &product.HistoryPage{
+		Entries: []*product.AuditEntry{},
+	}
+
+	db := r.db.Model(&product.AuditEntry{}).Where("product_id = ?", query.ProductID)
+	if err := db.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	result := r.db.Where("product_id = ?", query.ProductID).Order("id DESC").Offset(query.Offset).Limit(query.Limit).Find(&page.Entries)
+	return page, result.Error
+}
@@ -0,0 +1,131 @@
+package mysql_orm
+
+import (
+	"strings"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"gorm.io/gorm"
+)
+
+// parentPath recupera el camino de la categoría padre, o "" si la categoría es raíz
+func parentPath(tx *gorm.DB, parentID *uint) (string, error) {
+	if parentID == nil {
+		return "", nil
+	}
+
+	var parent product.Category
+	if err := tx.Take(&parent, *parentID).Error; err != nil {
+		return "", translateError(err, "category", *parentID)
+	}
+
+	return parent.Path, nil
+}
+
+func (r *ormRepo) CreateCategory(c *product.Category) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		path, err := parentPath(tx, c.ParentID)
+		if err != nil {
+			return err
+		}
+
+		// El camino incluye el ID, que se conoce recién después del alta
+		c.NameKey = product.CategoryNameKey(c.ParentID, c.Name)
+		if err := tx.Create(c).Error; err != nil {
+			return translateDuplicate(err, "category", "name", c.Name)
+		}
+
+		c.Path = product.CategoryPath(path, c.ID)
+		return tx.Model(c).Update("path", c.Path).Error
+	})
+}
+
+func (r *ormRepo) GetCategory(id uint) (*product.Category, error) {
+	var category product.Category
+	result := r.db.Take(&category, id)
+	return &category, translateError(result.Error, "category", id)
+}
+
+func (r *ormRepo) GetCategories() ([]*product.Category, error) {
+	categories := []*product.Category{}
+	result := r.db.Order("path").Find(&categories)
+	return categories, result.Error
+}
+
+func (r *ormRepo) UpdateCategory(c *product.Category) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		current := &product.Category{}
+		if err := tx.Take(current, c.ID).Error; err != nil {
+			return translateError(err, "category", c.ID)
+		}
+
+		path, err := parentPath(tx, c.ParentID)
+		if err != nil {
+			return err
+		}
+
+		c.NameKey = product.CategoryNameKey(c.ParentID, c.Name)
+		c.Path = product.CategoryPath(path, c.ID)
+		result := tx.Model(c).Select("name", "name_key", "description", "parent_id", "path", "updated_at").Updates(c)
+		if result.Error != nil {
+			return translateDuplicate(result.Error, "category", "name", c.Name)
+		}
+
+		// Si la categoría se movió, sus subcategorías se mueven con ella
+		if c.Path != current.Path {
+			descendants := []*product.Category{}
+			if err := tx.Where("path LIKE ? AND id <> ?", current.Path+"%", c.ID).Find(&descendants).Error; err != nil {
+				return err
+			}
+			for _, d := range descendants {
+				path := c.Path + strings.TrimPrefix(d.Path, current.Path)
+				if err := tx.Model(d).Update("path", path).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Take(c, c.ID).Error
+	})
+}
+
+func (r *ormRepo) DeleteCategory(c *product.Category) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var children int64
+		if err := tx.Model(&product.Category{}).Where("parent_id = ?", c.ID).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return product.ErrCategoryNotEmpty
+		}
+
+		if err := tx.Where("category_id = ?", c.ID).Delete(&product.ProductCategory{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&product.Category{}, c.ID).Error
+	})
+}
+
+func (r *ormRepo) SetProductCategories(productID uint, categoryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", productID).Delete(&product.ProductCategory{}).Error; err != nil {
+			return err
+		}
+		if len(categoryIDs) == 0 {
+			return nil
+		}
+
+		assignments := make([]*product.ProductCategory, 0, len(categoryIDs))
+		for _, categoryID := range categoryIDs {
+			assignments = append(assignments, &product.ProductCategory{ProductID: productID, CategoryID: categoryID})
+		}
+		return tx.Create(assignments).Error
+	})
+}
+
+func (r *ormRepo) GetProductCategories(productID uint) ([]*product.Category, error) {
+	categories := []*product.Category{}
+	result := r.db.Where("id IN (?)", r.db.Model(&product.ProductCategory{}).Select("category_id").Where("product_id = ?", productID)).
+		Order("path").Find(&categories)
+	return categories, result.Error
+}
@@ -0,0 +1,170 @@
+package mysql_orm
+
+import (
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *ormRepo) CreateLocation(l *product.Location) error {
+	result := r.db.Create(l)
+	return translateDuplicate(result.Error, "location", "code", l.Code)
+}
+
+func (r *ormRepo) GetLocation(id uint) (*product.Location, error) {
+	var location product.Location
+	result := r.db.Take(&location, id)
+	return &location, translateError(result.Error, "location", id)
+}
+
+func (r *ormRepo) GetLocationByCode(code string) (*product.Location, error) {
+	var location product.Location
+	result := r.db.Take(&location, "code = ?", code)
+	return &location, translateError(result.Error, "location", code)
+}
+
+func (r *ormRepo) GetLocations() ([]*product.Location, error) {
+	locations := []*product.Location{}
+	result := r.db.Order("code").Find(&locations)
+	return locations, result.Error
+}
+
+func (r *ormRepo) GetStockLevels(query *product.StockLevelQuery) ([]*product.StockLevel, error) {
+	levels := []*product.StockLevel{}
+	db := r.db
+	if query.ProductID != 0 {
+		db = db.Where("product_id = ?", query.ProductID)
+	}
+	if query.LocationID != 0 {
+		db = db.Where("location_id = ?", query.LocationID)
+	}
+	result := db.Order("product_id").Order("variant_id").Order("location_id").Find(&levels)
+	return levels, result.Error
+}
+
+func (r *ormRepo) TransferStock(transfer *product.StockTransfer) ([]*product.StockMovement, error) {
+	out := &product.StockMovement{
+		ProductID:  transfer.ProductID,
+		VariantID:  transfer.VariantID,
+		LocationID: &transfer.FromLocationID,
+		Quantity:   -transfer.Quantity,
+		Reason:     product.ReasonTransfer,
+		Reference:  transfer.Reference,
+	}
+	in := &product.StockMovement{
+		ProductID:  transfer.ProductID,
+		VariantID:  transfer.VariantID,
+		LocationID: &transfer.ToLocationID,
+		Quantity:   transfer.Quantity,
+		Reason:     product.ReasonTransfer,
+		Reference:  transfer.Reference,
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, movement := range []*product.StockMovement{out, in} {
+			if err := updateStockLevel(tx, movement); err != nil {
+				return err
+			}
+			if err := tx.Create(movement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []*product.StockMovement{out, in}, nil
+}
+
+// defaultLocation asigna la ubicación por defecto a un movimiento que no indica ubicación
+func defaultLocation(tx *gorm.DB, movement *product.StockMovement) error {
+	if movement.LocationID != nil {
+		return nil
+	}
+
+	var location product.Location
+	if err := tx.Take(&location, "code = ?", product.DefaultLocationCode).Error; err != nil {
+		return translateError(err, "location", product.DefaultLocationCode)
+	}
+	movement.LocationID = &location.ID
+
+	return nil
+}
+
+// updateStockLevel aplica la cantidad de un movimiento al nivel de stock de su ubicación.
+// Los ingresos crean el nivel si no existe. Los egresos sólo se aplican si el nivel no queda por debajo de su stock reservado,
+// en la misma sentencia que lo modifica; si no, devuelve ErrInsufficientStock.
+func updateStockLevel(tx *gorm.DB, movement *product.StockMovement) error {
+	variantID := uint(0)
+	if movement.VariantID != nil {
+		variantID = *movement.VariantID
+	}
+	now := time.Now()
+
+	if movement.Quantity >= 0 {
+		level := &product.StockLevel{
+			LocationID: *movement.LocationID,
+			ProductID:  movement.ProductID,
+			VariantID:  variantID,
+			Quantity:   movement.Quantity,
+			UpdatedAt:  now,
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "location_id"}, {Name: "product_id"}, {Name: "variant_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"quantity":   gorm.Expr("stock_levels.quantity + ?", movement.Quantity),
+				"updated_at": now,
+			}),
+		}).Create(level).Error
+	}
+
+	result := tx.Model(&product.StockLevel{}).
+		Where("location_id = ? AND product_id = ? AND variant_id = ? AND quantity + ? >= reserved", *movement.LocationID, movement.ProductID, variantID, movement.Quantity).
+		Updates(map[string]interface{}{
+			"quantity":   gorm.Expr("quantity + ?", movement.Quantity),
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return product.ErrInsufficientStock
+	}
+
+	return nil
+}
+
+// reserveStockLevel suma quantity al stock reservado del nivel de stock de la ubicación de una reserva, o lo resta si es negativa.
+// Al reservar, sólo lo modifica si el stock no reservado del nivel alcanza, en la misma sentencia; si no, devuelve ErrInsufficientStock.
+// Las reservas sin ubicación, anteriores a los niveles de stock, no reservan stock en ninguna ubicación.
+func reserveStockLevel(tx *gorm.DB, reservation *product.Reservation, quantity float64) error {
+	if reservation.LocationID == nil {
+		return nil
+	}
+	variantID := uint(0)
+	if reservation.VariantID != nil {
+		variantID = *reservation.VariantID
+	}
+
+	db := tx.Model(&product.StockLevel{}).
+		Where("location_id = ? AND product_id = ? AND variant_id = ?", *reservation.LocationID, reservation.ProductID, variantID)
+	if quantity > 0 {
+		db = db.Where("quantity - reserved >= ?", quantity)
+	}
+	result := db.Updates(map[string]interface{}{
+		"reserved":   gorm.Expr("reserved + ?", quantity),
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if quantity > 0 && result.RowsAffected == 0 {
+		return product.ErrInsufficientStock
+	}
+
+	return nil
+}
@@ -0,0 +1,1512 @@
+// Package memory implementa product.Store en memoria, sin dependencias externas.
+// Está pensado para pruebas y para ejecutar el servicio localmente sin una base de datos.
+// Respeta la misma semántica que el repositorio ORM: IDs autoincrementales, errores de dominio
+// (product.NotFoundError, product.ConflictError, etc.) y transacciones que se descartan ante un error.
+package memory
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/shopspring/decimal"
+)
+
+// state contiene los datos del repositorio
+type state struct {
+	products       map[uint]*product.Product
+	lastProductID  uint
+	movements      []*product.StockMovement
+	lastMovementID uint
+	reservations   map[string]*product.Reservation
+	events         []*outboxEvent
+	auditEntries   []*product.AuditEntry
+	lastAuditID    uint
+	prices         []*product.Price
+	lastPriceID    uint
+	priceLists     map[uint]*product.PriceList
+	lastListID     uint
+	listPrices     map[listPriceKey]*product.ListPrice
+	categories     map[uint]*product.Category
+	lastCategoryID uint
+	assignments    map[product.ProductCategory]bool // Asignaciones de productos a categorías
+	variants       map[uint]*product.Variant
+	lastVariantID  uint
+	locations      map[uint]*product.Location
+	lastLocationID uint
+	levels         map[levelKey]*product.StockLevel
+}
+
+// levelKey identifica el nivel de stock de un producto, o de una de sus variantes, en una ubicación
+type levelKey struct {
+	locationID uint
+	productID  uint
+	variantID  uint
+}
+
+// listPriceKey identifica el precio de un producto en una lista
+type listPriceKey struct {
+	listID    uint
+	productID uint
+}
+
+// outboxEvent es un evento del outbox, con el momento de su publicación
+type outboxEvent struct {
+	event  *product.Event
+	sentAt *time.Time // nil si está pendiente
+}
+
+func newState() *state {
+	return &state{
+		products:     map[uint]*product.Product{},
+		reservations: map[string]*product.Reservation{},
+		priceLists:   map[uint]*product.PriceList{},
+		listPrices:   map[listPriceKey]*product.ListPrice{},
+		categories:   map[uint]*product.Category{},
+		assignments:  map[product.ProductCategory]bool{},
+		variants:     map[uint]*product.Variant{},
+		// La ubicación por defecto, que en la base de datos crea la migración del esquema
+		locations: map[uint]*product.Location{
+			1: {ID: 1, Code: product.DefaultLocationCode, Name: "Default", CreatedAt: time.Now()},
+		},
+		lastLocationID: 1,
+		levels:         map[levelKey]*product.StockLevel{},
+	}
+}
+
+// clone devuelve una copia del estado, que permite descartar los cambios de una transacción
+func (s *state) clone() *state {
+	c := &state{
+		products:       make(map[uint]*product.Product, len(s.products)),
+		lastProductID:  s.lastProductID,
+		movements:      make([]*product.StockMovement, len(s.movements)),
+		lastMovementID: s.lastMovementID,
+		reservations:   make(map[string]*product.Reservation, len(s.reservations)),
+		events:         make([]*outboxEvent, len(s.events)),
+		auditEntries:   make([]*product.AuditEntry, len(s.auditEntries)),
+		lastAuditID:    s.lastAuditID,
+		prices:         make([]*product.Price, len(s.prices)),
+		lastPriceID:    s.lastPriceID,
+		priceLists:     make(map[uint]*product.PriceList, len(s.priceLists)),
+		lastListID:     s.lastListID,
+		listPrices:     make(map[listPriceKey]*product.ListPrice, len(s.listPrices)),
+		categories:     make(map[uint]*product.Category, len(s.categories)),
+		lastCategoryID: s.lastCategoryID,
+		assignments:    make(map[product.ProductCategory]bool, len(s.assignments)),
+		variants:       make(map[uint]*product.Variant, len(s.variants)),
+		lastVariantID:  s.lastVariantID,
+		locations:      make(map[uint]*product.Location, len(s.locations)),
+		lastLocationID: s.lastLocationID,
+		levels:         make(map[levelKey]*product.StockLevel, len(s.levels)),
+	}
+
+	for id, p := range s.products {
+		c.products[id] = copyProduct(p)
+	}
+	copy(c.movements, s.movements)
+	copy(c.auditEntries, s.auditEntries)
+	for id, r := range s.reservations {
+		reservation := *r
+		c.reservations[id] = &reservation
+	}
+	for i, e := range s.events {
+		event := *e
+		c.events[i] = &event
+	}
+	for i, p := range s.prices {
+		price := *p
+		c.prices[i] = &price
+	}
+	for id, l := range s.priceLists {
+		list := *l
+		c.priceLists[id] = &list
+	}
+	for key, p := range s.listPrices {
+		price := *p
+		c.listPrices[key] = &price
+	}
+	for id, cat := range s.categories {
+		c.categories[id] = copyCategory(cat)
+	}
+	for a := range s.assignments {
+		c.assignments[a] = true
+	}
+	for id, v := range s.variants {
+		c.variants[id] = copyVariant(v)
+	}
+	for id, l := range s.locations {
+		location := *l
+		c.locations[id] = &location
+	}
+	for key, l := range s.levels {
+		level := *l
+		c.levels[key] = &level
+	}
+
+	return c
+}
+
+type memoryRepo struct {
+	mu    *sync.Mutex
+	state *state
+	inTx  bool // Indica si el repositorio opera dentro de una transacción, que ya tiene tomado el lock
+}
+
+// NewRepo crea un repositorio en memoria vacío
+func NewRepo() product.Store {
+	return &memoryRepo{
+		mu:    &sync.Mutex{},
+		state: newState(),
+	}
+}
+
+// lock toma el lock del repositorio, salvo dentro de una transacción, y devuelve la función que lo libera
+func (r *memoryRepo) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+// Transaction ejecuta fn con el lock del repositorio tomado, de modo que las transacciones se ejecutan de a una.
+// Si fn devuelve un error, se restaura el estado previo.
+func (r *memoryRepo) Transaction(fn func(store product.Store) error) error {
+	defer r.lock()()
+
+	snapshot := r.state.clone()
+	tx := &memoryRepo{
+		mu:    r.mu,
+		state: r.state,
+		inTx:  true,
+	}
+
+	if err := fn(tx); err != nil {
+		*r.state = *snapshot
+		return err
+	}
+
+	return nil
+}
+
+func copyProduct(p *product.Product) *product.Product {
+	c := *p
+	return &c
+}
+
+// getProduct recupera un producto que no esté eliminado
+func (r *memoryRepo) getProduct(id uint) (*product.Product, error) {
+	p, ok := r.state.products[id]
+	if !ok || p.DeletedAt != nil {
+		return nil, &product.NotFoundError{Entity: "product", Key: id}
+	}
+
+	return p, nil
+}
+
+// checkName verifica que ningún otro producto tenga el nombre normalizado de p, como el índice único de la base de datos.
+// Los productos eliminados liberan su nombre.
+func (r *memoryRepo) checkName(p *product.Product) error {
+	for _, other := range r.state.products {
+		if other.DeletedAt == nil && other.NameKey == p.NameKey && other.ID != p.ID {
+			return &product.AlreadyExistsError{Entity: "product", Field: "name", Value: p.Name}
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryRepo) Create(p *product.Product) (*product.Product, error) {
+	defer r.lock()()
+
+	p.NameKey = product.NormalizeName(p.Name)
+	if err := r.checkName(p); err != nil {
+		return nil, err
+	}
+
+	r.state.lastProductID++
+	p.ID = r.state.lastProductID
+	r.state.products[p.ID] = copyProduct(p)
+
+	return p, nil
+}
+
+func (r *memoryRepo) GetByID(id uint) (*product.Product, error) {
+	defer r.lock()()
+
+	p, err := r.getProduct(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyProduct(p), nil
+}
+
+func (r *memoryRepo) GetByIDIncludeDeleted(id uint) (*product.Product, error) {
+	defer r.lock()()
+
+	p, ok := r.state.products[id]
+	if !ok {
+		return nil, &product.NotFoundError{Entity: "product", Key: id}
+	}
+
+	return copyProduct(p), nil
+}
+
+func (r *memoryRepo) GetByName(name string) (*product.Product, error) {
+	defer r.lock()()
+
+	key := product.NormalizeName(name)
+	for _, p := range r.state.products {
+		if p.DeletedAt == nil && p.NameKey == key {
+			return copyProduct(p), nil
+		}
+	}
+
+	return nil, &product.NotFoundError{Entity: "product", Key: name}
+}
+
+func (r *memoryRepo) GetAll(query *product.Query) (*product.Page, error) {
+	defer r.lock()()
+
+	products := []*product.Product{}
+	for _, p := range r.state.products {
+		if matches(p, query) && r.inCategory(p.ID, query.CategoryPath) {
+			products = append(products, p)
+		}
+	}
+
+	page := &product.Page{
+		Products: []*product.Product{},
+	}
+	if !query.SkipTotal {
+		page.Total = int64(len(products))
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		return less(products[i], products[j], query)
+	})
+
+	offset := query.Offset
+	if query.After != nil {
+		offset = sort.Search(len(products), func(i int) bool {
+			return after(products[i], query)
+		})
+	}
+	if offset > len(products) {
+		offset = len(products)
+	}
+	products = products[offset:]
+
+	if query.Limit > 0 && len(products) > query.Limit {
+		products = products[:query.Limit]
+	}
+	for _, p := range products {
+		page.Products = append(page.Products, copyProduct(p))
+	}
+
+	return page, nil
+}
+
+// matches indica si p cumple los filtros de query
+func matches(p *product.Product, query *product.Query) bool {
+	switch {
+	case !query.IncludeDeleted && p.DeletedAt != nil,
+		query.IsActive != nil && p.IsActive != *query.IsActive,
+		query.MinPrice != nil && p.Price.LessThan(*query.MinPrice),
+		query.MaxPrice != nil && p.Price.GreaterThan(*query.MaxPrice),
+		query.MinStock != nil && p.Stock < *query.MinStock,
+		query.MaxStock != nil && p.Stock > *query.MaxStock,
+		query.LowStock && !p.IsLowStock(),
+		query.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(p.Name), strings.ToLower(query.NamePrefix)):
+		return false
+	}
+
+	return true
+}
+
+// inCategory indica si el producto está asignado a la categoría con camino path o a alguna de sus subcategorías.
+// Si path es vacío, no se filtra por categoría.
+func (r *memoryRepo) inCategory(productID uint, path string) bool {
+	if path == "" {
+		return true
+	}
+
+	for a := range r.state.assignments {
+		if a.ProductID == productID && strings.HasPrefix(r.state.categories[a.CategoryID].Path, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compare compara a y b por el campo de orden de query. Devuelve un valor negativo, cero o positivo.
+func compare(a, b *product.Product, sortBy string) int {
+	switch sortBy {
+	case product.SortByName:
+		return strings.Compare(product.NameSortKey(a.Name), product.NameSortKey(b.Name))
+	case product.SortByPrice:
+		return a.Price.Cmp(b.Price)
+	case product.SortByStock:
+		return compareFloat(a.Stock, b.Stock)
+	}
+
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// less indica si a va antes que b según el orden de query. El ID desempata.
+func less(a, b *product.Product, query *product.Query) bool {
+	c := compare(a, b, query.SortBy)
+	if c == 0 {
+		c = compareFloat(float64(a.ID), float64(b.ID))
+	}
+	if query.SortOrder == product.SortDesc {
+		return c > 0
+	}
+
+	return c < 0
+}
+
+// after indica si p va después de la posición indicada por el cursor de query
+func after(p *product.Product, query *product.Query) bool {
+	cursor := &product.Product{ID: query.After.ID}
+	switch value := query.After.Value.(type) {
+	case string:
+		cursor.Name = value
+	case decimal.Decimal:
+		cursor.Price = value
+	case float64:
+		cursor.Stock = value
+	}
+
+	return less(cursor, p, query)
+}
+
+func (r *memoryRepo) Update(p *product.Product) (*product.Product, error) {
+	defer r.lock()()
+
+	current, err := r.getProduct(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Version != 0 && p.Version != current.Version {
+		return nil, &product.ConflictError{ID: p.ID, Version: p.Version}
+	}
+	p.NameKey = product.NormalizeName(p.Name)
+	if err := r.checkName(p); err != nil {
+		return nil, err
+	}
+
+	// Como en la base de datos, sólo se modifican los atributos que puede cambiar Update:
+	// el stock sólo se modifica a través de AddStockMovement, y el stock reservado a través de las reservas
+	updated := copyProduct(current)
+	updated.Name = p.Name
+	updated.NameKey = p.NameKey
+	updated.Description = p.Description
+	updated.Unit = p.Unit
+	updated.Price = p.Price
+	updated.Currency = p.Currency
+	updated.ReorderThreshold = p.ReorderThreshold
+	updated.ReorderQuantity = p.ReorderQuantity
+	updated.IsActive = p.IsActive
+	updated.Version = current.Version + 1
+	r.state.products[p.ID] = updated
+
+	*p = *updated
+	return p, nil
+}
+
+func (r *memoryRepo) Delete(p *product.Product) error {
+	defer r.lock()()
+
+	current, err := r.getProduct(p.ID)
+	if err != nil {
+		return nil
+	}
+
+	// Se libera el nombre normalizado, para que pueda usarlo otro producto
+	now := time.Now()
+	current.DeletedAt = &now
+	current.NameKey = ""
+	current.Version++
+
+	return nil
+}
+
+func (r *memoryRepo) Restore(p *product.Product) (*product.Product, error) {
+	defer r.lock()()
+
+	current, ok := r.state.products[p.ID]
+	if !ok || current.DeletedAt == nil {
+		return nil, &product.NotFoundError{Entity: "product", Key: p.ID}
+	}
+
+	restored := copyProduct(current)
+	restored.DeletedAt = nil
+	restored.NameKey = product.NormalizeName(restored.Name)
+	if err := r.checkName(restored); err != nil {
+		return nil, err
+	}
+	restored.Version++
+	r.state.products[p.ID] = restored
+
+	return copyProduct(restored), nil
+}
+
+func (r *memoryRepo) Purge(p *product.Product) error {
+	defer r.lock()()
+
+	for id, v := range r.state.variants {
+		if v.ProductID == p.ID {
+			delete(r.state.variants, id)
+		}
+	}
+	for key := range r.state.levels {
+		if key.productID == p.ID {
+			delete(r.state.levels, key)
+		}
+	}
+	for assignment := range r.state.assignments {
+		if assignment.ProductID == p.ID {
+			delete(r.state.assignments, assignment)
+		}
+	}
+	for key := range r.state.listPrices {
+		if key.productID == p.ID {
+			delete(r.state.listPrices, key)
+		}
+	}
+	prices := make([]*product.Price, 0, len(r.state.prices))
+	for _, price := range r.state.prices {
+		if price.ProductID != p.ID || price.Status != product.PriceScheduled {
+			prices = append(prices, price)
+		}
+	}
+	r.state.prices = prices
+	now := time.Now()
+	for _, res := range r.state.reservations {
+		if res.ProductID == p.ID && res.Status == product.ReservationPending {
+			res.Status = product.ReservationReleased
+			res.UpdatedAt = now
+		}
+	}
+	delete(r.state.products, p.ID)
+	return nil
+}
+
+func (r *memoryRepo) AddEvent(event *product.Event) error {
+	defer r.lock()()
+
+	r.state.events = append(r.state.events, &outboxEvent{event: event})
+	return nil
+}
+
+func (r *memoryRepo) GetPendingEvents(limit int) ([]*product.Event, error) {
+	defer r.lock()()
+
+	events := []*product.Event{}
+	for _, e := range r.state.events {
+		if len(events) == limit {
+			break
+		}
+		if e.sentAt == nil {
+			events = append(events, e.event)
+		}
+	}
+
+	return events, nil
+}
+
+func (r *memoryRepo) MarkEventSent(event *product.Event) error {
+	defer r.lock()()
+
+	now := time.Now()
+	for _, e := range r.state.events {
+		if e.event.ID == event.ID {
+			e.sentAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryRepo) DeleteSentEvents(before time.Time) (int64, error) {
+	defer r.lock()()
+
+	events := []*outboxEvent{}
+	for _, e := range r.state.events {
+		if e.sentAt == nil || !e.sentAt.Before(before) {
+			events = append(events, e)
+		}
+	}
+	deleted := int64(len(r.state.events) - len(events))
+	r.state.events = events
+
+	return deleted, nil
+}
+
+func (r *memoryRepo) AddStockMovement(movement *product.StockMovement) (*product.Product, error) {
+	defer r.lock()()
+
+	p, err := r.getProduct(movement.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Stock+movement.Quantity < p.Reserved {
+		return nil, product.ErrInsufficientStock
+	}
+	var v *product.Variant
+	if movement.VariantID != nil {
+		if v, err = r.getVariant(movement.ProductID, *movement.VariantID); err != nil {
+			return nil, err
+		}
+		if v.Stock+movement.Quantity < v.Reserved {
+			return nil, product.ErrInsufficientStock
+		}
+	}
+	if err := r.defaultLocation(movement); err != nil {
+		return nil, err
+	}
+	if err := r.checkStockLevel(movement); err != nil {
+		return nil, err
+	}
+
+	p.Stock += movement.Quantity
+	p.Version++
+	if v != nil {
+		v.Stock += movement.Quantity
+		v.UpdatedAt = time.Now()
+	}
+	r.updateStockLevel(movement)
+
+	r.state.lastMovementID++
+	movement.ID = r.state.lastMovementID
+	if movement.CreatedAt.IsZero() {
+		movement.CreatedAt = time.Now()
+	}
+	m := *movement
+	r.state.movements = append(r.state.movements, &m)
+
+	return copyProduct(p), nil
+}
+
+func (r *memoryRepo) GetStockMovements(productID uint) ([]*product.StockMovement, error) {
+	defer r.lock()()
+
+	movements := []*product.StockMovement{}
+	for _, m := range r.state.movements {
+		if m.ProductID == productID {
+			movement := *m
+			movements = append(movements, &movement)
+		}
+	}
+
+	return movements, nil
+}
+
+func (r *memoryRepo) CreateReservation(reservation *product.Reservation) (*product.Product, error) {
+	defer r.lock()()
+
+	p, err := r.getProduct(reservation.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Stock-p.Reserved < reservation.Quantity {
+		return nil, product.ErrInsufficientStock
+	}
+	var v *product.Variant
+	if reservation.VariantID != nil {
+		if v, err = r.getVariant(reservation.ProductID, *reservation.VariantID); err != nil {
+			return nil, err
+		}
+		if v.Stock-v.Reserved < reservation.Quantity {
+			return nil, product.ErrInsufficientStock
+		}
+	}
+	level, ok := r.reservationLevel(reservation)
+	if !ok || level != nil && level.Quantity-level.Reserved < reservation.Quantity {
+		return nil, product.ErrInsufficientStock
+	}
+	if _, ok := r.state.reservations[reservation.ID]; ok {
+		return nil, &product.AlreadyExistsError{Entity: "reservation", Field: "id", Value: reservation.ID}
+	}
+
+	now := time.Now()
+	p.Reserved += reservation.Quantity
+	p.Version++
+	if v != nil {
+		v.Reserved += reservation.Quantity
+		v.UpdatedAt = now
+	}
+	if level != nil {
+		level.Reserved += reservation.Quantity
+		level.UpdatedAt = now
+	}
+
+	reservation.CreatedAt = now
+	reservation.UpdatedAt = now
+	res := *reservation
+	r.state.reservations[reservation.ID] = &res
+
+	return copyProduct(p), nil
+}
+
+func (r *memoryRepo) GetReservation(id string) (*product.Reservation, error) {
+	defer r.lock()()
+
+	res, ok := r.state.reservations[id]
+	if !ok {
+		return nil, &product.NotFoundError{Entity: "reservation", Key: id}
+	}
+
+	reservation := *res
+	return &reservation, nil
+}
+
+func (r *memoryRepo) CloseReservation(reservation *product.Reservation, status string) (*product.Product, error) {
+	defer r.lock()()
+
+	res, ok := r.state.reservations[reservation.ID]
+	if !ok || res.Status != product.ReservationPending {
+		return nil, product.ErrReservationClosed
+	}
+
+	// Como en la base de datos, las reservas de un producto eliminado también se cierran
+	p, ok := r.state.products[res.ProductID]
+	if !ok {
+		return nil, &product.NotFoundError{Entity: "product", Key: res.ProductID}
+	}
+
+	res.Status = status
+	res.UpdatedAt = time.Now()
+	p.Reserved -= res.Quantity
+	p.Version++
+	if res.VariantID != nil {
+		if v, ok := r.state.variants[*res.VariantID]; ok {
+			v.Reserved -= res.Quantity
+			v.UpdatedAt = res.UpdatedAt
+		}
+	}
+	if level, _ := r.reservationLevel(res); level != nil {
+		level.Reserved -= res.Quantity
+		level.UpdatedAt = res.UpdatedAt
+	}
+
+	return copyProduct(p), nil
+}
+
+func (r *memoryRepo) GetExpiredReservations(now time.Time, limit int) ([]*product.Reservation, error) {
+	defer r.lock()()
+
+	reservations := []*product.Reservation{}
+	for _, res := range r.state.reservations {
+		if res.Status == product.ReservationPending && res.ExpiresAt.Before(now) {
+			reservation := *res
+			reservations = append(reservations, &reservation)
+		}
+	}
+
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].ExpiresAt.Before(reservations[j].ExpiresAt)
+	})
+	if len(reservations) > limit {
+		reservations = reservations[:limit]
+	}
+
+	return reservations, nil
+}
+
+func (r *memoryRepo) AddAuditEntry(entry *product.AuditEntry) error {
+	defer r.lock()()
+
+	r.state.lastAuditID++
+	entry.ID = r.state.lastAuditID
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	e := *entry
+	r.state.auditEntries = append(r.state.auditEntries, &e)
+
+	return nil
+}
+
+func (r *memoryRepo) GetAuditEntries(query *product.HistoryQuery) (*product.HistoryPage, error) {
+	defer r.lock()()
+
+	page := &product.HistoryPage{
+		Entries: []*product.AuditEntry{},
+	}
+
+	skipped := 0
+	for i := len(r.state.auditEntries) - 1; i >= 0; i-- {
+		e := r.state.auditEntries[i]
+		if e.ProductID != query.ProductID {
+			continue
+		}
+
+		page.Total++
+		if skipped < query.Offset {
+			skipped++
+			continue
+		}
+		if len(page.Entries) < query.Limit {
+			entry := *e
+			page.Entries = append(page.Entries, &entry)
+		}
+	}
+
+	return page, nil
+}
+
+func (r *memoryRepo) AddPrice(price *product.Price) error {
+	defer r.lock()()
+
+	if price.Status == product.PriceApplied {
+		r.closePrice(price.ProductID, price.EffectiveFrom)
+	}
+
+	r.state.lastPriceID++
+	price.ID = r.state.lastPriceID
+	if price.CreatedAt.IsZero() {
+		price.CreatedAt = time.Now()
+	}
+	p := *price
+	r.state.prices = append(r.state.prices, &p)
+
+	return nil
+}
+
+func (r *memoryRepo) GetPrice(id uint) (*product.Price, error) {
+	defer r.lock()()
+
+	p, err := r.getPrice(id)
+	if err != nil {
+		return nil, err
+	}
+
+	price := *p
+	return &price, nil
+}
+
+func (r *memoryRepo) ApplyPrice(price *product.Price) error {
+	defer r.lock()()
+
+	p, err := r.getPrice(price.ID)
+	if err != nil || p.Status != product.PriceScheduled {
+		return product.ErrPriceNotScheduled
+	}
+
+	r.closePrice(p.ProductID, price.EffectiveFrom)
+	p.Status = product.PriceApplied
+	p.EffectiveFrom = price.EffectiveFrom
+	price.Status = product.PriceApplied
+
+	return nil
+}
+
+func (r *memoryRepo) CancelPrice(price *product.Price) error {
+	defer r.lock()()
+
+	p, err := r.getPrice(price.ID)
+	if err != nil || p.Status != product.PriceScheduled {
+		return product.ErrPriceNotScheduled
+	}
+
+	p.Status = product.PriceCancelled
+	price.Status = product.PriceCancelled
+
+	return nil
+}
+
+func (r *memoryRepo) GetPrices(productID uint) ([]*product.Price, error) {
+	defer r.lock()()
+
+	prices := []*product.Price{}
+	for _, p := range r.state.prices {
+		if p.ProductID == productID {
+			price := *p
+			prices = append(prices, &price)
+		}
+	}
+	sortPrices(prices)
+
+	return prices, nil
+}
+
+func (r *memoryRepo) GetDuePrices(now time.Time, limit int) ([]*product.Price, error) {
+	defer r.lock()()
+
+	prices := []*product.Price{}
+	for _, p := range r.state.prices {
+		if p.Status == product.PriceScheduled && !p.EffectiveFrom.After(now) {
+			price := *p
+			prices = append(prices, &price)
+		}
+	}
+	sortPrices(prices)
+	if len(prices) > limit {
+		prices = prices[:limit]
+	}
+
+	return prices, nil
+}
+
+func (r *memoryRepo) getPrice(id uint) (*product.Price, error) {
+	for _, p := range r.state.prices {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+
+	return nil, &product.NotFoundError{Entity: "price", Key: id}
+}
+
+// closePrice cierra en el momento at la vigencia del precio vigente de un producto
+func (r *memoryRepo) closePrice(productID uint, at time.Time) {
+	for _, p := range r.state.prices {
+		if p.ProductID == productID && p.Status == product.PriceApplied && p.EffectiveTo == nil {
+			effectiveTo := at
+			p.EffectiveTo = &effectiveTo
+		}
+	}
+}
+
+// sortPrices ordena los precios por inicio de vigencia y, a igual inicio, por ID
+func sortPrices(prices []*product.Price) {
+	sort.Slice(prices, func(i, j int) bool {
+		if !prices[i].EffectiveFrom.Equal(prices[j].EffectiveFrom) {
+			return prices[i].EffectiveFrom.Before(prices[j].EffectiveFrom)
+		}
+		return prices[i].ID < prices[j].ID
+	})
+}
+
+func (r *memoryRepo) CreatePriceList(list *product.PriceList) error {
+	defer r.lock()()
+
+	for _, l := range r.state.priceLists {
+		if l.Name == list.Name {
+			return &product.AlreadyExistsError{Entity: "price list", Field: "name", Value: list.Name}
+		}
+	}
+
+	r.state.lastListID++
+	list.ID = r.state.lastListID
+	if list.CreatedAt.IsZero() {
+		list.CreatedAt = time.Now()
+	}
+	l := *list
+	r.state.priceLists[list.ID] = &l
+
+	return nil
+}
+
+func (r *memoryRepo) GetPriceList(name string) (*product.PriceList, error) {
+	defer r.lock()()
+
+	for _, l := range r.state.priceLists {
+		if l.Name == name {
+			list := *l
+			return &list, nil
+		}
+	}
+
+	return nil, &product.NotFoundError{Entity: "price list", Key: name}
+}
+
+func (r *memoryRepo) GetPriceLists() ([]*product.PriceList, error) {
+	defer r.lock()()
+
+	lists := []*product.PriceList{}
+	for _, l := range r.state.priceLists {
+		list := *l
+		lists = append(lists, &list)
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Name < lists[j].Name
+	})
+
+	return lists, nil
+}
+
+func (r *memoryRepo) DeletePriceList(list *product.PriceList) error {
+	defer r.lock()()
+
+	for key := range r.state.listPrices {
+		if key.listID == list.ID {
+			delete(r.state.listPrices, key)
+		}
+	}
+	delete(r.state.priceLists, list.ID)
+
+	return nil
+}
+
+func (r *memoryRepo) SetListPrices(prices []*product.ListPrice) error {
+	defer r.lock()()
+
+	now := time.Now()
+	for _, price := range prices {
+		price.UpdatedAt = now
+		p := *price
+		r.state.listPrices[listPriceKey{listID: price.PriceListID, productID: price.ProductID}] = &p
+	}
+
+	return nil
+}
+
+func (r *memoryRepo) RemoveListPrices(listID uint, productIDs []uint) (int64, error) {
+	defer r.lock()()
+
+	removed := int64(0)
+	for _, productID := range productIDs {
+		key := listPriceKey{listID: listID, productID: productID}
+		if _, ok := r.state.listPrices[key]; ok {
+			delete(r.state.listPrices, key)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+func (r *memoryRepo) GetListPrice(listID, productID uint) (*product.ListPrice, error) {
+	defer r.lock()()
+
+	p, ok := r.state.listPrices[listPriceKey{listID: listID, productID: productID}]
+	if !ok {
+		return nil, &product.NotFoundError{Entity: "list price", Key: productID}
+	}
+
+	price := *p
+	return &price, nil
+}
+
+func (r *memoryRepo) GetListPrices(listID uint, query *product.ListPricesQuery) (*product.ListPricesPage, error) {
+	defer r.lock()()
+
+	prices := []*product.ListPrice{}
+	for key, p := range r.state.listPrices {
+		if key.listID == listID {
+			price := *p
+			prices = append(prices, &price)
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].ProductID < prices[j].ProductID
+	})
+
+	page := &product.ListPricesPage{
+		Prices: []*product.ListPrice{},
+		Total:  int64(len(prices)),
+	}
+	if query.Offset < len(prices) {
+		prices = prices[query.Offset:]
+		if len(prices) > query.Limit {
+			prices = prices[:query.Limit]
+		}
+		page.Prices = prices
+	}
+
+	return page, nil
+}
+
+func copyCategory(c *product.Category) *product.Category {
+	category := *c
+	if c.ParentID != nil {
+		parentID := *c.ParentID
+		category.ParentID = &parentID
+	}
+	return &category
+}
+
+// checkCategoryName verifica que ninguna otra categoría tenga la clave única de c, como el índice único de la base de datos
+func (r *memoryRepo) checkCategoryName(c *product.Category) error {
+	for _, other := range r.state.categories {
+		if other.NameKey == c.NameKey && other.ID != c.ID {
+			return &product.AlreadyExistsError{Entity: "category", Field: "name", Value: c.Name}
+		}
+	}
+
+	return nil
+}
+
+// parentPath devuelve el camino de la categoría padre, o "" si la categoría es raíz
+func (r *memoryRepo) parentPath(parentID *uint) (string, error) {
+	if parentID == nil {
+		return "", nil
+	}
+
+	parent, ok := r.state.categories[*parentID]
+	if !ok {
+		return "", &product.NotFoundError{Entity: "category", Key: *parentID}
+	}
+
+	return parent.Path, nil
+}
+
+func (r *memoryRepo) CreateCategory(category *product.Category) error {
+	defer r.lock()()
+
+	path, err := r.parentPath(category.ParentID)
+	if err != nil {
+		return err
+	}
+	category.NameKey = product.CategoryNameKey(category.ParentID, category.Name)
+	if err := r.checkCategoryName(category); err != nil {
+		return err
+	}
+
+	r.state.lastCategoryID++
+	category.ID = r.state.lastCategoryID
+	category.Path = product.CategoryPath(path, category.ID)
+	now := time.Now()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	r.state.categories[category.ID] = copyCategory(category)
+
+	return nil
+}
+
+func (r *memoryRepo) GetCategory(id uint) (*product.Category, error) {
+	defer r.lock()()
+
+	c, ok := r.state.categories[id]
+	if !ok {
+		return nil, &product.NotFoundError{Entity: "category", Key: id}
+	}
+
+	return copyCategory(c), nil
+}
+
+// sortCategories ordena las categorías por camino
+func sortCategories(categories []*product.Category) {
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Path < categories[j].Path
+	})
+}
+
+func (r *memoryRepo) GetCategories() ([]*product.Category, error) {
+	defer r.lock()()
+
+	categories := []*product.Category{}
+	for _, c := range r.state.categories {
+		categories = append(categories, copyCategory(c))
+	}
+	sortCategories(categories)
+
+	return categories, nil
+}
+
+func (r *memoryRepo) UpdateCategory(category *product.Category) error {
+	defer r.lock()()
+
+	current, ok := r.state.categories[category.ID]
+	if !ok {
+		return &product.NotFoundError{Entity: "category", Key: category.ID}
+	}
+	path, err := r.parentPath(category.ParentID)
+	if err != nil {
+		return err
+	}
+	category.NameKey = product.CategoryNameKey(category.ParentID, category.Name)
+	if err := r.checkCategoryName(category); err != nil {
+		return err
+	}
+
+	// Si la categoría se movió, sus subcategorías se mueven con ella
+	oldPath := current.Path
+	category.Path = product.CategoryPath(path, category.ID)
+	if category.Path != oldPath {
+		for _, c := range r.state.categories {
+			if c.ID != category.ID && strings.HasPrefix(c.Path, oldPath) {
+				c.Path = category.Path + strings.TrimPrefix(c.Path, oldPath)
+			}
+		}
+	}
+
+	category.CreatedAt = current.CreatedAt
+	category.UpdatedAt = time.Now()
+	r.state.categories[category.ID] = copyCategory(category)
+
+	return nil
+}
+
+func (r *memoryRepo) DeleteCategory(category *product.Category) error {
+	defer r.lock()()
+
+	for _, c := range r.state.categories {
+		if c.ParentID != nil && *c.ParentID == category.ID {
+			return product.ErrCategoryNotEmpty
+		}
+	}
+
+	for a := range r.state.assignments {
+		if a.CategoryID == category.ID {
+			delete(r.state.assignments, a)
+		}
+	}
+	delete(r.state.categories, category.ID)
+
+	return nil
+}
+
+func (r *memoryRepo) SetProductCategories(productID uint, categoryIDs []uint) error {
+	defer r.lock()()
+
+	for a := range r.state.assignments {
+		if a.ProductID == productID {
+			delete(r.state.assignments, a)
+		}
+	}
+	for _, categoryID := range categoryIDs {
+		r.state.assignments[product.ProductCategory{ProductID: productID, CategoryID: categoryID}] = true
+	}
+
+	return nil
+}
+
+func (r *memoryRepo) GetProductCategories(productID uint) ([]*product.Category, error) {
+	defer r.lock()()
+
+	categories := []*product.Category{}
+	for a := range r.state.assignments {
+		if c, ok := r.state.categories[a.CategoryID]; ok && a.ProductID == productID {
+			categories = append(categories, copyCategory(c))
+		}
+	}
+	sortCategories(categories)
+
+	return categories, nil
+}
+
+func copyVariant(v *product.Variant) *product.Variant {
+	variant := *v
+	if v.Options != nil {
+		variant.Options = make(map[string]string, len(v.Options))
+		for key, value := range v.Options {
+			variant.Options[key] = value
+		}
+	}
+	if v.Price != nil {
+		price := *v.Price
+		variant.Price = &price
+	}
+	return &variant
+}
+
+// getVariant recupera una variante del producto productID
+func (r *memoryRepo) getVariant(productID, id uint) (*product.Variant, error) {
+	v, ok := r.state.variants[id]
+	if !ok || v.ProductID != productID {
+		return nil, &product.NotFoundError{Entity: "variant", Key: id}
+	}
+
+	return v, nil
+}
+
+// checkSKU verifica que ninguna otra variante tenga el SKU de v, como el índice único de la base de datos
+func (r *memoryRepo) checkSKU(v *product.Variant) error {
+	for _, other := range r.state.variants {
+		if other.SKU == v.SKU && other.ID != v.ID {
+			return &product.AlreadyExistsError{Entity: "variant", Field: "sku", Value: v.SKU}
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryRepo) CreateVariant(variant *product.Variant) error {
+	defer r.lock()()
+
+	if err := r.checkSKU(variant); err != nil {
+		return err
+	}
+
+	r.state.lastVariantID++
+	variant.ID = r.state.lastVariantID
+	now := time.Now()
+	variant.CreatedAt = now
+	variant.UpdatedAt = now
+	r.state.variants[variant.ID] = copyVariant(variant)
+
+	return nil
+}
+
+func (r *memoryRepo) GetVariant(id uint) (*product.Variant, error) {
+	defer r.lock()()
+
+	v, ok := r.state.variants[id]
+	if !ok {
+		return nil, &product.NotFoundError{Entity: "variant", Key: id}
+	}
+
+	return copyVariant(v), nil
+}
+
+func (r *memoryRepo) GetVariantBySKU(sku string) (*product.Variant, error) {
+	defer r.lock()()
+
+	for _, v := range r.state.variants {
+		if v.SKU == sku {
+			return copyVariant(v), nil
+		}
+	}
+
+	return nil, &product.NotFoundError{Entity: "variant", Key: sku}
+}
+
+func (r *memoryRepo) GetVariants(productID uint) ([]*product.Variant, error) {
+	defer r.lock()()
+
+	variants := []*product.Variant{}
+	for _, v := range r.state.variants {
+		if v.ProductID == productID {
+			variants = append(variants, copyVariant(v))
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i].ID < variants[j].ID
+	})
+
+	return variants, nil
+}
+
+func (r *memoryRepo) UpdateVariant(variant *product.Variant) error {
+	defer r.lock()()
+
+	current, ok := r.state.variants[variant.ID]
+	if !ok {
+		return &product.NotFoundError{Entity: "variant", Key: variant.ID}
+	}
+	if err := r.checkSKU(variant); err != nil {
+		return err
+	}
+
+	// El stock sólo se modifica a través de los movimientos de stock y las reservas
+	updated := copyVariant(variant)
+	updated.ProductID = current.ProductID
+	updated.Stock = current.Stock
+	updated.Reserved = current.Reserved
+	updated.CreatedAt = current.CreatedAt
+	updated.UpdatedAt = time.Now()
+	r.state.variants[variant.ID] = updated
+	*variant = *copyVariant(updated)
+
+	return nil
+}
+
+func (r *memoryRepo) DeleteVariant(variant *product.Variant) error {
+	defer r.lock()()
+
+	v, ok := r.state.variants[variant.ID]
+	if !ok {
+		return &product.NotFoundError{Entity: "variant", Key: variant.ID}
+	}
+	if v.Stock != 0 || v.Reserved != 0 {
+		return product.ErrVariantHasStock
+	}
+
+	delete(r.state.variants, variant.ID)
+	return nil
+}
+
+// defaultLocation asigna la ubicación por defecto a un movimiento que no indica ubicación
+func (r *memoryRepo) defaultLocation(movement *product.StockMovement) error {
+	if movement.LocationID != nil {
+		return nil
+	}
+
+	for _, l := range r.state.locations {
+		if l.Code == product.DefaultLocationCode {
+			id := l.ID
+			movement.LocationID = &id
+			return nil
+		}
+	}
+
+	return &product.NotFoundError{Entity: "location", Key: product.DefaultLocationCode}
+}
+
+// movementLevelKey devuelve la clave del nivel de stock que modifica un movimiento
+func movementLevelKey(movement *product.StockMovement) levelKey {
+	key := levelKey{locationID: *movement.LocationID, productID: movement.ProductID}
+	if movement.VariantID != nil {
+		key.variantID = *movement.VariantID
+	}
+	return key
+}
+
+// checkStockLevel verifica que el nivel de stock que modifica un movimiento no quede por debajo de su stock reservado
+func (r *memoryRepo) checkStockLevel(movement *product.StockMovement) error {
+	if movement.Quantity >= 0 {
+		return nil
+	}
+
+	level, ok := r.state.levels[movementLevelKey(movement)]
+	if !ok || level.Quantity+movement.Quantity < level.Reserved {
+		return product.ErrInsufficientStock
+	}
+
+	return nil
+}
+
+// reservationLevel devuelve el nivel de stock de la ubicación de una reserva. Si la reserva no indica ubicación,
+// devuelve nil y true, porque no reserva stock en ninguna ubicación; si el nivel no existe, nil y false.
+func (r *memoryRepo) reservationLevel(reservation *product.Reservation) (*product.StockLevel, bool) {
+	if reservation.LocationID == nil {
+		return nil, true
+	}
+	key := levelKey{locationID: *reservation.LocationID, productID: reservation.ProductID}
+	if reservation.VariantID != nil {
+		key.variantID = *reservation.VariantID
+	}
+
+	level, ok := r.state.levels[key]
+	return level, ok
+}
+
+// updateStockLevel aplica la cantidad de un movimiento al nivel de stock de su ubicación, creándolo si no existe
+func (r *memoryRepo) updateStockLevel(movement *product.StockMovement) {
+	key := movementLevelKey(movement)
+	level, ok := r.state.levels[key]
+	if !ok {
+		level = &product.StockLevel{LocationID: key.locationID, ProductID: key.productID, VariantID: key.variantID}
+		r.state.levels[key] = level
+	}
+	level.Quantity += movement.Quantity
+	level.UpdatedAt = time.Now()
+}
+
+func (r *memoryRepo) CreateLocation(location *product.Location) error {
+	defer r.lock()()
+
+	for _, other := range r.state.locations {
+		if other.Code == location.Code {
+			return &product.AlreadyExistsError{Entity: "location", Field: "code", Value: location.Code}
+		}
+	}
+
+	r.state.lastLocationID++
+	location.ID = r.state.lastLocationID
+	location.CreatedAt = time.Now()
+	l := *location
+	r.state.locations[location.ID] = &l
+
+	return nil
+}
+
+func (r *memoryRepo) GetLocation(id uint) (*product.Location, error) {
+	defer r.lock()()
+
+	l, ok := r.state.locations[id]
+	if !ok {
+		return nil, &product.NotFoundError{Entity: "location", Key: id}
+	}
+
+	location := *l
+	return &location, nil
+}
+
+func (r *memoryRepo) GetLocationByCode(code string) (*product.Location, error) {
+	defer r.lock()()
+
+	for _, l := range r.state.locations {
+		if l.Code == code {
+			location := *l
+			return &location, nil
+		}
+	}
+
+	return nil, &product.NotFoundError{Entity: "location", Key: code}
+}
+
+func (r *memoryRepo) GetLocations() ([]*product.Location, error) {
+	defer r.lock()()
+
+	locations := []*product.Location{}
+	for _, l := range r.state.locations {
+		location := *l
+		locations = append(locations, &location)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].Code < locations[j].Code
+	})
+
+	return locations, nil
+}
+
+func (r *memoryRepo) GetStockLevels(query *product.StockLevelQuery) ([]*product.StockLevel, error) {
+	defer r.lock()()
+
+	levels := []*product.StockLevel{}
+	for key, l := range r.state.levels {
+		if (query.ProductID == 0 || key.productID == query.ProductID) && (query.LocationID == 0 || key.locationID == query.LocationID) {
+			level := *l
+			levels = append(levels, &level)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		a, b := levels[i], levels[j]
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		if a.VariantID != b.VariantID {
+			return a.VariantID < b.VariantID
+		}
+		return a.LocationID < b.LocationID
+	})
+
+	return levels, nil
+}
+
+func (r *memoryRepo) TransferStock(transfer *product.StockTransfer) ([]*product.StockMovement, error) {
+	defer r.lock()()
+
+	movements := []*product.StockMovement{}
+	for _, m := range []struct {
+		locationID uint
+		quantity   float64
+	}{{transfer.FromLocationID, -transfer.Quantity}, {transfer.ToLocationID, transfer.Quantity}} {
+		locationID := m.locationID
+		movements = append(movements, &product.StockMovement{
+			ProductID:  transfer.ProductID,
+			VariantID:  transfer.VariantID,
+			LocationID: &locationID,
+			Quantity:   m.quantity,
+			Reason:     product.ReasonTransfer,
+			Reference:  transfer.Reference,
+		})
+	}
+	if err := r.checkStockLevel(movements[0]); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, movement := range movements {
+		r.updateStockLevel(movement)
+
+		r.state.lastMovementID++
+		movement.ID = r.state.lastMovementID
+		movement.CreatedAt = now
+		m := *movement
+		r.state.movements = append(r.state.movements, &m)
+	}
+
+	return movements, nil
+}
@@ -0,0 +1,47 @@
+package memory_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/marceloaguero/go-nats-products/products/pkg/repository/memory"
+	"github.com/marceloaguero/go-nats-products/products/pkg/repository/repotest"
+	"github.com/shopspring/decimal"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) product.Store {
+		return memory.NewRepo()
+	})
+}
+
+func TestConcurrentStockMovements(t *testing.T) {
+	store := memory.NewRepo()
+	p, err := store.Create(&product.Product{Name: "product", Price: decimal.NewFromInt(10)})
+	if err != nil {
+		t.Fatalf("Create: unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = store.Transaction(func(tx product.Store) error {
+				_, err := tx.AddStockMovement(&product.StockMovement{ProductID: p.ID, Quantity: 1, Reason: product.ReasonPurchase})
+				return err
+			})
+			_, _ = store.GetByID(p.ID)
+		}()
+	}
+	wg.Wait()
+
+	p, err = store.GetByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Stock != 50 {
+		t.Fatalf("expected stock 50, got %v", p.Stock)
+	}
+}
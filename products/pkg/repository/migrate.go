@@ -0,0 +1,32 @@
+package mysql_orm
+
+import (
+	"fmt"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/repository/migrations"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// NewMigrator crea un migrations.Migrator sobre la base de datos del driver indicado (mysql, postgres o sqlite),
+// sin aplicar ninguna migración
+func NewMigrator(driver, dsn, dbName string) (*migrations.Migrator, error) {
+	var db *gorm.DB
+	var err error
+
+	switch driver {
+	case "", "mysql":
+		db, err = dbConnect(dsn, dbName)
+	case "postgres":
+		db, err = postgresConnect(dsn)
+	case "sqlite":
+		db, err = sqliteConnect(dsn)
+	default:
+		return nil, fmt.Errorf("ORM - Driver %q doesn't support migrations", driver)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "ORM - Can't connect to DB")
+	}
+
+	return migrations.New(db)
+}
@@ -0,0 +1,328 @@
+// Package migrations aplica las migraciones versionadas del esquema de la base de datos.
+// Las migraciones son archivos SQL embebidos en el binario, uno por versión y por dialecto (mysql, postgres, sqlite),
+// con el nombre NNNN_descripcion.sql. Cada archivo tiene una sección "-- +migrate Up" con las sentencias que aplican
+// la migración y una sección "-- +migrate Down" con las que la revierten. Cada sentencia termina con ";" al final de una línea.
+// Las versiones aplicadas se registran en la tabla schema_migrations.
+package migrations
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+const (
+	upMarker   = "-- +migrate Up"
+	downMarker = "-- +migrate Down"
+)
+
+// Migration es una versión del esquema
+type Migration struct {
+	Version   uint       // Versión, tomada del prefijo numérico del archivo
+	Name      string     // Descripción, tomada del resto del nombre del archivo
+	Up        []string   // Sentencias que aplican la migración
+	Down      []string   // Sentencias que revierten la migración
+	AppliedAt *time.Time // Momento en que se aplicó, nil si está pendiente
+}
+
+// schemaMigration es la fila de la tabla schema_migrations que registra una versión aplicada
+type schemaMigration struct {
+	Version   uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+// Migrator aplica y revierte las migraciones de un dialecto sobre una base de datos
+type Migrator struct {
+	db         *gorm.DB
+	migrations []*Migration
+}
+
+// New crea un Migrator para las migraciones del dialecto de db
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := load(db.Dialector.Name())
+	if err != nil {
+		return nil, errors.Wrap(err, "MIGRATIONS - Can't load migrations")
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// load lee las migraciones embebidas de un dialecto, ordenadas por versión
+func load(dialect string) ([]*Migration, error) {
+	names, err := fs.Glob(files, dialect+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no migrations for dialect %s", dialect)
+	}
+
+	migrations := []*Migration{}
+	versions := map[uint]bool{}
+	for _, name := range names {
+		migration, err := parse(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid migration %s", name)
+		}
+		if versions[migration.Version] {
+			return nil, fmt.Errorf("duplicated migration version %d", migration.Version)
+		}
+		versions[migration.Version] = true
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// parse lee un archivo de migración
+func parse(name string) (*Migration, error) {
+	base := strings.TrimSuffix(path.Base(name), ".sql")
+	prefix, description, found := strings.Cut(base, "_")
+	if !found {
+		return nil, errors.New("the file name must be NNNN_description.sql")
+	}
+	version, err := strconv.ParseUint(prefix, 10, 32)
+	if err != nil || version == 0 {
+		return nil, errors.New("the file name must start with a positive version number")
+	}
+
+	data, err := files.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	migration := &Migration{
+		Version: uint(version),
+		Name:    description,
+	}
+
+	var section *[]string
+	statement := ""
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == upMarker:
+			section = &migration.Up
+			continue
+		case line == downMarker:
+			section = &migration.Down
+			continue
+		case line == "" || strings.HasPrefix(line, "--"):
+			continue
+		case section == nil:
+			return nil, fmt.Errorf("statement outside of the %q and %q sections", upMarker, downMarker)
+		}
+
+		statement += scanner.Text() + "\n"
+		if strings.HasSuffix(line, ";") {
+			*section = append(*section, strings.TrimSpace(statement))
+			statement = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(statement) != "" {
+		return nil, errors.New("the last statement must end with ;")
+	}
+	if len(migration.Up) == 0 {
+		return nil, errors.New("the migration has no up statements")
+	}
+
+	return migration, nil
+}
+
+// Status devuelve todas las migraciones, indicando cuáles están aplicadas
+func (m *Migrator) Status() ([]*Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, errors.Wrap(err, "MIGRATIONS - Status - Can't read applied migrations")
+	}
+
+	migrations := []*Migration{}
+	for _, migration := range m.migrations {
+		status := *migration
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+		}
+		migrations = append(migrations, &status)
+	}
+
+	return migrations, nil
+}
+
+// Up aplica hasta steps migraciones pendientes, en orden de versión. Con steps 0 aplica todas.
+// Con dryRun no modifica la base de datos: sólo devuelve las migraciones que aplicaría.
+func (m *Migrator) Up(steps int, dryRun bool) ([]*Migration, error) {
+	var pending []*Migration
+	err := m.withLock(dryRun, func() error {
+		migrations, err := m.Status()
+		if err != nil {
+			return errors.Wrap(err, "MIGRATIONS - Up")
+		}
+
+		for _, migration := range migrations {
+			if migration.AppliedAt == nil {
+				pending = append(pending, migration)
+			}
+		}
+		if steps > 0 && len(pending) > steps {
+			pending = pending[:steps]
+		}
+
+		if dryRun {
+			return nil
+		}
+		for _, migration := range pending {
+			err := m.run(migration.Up, func(tx *gorm.DB) error {
+				return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return errors.Wrapf(err, "MIGRATIONS - Up - Error applying migration %d_%s", migration.Version, migration.Name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// Down revierte las últimas steps migraciones aplicadas, de la más reciente a la más antigua. Con steps 0 revierte todas.
+// Con dryRun no modifica la base de datos: sólo devuelve las migraciones que revertiría.
+func (m *Migrator) Down(steps int, dryRun bool) ([]*Migration, error) {
+	var applied []*Migration
+	err := m.withLock(dryRun, func() error {
+		migrations, err := m.Status()
+		if err != nil {
+			return errors.Wrap(err, "MIGRATIONS - Down")
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if migrations[i].AppliedAt != nil {
+				applied = append(applied, migrations[i])
+			}
+		}
+		if steps > 0 && len(applied) > steps {
+			applied = applied[:steps]
+		}
+
+		if dryRun {
+			return nil
+		}
+		for _, migration := range applied {
+			err := m.run(migration.Down, func(tx *gorm.DB) error {
+				return tx.Delete(&schemaMigration{Version: migration.Version}).Error
+			})
+			if err != nil {
+				return errors.Wrapf(err, "MIGRATIONS - Down - Error reverting migration %d_%s", migration.Version, migration.Name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// lockName identifica el lock que serializa las migraciones de las instancias del servicio que comparten una base de datos
+const lockName = "schema_migrations"
+
+// withLock ejecuta fn con el lock de migraciones tomado, para que varias instancias que inician a la vez no apliquen
+// dos veces la misma migración. Las que esperan el lock leen las versiones aplicadas recién al obtenerlo.
+// En MySQL y PostgreSQL es un lock de sesión (advisory lock), que se toma en una conexión dedicada y se libera al terminar,
+// o al cerrarse la conexión si el proceso termina antes. SQLite no tiene locks de sesión: una base SQLite es de una
+// única instancia. Las ejecuciones con dryRun no modifican la base de datos y no toman el lock.
+func (m *Migrator) withLock(dryRun bool, fn func() error) error {
+	dialect := m.db.Dialector.Name()
+	if dryRun || (dialect != "mysql" && dialect != "postgres") {
+		return fn()
+	}
+
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if dialect == "mysql" {
+			// GET_LOCK devuelve 1 al obtener el lock. Con un timeout negativo espera indefinidamente
+			var acquired *int
+			if err := conn.Raw("SELECT GET_LOCK(?, -1)", lockName).Scan(&acquired).Error; err != nil {
+				return errors.Wrap(err, "MIGRATIONS - Can't acquire the migrations lock")
+			}
+			if acquired == nil || *acquired != 1 {
+				return errors.New("MIGRATIONS - Can't acquire the migrations lock")
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", lockName)
+		} else {
+			if err := conn.Exec("SELECT pg_advisory_lock(hashtext(?))", lockName).Error; err != nil {
+				return errors.Wrap(err, "MIGRATIONS - Can't acquire the migrations lock")
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(hashtext(?))", lockName)
+		}
+
+		return fn()
+	})
+}
+
+// applied recupera las versiones aplicadas. Si la tabla schema_migrations no existe, no hay ninguna.
+func (m *Migrator) applied() (map[uint]*schemaMigration, error) {
+	applied := map[uint]*schemaMigration{}
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+
+	rows := []*schemaMigration{}
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+// run ejecuta las sentencias de una migración y registra el cambio de versión en una transacción.
+// En MySQL las sentencias DDL confirman la transacción implícitamente, por lo que una migración
+// que falla a mitad de camino puede quedar aplicada en forma parcial.
+func (m *Migrator) run(statements []string, record func(tx *gorm.DB) error) error {
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		if err := m.db.Migrator().CreateTable(&schemaMigration{}); err != nil {
+			return errors.Wrap(err, "Can't create the schema_migrations table")
+		}
+	}
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+
+		return record(tx)
+	})
+}
@@ -0,0 +1,176 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestLoad(t *testing.T) {
+	var versions []uint
+	for _, dialect := range []string{"mysql", "postgres", "sqlite"} {
+		migrations, err := load(dialect)
+		if err != nil {
+			t.Fatalf("load(%s): unexpected error: %v", dialect, err)
+		}
+
+		// Todos los dialectos deben tener las mismas versiones
+		dialectVersions := []uint{}
+		for _, migration := range migrations {
+			if len(migration.Down) == 0 {
+				t.Fatalf("%s: migration %d has no down statements", dialect, migration.Version)
+			}
+			dialectVersions = append(dialectVersions, migration.Version)
+		}
+		if versions == nil {
+			versions = dialectVersions
+		}
+		if len(versions) != len(dialectVersions) {
+			t.Fatalf("%s: expected versions %v, got %v", dialect, versions, dialectVersions)
+		}
+		for i := range versions {
+			if versions[i] != dialectVersions[i] {
+				t.Fatalf("%s: expected versions %v, got %v", dialect, versions, dialectVersions)
+			}
+		}
+	}
+}
+
+func newMigrator(t *testing.T) (*Migrator, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("gorm.Open: unexpected error: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB: unexpected error: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+
+	return migrator, db
+}
+
+func pending(t *testing.T, m *Migrator) int {
+	t.Helper()
+
+	migrations, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status: unexpected error: %v", err)
+	}
+
+	n := 0
+	for _, migration := range migrations {
+		if migration.AppliedAt == nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestUpDown(t *testing.T) {
+	m, db := newMigrator(t)
+	total := len(m.migrations)
+
+	migrations, err := m.Up(0, true)
+	if err != nil {
+		t.Fatalf("Up dry-run: unexpected error: %v", err)
+	}
+	if len(migrations) != total || pending(t, m) != total {
+		t.Fatalf("Up dry-run: expected %d migrations to be listed and none applied", total)
+	}
+	if db.Migrator().HasTable("products") {
+		t.Fatal("Up dry-run: expected the schema not to be modified")
+	}
+
+	if _, err := m.Up(0, false); err != nil {
+		t.Fatalf("Up: unexpected error: %v", err)
+	}
+	if pending(t, m) != 0 || !db.Migrator().HasTable("products") {
+		t.Fatal("Up: expected all migrations to be applied")
+	}
+
+	migrations, err = m.Up(0, false)
+	if err != nil {
+		t.Fatalf("Up: unexpected error: %v", err)
+	}
+	if len(migrations) != 0 {
+		t.Fatalf("Up: expected no migrations to be applied twice, got %d", len(migrations))
+	}
+
+	migrations, err = m.Down(1, true)
+	if err != nil {
+		t.Fatalf("Down dry-run: unexpected error: %v", err)
+	}
+	if len(migrations) != 1 || migrations[0].Version != m.migrations[total-1].Version || pending(t, m) != 0 {
+		t.Fatal("Down dry-run: expected the last migration to be listed and none reverted")
+	}
+
+	if _, err := m.Down(0, false); err != nil {
+		t.Fatalf("Down: unexpected error: %v", err)
+	}
+	if pending(t, m) != total || db.Migrator().HasTable("products") {
+		t.Fatal("Down: expected all migrations to be reverted")
+	}
+
+	if _, err := m.Up(0, false); err != nil {
+		t.Fatalf("Up after Down: unexpected error: %v", err)
+	}
+}
+
+func TestLocationsBackfill(t *testing.T) {
+	m, db := newMigrator(t)
+
+	// Esquema previo a las ubicaciones, con un producto con variantes y otro sin ellas
+	if _, err := m.Up(10, false); err != nil {
+		t.Fatalf("Up(10): unexpected error: %v", err)
+	}
+	statements := []string{
+		"INSERT INTO products (id, name, stock) VALUES (1, 'shirt', 12), (2, 'pants', 4)",
+		"INSERT INTO variants (id, product_id, sku, stock) VALUES (1, 1, 'SHIRT-S', 3), (2, 1, 'SHIRT-M', 0), (3, 1, 'SHIRT-L', 7)",
+		"INSERT INTO reservations (id, product_id, variant_id, quantity, status) VALUES ('r1', 1, 3, 2, 'pending'), ('r2', 2, NULL, 1, 'pending'), ('r3', 2, NULL, 3, 'released')",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("Exec(%q): unexpected error: %v", statement, err)
+		}
+	}
+
+	if _, err := m.Up(0, false); err != nil {
+		t.Fatalf("Up: unexpected error: %v", err)
+	}
+
+	type level struct {
+		LocationID uint
+		ProductID  uint
+		VariantID  uint
+		Quantity   float64
+		Reserved   float64
+	}
+	levels := []level{}
+	if err := db.Table("stock_levels").Order("product_id, variant_id").Find(&levels).Error; err != nil {
+		t.Fatalf("stock_levels: unexpected error: %v", err)
+	}
+
+	// El stock que no corresponde a variantes queda en el nivel sin variante del producto,
+	// y las reservas pendientes reservan stock en la ubicación por defecto
+	expected := []level{{1, 1, 0, 2, 0}, {1, 1, 1, 3, 0}, {1, 1, 3, 7, 2}, {1, 2, 0, 4, 1}}
+	if len(levels) != len(expected) {
+		t.Fatalf("expected levels %v, got %v", expected, levels)
+	}
+	for i := range expected {
+		if levels[i] != expected[i] {
+			t.Fatalf("expected levels %v, got %v", expected, levels)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+-- Esquema inicial: la tabla de productos tal como la creaba AutoMigrate antes de las migraciones versionadas,
+-- de modo que las bases existentes quedan registradas en esta versión sin modificaciones (IF NOT EXISTS).
+-- Las columnas y tablas posteriores se agregan en las migraciones siguientes.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS products (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	name varchar(60) DEFAULT NULL,
+	description varchar(250) DEFAULT NULL,
+	unit longtext,
+	price double DEFAULT NULL,
+	stock double DEFAULT NULL,
+	is_active tinyint(1) DEFAULT NULL,
+	PRIMARY KEY (id)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS products;
@@ -0,0 +1,51 @@
+-- Stock comprometido en reservas y versión de los productos (control de concurrencia optimista),
+-- movimientos de stock, reservas y eventos pendientes de publicar (outbox).
+-- Los productos existentes quedan sin stock reservado y en la versión 1.
+
+-- +migrate Up
+ALTER TABLE products ADD COLUMN reserved double NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN version bigint unsigned NOT NULL DEFAULT 1;
+
+CREATE TABLE stock_movements (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	product_id bigint unsigned DEFAULT NULL,
+	quantity double DEFAULT NULL,
+	reason varchar(16) DEFAULT NULL,
+	reference varchar(100) DEFAULT NULL,
+	created_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (id),
+	KEY idx_stock_movements_product_id (product_id)
+);
+
+CREATE TABLE reservations (
+	id varchar(64) NOT NULL,
+	product_id bigint unsigned DEFAULT NULL,
+	quantity double DEFAULT NULL,
+	status varchar(16) DEFAULT NULL,
+	expires_at datetime(3) DEFAULT NULL,
+	created_at datetime(3) DEFAULT NULL,
+	updated_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (id),
+	KEY idx_reservations_product_id (product_id),
+	KEY idx_reservations_status (status),
+	KEY idx_reservations_expires_at (expires_at)
+);
+
+CREATE TABLE outbox_events (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	event_id varchar(32) DEFAULT NULL,
+	subject varchar(64) DEFAULT NULL,
+	payload longblob,
+	created_at datetime(3) DEFAULT NULL,
+	sent_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_outbox_events_event_id (event_id),
+	KEY idx_outbox_events_sent_at (sent_at)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS stock_movements;
+ALTER TABLE products DROP COLUMN version;
+ALTER TABLE products DROP COLUMN reserved;
@@ -0,0 +1,12 @@
+-- Nombre normalizado del producto (ver product.NormalizeName), con un índice único que impide
+-- crear en simultáneo dos productos con el mismo nombre. Si ya existen productos cuyos nombres sólo
+-- difieren en mayúsculas o espacios, la creación del índice falla y deben renombrarse antes de migrar.
+
+-- +migrate Up
+ALTER TABLE products ADD COLUMN name_key varchar(60) DEFAULT NULL;
+UPDATE products SET name_key = LOWER(TRIM(REGEXP_REPLACE(name, '[[:space:]]+', ' ')));
+CREATE UNIQUE INDEX idx_products_name_key ON products (name_key);
+
+-- +migrate Down
+DROP INDEX idx_products_name_key ON products;
+ALTER TABLE products DROP COLUMN name_key;
@@ -0,0 +1,16 @@
+-- Historial de auditoría de los cambios en los productos.
+
+-- +migrate Up
+CREATE TABLE audit_entries (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	product_id bigint unsigned DEFAULT NULL,
+	actor varchar(100) DEFAULT NULL,
+	operation varchar(32) DEFAULT NULL,
+	changes longtext,
+	created_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (id),
+	KEY idx_audit_entries_product_id (product_id)
+);
+
+-- +migrate Down
+DROP TABLE audit_entries;
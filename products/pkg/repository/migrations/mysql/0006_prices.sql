@@ -0,0 +1,22 @@
+-- Historial de precios y cambios de precio programados. El precio actual de cada
+-- producto existente se registra como vigente desde el momento de la migración.
+
+-- +migrate Up
+CREATE TABLE prices (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	product_id bigint unsigned DEFAULT NULL,
+	price double DEFAULT NULL,
+	status varchar(16) DEFAULT NULL,
+	effective_from datetime(3) DEFAULT NULL,
+	effective_to datetime(3) DEFAULT NULL,
+	created_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (id),
+	KEY idx_prices_product_id (product_id),
+	KEY idx_prices_status (status),
+	KEY idx_prices_effective_from (effective_from)
+);
+INSERT INTO prices (product_id, price, status, effective_from, created_at)
+SELECT id, price, 'applied', CURRENT_TIMESTAMP(3), CURRENT_TIMESTAMP(3) FROM products;
+
+-- +migrate Down
+DROP TABLE prices;
@@ -0,0 +1,11 @@
+-- Precios exactos: las columnas de precio pasan de double a decimal, con la escala de
+-- product.PriceScale, y se agrega la moneda (ISO 4217). Los precios existentes quedan en
+-- product.DefaultCurrency.
+
+-- +migrate Up
+ALTER TABLE products MODIFY price decimal(19,4) DEFAULT NULL, ADD COLUMN currency varchar(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE prices MODIFY price decimal(19,4) DEFAULT NULL, ADD COLUMN currency varchar(3) NOT NULL DEFAULT 'USD';
+
+-- +migrate Down
+ALTER TABLE prices MODIFY price double DEFAULT NULL, DROP COLUMN currency;
+ALTER TABLE products MODIFY price double DEFAULT NULL, DROP COLUMN currency;
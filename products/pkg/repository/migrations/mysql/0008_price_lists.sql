@@ -0,0 +1,23 @@
+-- Listas de precios por mercado, con precios propios por producto.
+
+-- +migrate Up
+CREATE TABLE price_lists (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	name varchar(60) DEFAULT NULL,
+	currency varchar(3) DEFAULT NULL,
+	description varchar(250) DEFAULT NULL,
+	created_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_price_lists_name (name)
+);
+CREATE TABLE list_prices (
+	price_list_id bigint unsigned NOT NULL,
+	product_id bigint unsigned NOT NULL,
+	price decimal(19,4) DEFAULT NULL,
+	updated_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (price_list_id, product_id)
+);
+
+-- +migrate Down
+DROP TABLE list_prices;
+DROP TABLE price_lists;
@@ -0,0 +1,27 @@
+-- Árbol de categorías de productos (camino materializado) y asignación de productos a categorías.
+
+-- +migrate Up
+CREATE TABLE categories (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	name varchar(60) DEFAULT NULL,
+	name_key varchar(80) DEFAULT NULL,
+	description varchar(250) DEFAULT NULL,
+	parent_id bigint unsigned DEFAULT NULL,
+	path varchar(255) DEFAULT NULL,
+	created_at datetime(3) DEFAULT NULL,
+	updated_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_categories_name_key (name_key),
+	KEY idx_categories_parent_id (parent_id),
+	KEY idx_categories_path (path)
+);
+CREATE TABLE product_categories (
+	product_id bigint unsigned NOT NULL,
+	category_id bigint unsigned NOT NULL,
+	PRIMARY KEY (product_id, category_id),
+	KEY idx_product_categories_category_id (category_id)
+);
+
+-- +migrate Down
+DROP TABLE product_categories;
+DROP TABLE categories;
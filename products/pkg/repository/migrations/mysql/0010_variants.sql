@@ -0,0 +1,26 @@
+-- Variantes de los productos (SKU, opciones, precio y stock propios), y variante afectada por los movimientos de stock y las reservas.
+
+-- +migrate Up
+CREATE TABLE variants (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	product_id bigint unsigned DEFAULT NULL,
+	sku varchar(64) DEFAULT NULL,
+	options longtext,
+	price decimal(19,4) DEFAULT NULL,
+	stock double DEFAULT NULL,
+	reserved double NOT NULL DEFAULT 0,
+	created_at datetime(3) DEFAULT NULL,
+	updated_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_variants_sku (sku),
+	KEY idx_variants_product_id (product_id)
+);
+ALTER TABLE stock_movements ADD COLUMN variant_id bigint unsigned DEFAULT NULL;
+CREATE INDEX idx_stock_movements_variant_id ON stock_movements (variant_id);
+ALTER TABLE reservations ADD COLUMN variant_id bigint unsigned DEFAULT NULL;
+
+-- +migrate Down
+ALTER TABLE reservations DROP COLUMN variant_id;
+DROP INDEX idx_stock_movements_variant_id ON stock_movements;
+ALTER TABLE stock_movements DROP COLUMN variant_id;
+DROP TABLE variants;
@@ -0,0 +1,41 @@
+-- Ubicaciones de stock (depósitos, tiendas, etc.), stock de cada producto y variante por ubicación, y ubicación
+-- afectada por los movimientos de stock y las reservas. Se crea la ubicación por defecto, y el stock existente
+-- de cada producto y variante se registra en ella.
+
+-- +migrate Up
+CREATE TABLE locations (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	code varchar(32) DEFAULT NULL,
+	name varchar(60) DEFAULT NULL,
+	address varchar(250) DEFAULT NULL,
+	created_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY idx_locations_code (code)
+);
+CREATE TABLE stock_levels (
+	location_id bigint unsigned NOT NULL,
+	product_id bigint unsigned NOT NULL,
+	variant_id bigint unsigned NOT NULL,
+	quantity double DEFAULT NULL,
+	updated_at datetime(3) DEFAULT NULL,
+	PRIMARY KEY (location_id, product_id, variant_id),
+	KEY idx_stock_levels_product_id (product_id)
+);
+ALTER TABLE stock_movements ADD COLUMN location_id bigint unsigned DEFAULT NULL;
+CREATE INDEX idx_stock_movements_location_id ON stock_movements (location_id);
+ALTER TABLE reservations ADD COLUMN location_id bigint unsigned DEFAULT NULL;
+INSERT INTO locations (code, name, created_at) VALUES ('default', 'Default', CURRENT_TIMESTAMP(3));
+INSERT INTO stock_levels (location_id, product_id, variant_id, quantity, updated_at)
+SELECT locations.id, variants.product_id, variants.id, variants.stock, CURRENT_TIMESTAMP(3)
+FROM variants, locations WHERE locations.code = 'default' AND variants.stock <> 0;
+INSERT INTO stock_levels (location_id, product_id, variant_id, quantity, updated_at)
+SELECT locations.id, products.id, 0, products.stock - COALESCE((SELECT SUM(variants.stock) FROM variants WHERE variants.product_id = products.id), 0), CURRENT_TIMESTAMP(3)
+FROM products, locations WHERE locations.code = 'default'
+AND products.stock - COALESCE((SELECT SUM(variants.stock) FROM variants WHERE variants.product_id = products.id), 0) <> 0;
+
+-- +migrate Down
+ALTER TABLE reservations DROP COLUMN location_id;
+DROP INDEX idx_stock_movements_location_id ON stock_movements;
+ALTER TABLE stock_movements DROP COLUMN location_id;
+DROP TABLE stock_levels;
+DROP TABLE locations;
@@ -0,0 +1,9 @@
+-- Umbral y cantidad de reposición de los productos, para detectar y alertar el stock bajo.
+
+-- +migrate Up
+ALTER TABLE products ADD COLUMN reorder_threshold double NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN reorder_quantity double NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE products DROP COLUMN reorder_quantity;
+ALTER TABLE products DROP COLUMN reorder_threshold;
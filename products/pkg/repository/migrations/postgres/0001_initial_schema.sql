@@ -0,0 +1,17 @@
+-- Esquema inicial: la tabla de productos tal como la creaba AutoMigrate antes de las migraciones versionadas,
+-- de modo que las bases existentes quedan registradas en esta versión sin modificaciones (IF NOT EXISTS).
+-- Las columnas y tablas posteriores se agregan en las migraciones siguientes.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS products (
+	id bigserial PRIMARY KEY,
+	name varchar(60),
+	description varchar(250),
+	unit text,
+	price decimal,
+	stock decimal,
+	is_active boolean
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS products;
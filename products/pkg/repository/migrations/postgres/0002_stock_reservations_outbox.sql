@@ -0,0 +1,54 @@
+-- Stock comprometido en reservas y versión de los productos (control de concurrencia optimista),
+-- movimientos de stock, reservas y eventos pendientes de publicar (outbox).
+-- Los productos existentes quedan sin stock reservado y en la versión 1. Si ya existen productos cuyos nombres
+-- sólo difieren en mayúsculas, la creación del índice sobre lower(name) falla y deben renombrarse antes de migrar.
+
+-- +migrate Up
+ALTER TABLE products ADD COLUMN reserved decimal NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN version bigint NOT NULL DEFAULT 1;
+
+-- Las comparaciones de PostgreSQL distinguen mayúsculas de minúsculas.
+-- El índice sobre lower(name) impide nombres que sólo difieren en ellas
+CREATE UNIQUE INDEX idx_products_name_lower ON products (lower(name));
+
+CREATE TABLE stock_movements (
+	id bigserial PRIMARY KEY,
+	product_id bigint,
+	quantity decimal,
+	reason varchar(16),
+	reference varchar(100),
+	created_at timestamptz
+);
+CREATE INDEX idx_stock_movements_product_id ON stock_movements (product_id);
+
+CREATE TABLE reservations (
+	id varchar(64) PRIMARY KEY,
+	product_id bigint,
+	quantity decimal,
+	status varchar(16),
+	expires_at timestamptz,
+	created_at timestamptz,
+	updated_at timestamptz
+);
+CREATE INDEX idx_reservations_product_id ON reservations (product_id);
+CREATE INDEX idx_reservations_status ON reservations (status);
+CREATE INDEX idx_reservations_expires_at ON reservations (expires_at);
+
+CREATE TABLE outbox_events (
+	id bigserial PRIMARY KEY,
+	event_id varchar(32),
+	subject varchar(64),
+	payload bytea,
+	created_at timestamptz,
+	sent_at timestamptz
+);
+CREATE UNIQUE INDEX idx_outbox_events_event_id ON outbox_events (event_id);
+CREATE INDEX idx_outbox_events_sent_at ON outbox_events (sent_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS stock_movements;
+DROP INDEX IF EXISTS idx_products_name_lower;
+ALTER TABLE products DROP COLUMN version;
+ALTER TABLE products DROP COLUMN reserved;
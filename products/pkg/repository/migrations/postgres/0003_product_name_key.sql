@@ -0,0 +1,15 @@
+-- Nombre normalizado del producto (ver product.NormalizeName), con un índice único que impide
+-- crear en simultáneo dos productos con el mismo nombre. Reemplaza al índice sobre lower(name).
+-- Si ya existen productos cuyos nombres sólo difieren en espacios, la creación del índice falla
+-- y deben renombrarse antes de migrar.
+
+-- +migrate Up
+ALTER TABLE products ADD COLUMN name_key varchar(60);
+UPDATE products SET name_key = lower(trim(regexp_replace(name, '\s+', ' ', 'g')));
+DROP INDEX IF EXISTS idx_products_name_lower;
+CREATE UNIQUE INDEX idx_products_name_key ON products (name_key);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_products_name_key;
+ALTER TABLE products DROP COLUMN name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_name_lower ON products (lower(name));
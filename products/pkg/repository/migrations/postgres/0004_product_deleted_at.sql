@@ -0,0 +1,10 @@
+-- Baja lógica de productos: los productos eliminados conservan la fila con el momento de la baja.
+
+-- +migrate Up
+ALTER TABLE products ADD COLUMN deleted_at timestamptz;
+CREATE INDEX idx_products_deleted_at ON products (deleted_at);
+
+-- +migrate Down
+DELETE FROM products WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_products_deleted_at;
+ALTER TABLE products DROP COLUMN deleted_at;
@@ -0,0 +1,15 @@
+-- Historial de auditoría de los cambios en los productos.
+
+-- +migrate Up
+CREATE TABLE audit_entries (
+	id bigserial PRIMARY KEY,
+	product_id bigint,
+	actor varchar(100),
+	operation varchar(32),
+	changes text,
+	created_at timestamptz
+);
+CREATE INDEX idx_audit_entries_product_id ON audit_entries (product_id);
+
+-- +migrate Down
+DROP TABLE audit_entries;
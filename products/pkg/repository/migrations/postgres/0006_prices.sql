@@ -0,0 +1,21 @@
+-- Historial de precios y cambios de precio programados. El precio actual de cada
+-- producto existente se registra como vigente desde el momento de la migración.
+
+-- +migrate Up
+CREATE TABLE prices (
+	id bigserial PRIMARY KEY,
+	product_id bigint,
+	price decimal,
+	status varchar(16),
+	effective_from timestamptz,
+	effective_to timestamptz,
+	created_at timestamptz
+);
+CREATE INDEX idx_prices_product_id ON prices (product_id);
+CREATE INDEX idx_prices_status ON prices (status);
+CREATE INDEX idx_prices_effective_from ON prices (effective_from);
+INSERT INTO prices (product_id, price, status, effective_from, created_at)
+SELECT id, price, 'applied', now(), now() FROM products;
+
+-- +migrate Down
+DROP TABLE prices;
@@ -0,0 +1,10 @@
+-- Precios exactos: las columnas de precio se limitan a la escala de product.PriceScale,
+-- y se agrega la moneda (ISO 4217). Los precios existentes quedan en product.DefaultCurrency.
+
+-- +migrate Up
+ALTER TABLE products ALTER COLUMN price TYPE decimal(19,4), ADD COLUMN currency varchar(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE prices ALTER COLUMN price TYPE decimal(19,4), ADD COLUMN currency varchar(3) NOT NULL DEFAULT 'USD';
+
+-- +migrate Down
+ALTER TABLE prices ALTER COLUMN price TYPE decimal, DROP COLUMN currency;
+ALTER TABLE products ALTER COLUMN price TYPE decimal, DROP COLUMN currency;
@@ -0,0 +1,22 @@
+-- Listas de precios por mercado, con precios propios por producto.
+
+-- +migrate Up
+CREATE TABLE price_lists (
+	id bigserial PRIMARY KEY,
+	name varchar(60),
+	currency varchar(3),
+	description varchar(250),
+	created_at timestamptz
+);
+CREATE UNIQUE INDEX idx_price_lists_name ON price_lists (name);
+CREATE TABLE list_prices (
+	price_list_id bigint NOT NULL,
+	product_id bigint NOT NULL,
+	price decimal(19,4),
+	updated_at timestamptz,
+	PRIMARY KEY (price_list_id, product_id)
+);
+
+-- +migrate Down
+DROP TABLE list_prices;
+DROP TABLE price_lists;
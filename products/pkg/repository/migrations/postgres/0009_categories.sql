@@ -0,0 +1,26 @@
+-- Árbol de categorías de productos (camino materializado) y asignación de productos a categorías.
+
+-- +migrate Up
+CREATE TABLE categories (
+	id bigserial PRIMARY KEY,
+	name varchar(60),
+	name_key varchar(80),
+	description varchar(250),
+	parent_id bigint,
+	path varchar(255),
+	created_at timestamptz,
+	updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_categories_name_key ON categories (name_key);
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+CREATE INDEX idx_categories_path ON categories (path varchar_pattern_ops);
+CREATE TABLE product_categories (
+	product_id bigint NOT NULL,
+	category_id bigint NOT NULL,
+	PRIMARY KEY (product_id, category_id)
+);
+CREATE INDEX idx_product_categories_category_id ON product_categories (category_id);
+
+-- +migrate Down
+DROP TABLE product_categories;
+DROP TABLE categories;
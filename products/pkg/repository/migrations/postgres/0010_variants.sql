@@ -0,0 +1,25 @@
+-- Variantes de los productos (SKU, opciones, precio y stock propios), y variante afectada por los movimientos de stock y las reservas.
+
+-- +migrate Up
+CREATE TABLE variants (
+	id bigserial PRIMARY KEY,
+	product_id bigint,
+	sku varchar(64),
+	options text,
+	price decimal(19,4),
+	stock double precision,
+	reserved double precision NOT NULL DEFAULT 0,
+	created_at timestamptz,
+	updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_variants_sku ON variants (sku);
+CREATE INDEX idx_variants_product_id ON variants (product_id);
+ALTER TABLE stock_movements ADD COLUMN variant_id bigint;
+CREATE INDEX idx_stock_movements_variant_id ON stock_movements (variant_id);
+ALTER TABLE reservations ADD COLUMN variant_id bigint;
+
+-- +migrate Down
+ALTER TABLE reservations DROP COLUMN variant_id;
+DROP INDEX idx_stock_movements_variant_id;
+ALTER TABLE stock_movements DROP COLUMN variant_id;
+DROP TABLE variants;
@@ -0,0 +1,40 @@
+-- Ubicaciones de stock (depósitos, tiendas, etc.), stock de cada producto y variante por ubicación, y ubicación
+-- afectada por los movimientos de stock y las reservas. Se crea la ubicación por defecto, y el stock existente
+-- de cada producto y variante se registra en ella.
+
+-- +migrate Up
+CREATE TABLE locations (
+	id bigserial PRIMARY KEY,
+	code varchar(32),
+	name varchar(60),
+	address varchar(250),
+	created_at timestamptz
+);
+CREATE UNIQUE INDEX idx_locations_code ON locations (code);
+CREATE TABLE stock_levels (
+	location_id bigint NOT NULL,
+	product_id bigint NOT NULL,
+	variant_id bigint NOT NULL,
+	quantity double precision,
+	updated_at timestamptz,
+	PRIMARY KEY (location_id, product_id, variant_id)
+);
+CREATE INDEX idx_stock_levels_product_id ON stock_levels (product_id);
+ALTER TABLE stock_movements ADD COLUMN location_id bigint;
+CREATE INDEX idx_stock_movements_location_id ON stock_movements (location_id);
+ALTER TABLE reservations ADD COLUMN location_id bigint;
+INSERT INTO locations (code, name, created_at) VALUES ('default', 'Default', now());
+INSERT INTO stock_levels (location_id, product_id, variant_id, quantity, updated_at)
+SELECT locations.id, variants.product_id, variants.id, variants.stock, now()
+FROM variants, locations WHERE locations.code = 'default' AND variants.stock <> 0;
+INSERT INTO stock_levels (location_id, product_id, variant_id, quantity, updated_at)
+SELECT locations.id, products.id, 0, products.stock - COALESCE((SELECT SUM(variants.stock) FROM variants WHERE variants.product_id = products.id), 0), now()
+FROM products, locations WHERE locations.code = 'default'
+AND products.stock - COALESCE((SELECT SUM(variants.stock) FROM variants WHERE variants.product_id = products.id), 0) <> 0;
+
+-- +migrate Down
+ALTER TABLE reservations DROP COLUMN location_id;
+DROP INDEX idx_stock_movements_location_id;
+ALTER TABLE stock_movements DROP COLUMN location_id;
+DROP TABLE stock_levels;
+DROP TABLE locations;
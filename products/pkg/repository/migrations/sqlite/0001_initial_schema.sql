@@ -0,0 +1,17 @@
+-- Esquema inicial: la tabla de productos tal como la creaba AutoMigrate antes de las migraciones versionadas,
+-- de modo que las bases existentes quedan registradas en esta versión sin modificaciones (IF NOT EXISTS).
+-- Las columnas y tablas posteriores se agregan en las migraciones siguientes.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS products (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	name text,
+	description text,
+	unit text,
+	price real,
+	stock real,
+	is_active numeric
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS products;
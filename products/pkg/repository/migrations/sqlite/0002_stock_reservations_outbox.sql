@@ -0,0 +1,48 @@
+-- Stock comprometido en reservas y versión de los productos (control de concurrencia optimista),
+-- movimientos de stock, reservas y eventos pendientes de publicar (outbox).
+-- Los productos existentes quedan sin stock reservado y en la versión 1.
+
+-- +migrate Up
+ALTER TABLE products ADD COLUMN reserved real NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN version integer NOT NULL DEFAULT 1;
+
+CREATE TABLE stock_movements (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	product_id integer,
+	quantity real,
+	reason text,
+	reference text,
+	created_at datetime
+);
+CREATE INDEX idx_stock_movements_product_id ON stock_movements (product_id);
+
+CREATE TABLE reservations (
+	id text PRIMARY KEY,
+	product_id integer,
+	quantity real,
+	status text,
+	expires_at datetime,
+	created_at datetime,
+	updated_at datetime
+);
+CREATE INDEX idx_reservations_product_id ON reservations (product_id);
+CREATE INDEX idx_reservations_status ON reservations (status);
+CREATE INDEX idx_reservations_expires_at ON reservations (expires_at);
+
+CREATE TABLE outbox_events (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	event_id text,
+	subject text,
+	payload blob,
+	created_at datetime,
+	sent_at datetime
+);
+CREATE UNIQUE INDEX idx_outbox_events_event_id ON outbox_events (event_id);
+CREATE INDEX idx_outbox_events_sent_at ON outbox_events (sent_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS stock_movements;
+ALTER TABLE products DROP COLUMN version;
+ALTER TABLE products DROP COLUMN reserved;
@@ -0,0 +1,14 @@
+-- Nombre normalizado del producto (ver product.NormalizeName), con un índice único que impide
+-- crear en simultáneo dos productos con el mismo nombre. SQLite no tiene expresiones regulares:
+-- los tabs y los espacios repetidos se reemplazan por uno solo en varias pasadas.
+-- Si ya existen productos cuyos nombres sólo difieren en mayúsculas o espacios, la creación
+-- del índice falla y deben renombrarse antes de migrar.
+
+-- +migrate Up
+ALTER TABLE products ADD COLUMN name_key text;
+UPDATE products SET name_key = lower(trim(replace(replace(replace(replace(replace(name, char(9), ' '), '    ', ' '), '  ', ' '), '  ', ' '), '  ', ' ')));
+CREATE UNIQUE INDEX idx_products_name_key ON products (name_key);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_products_name_key;
+ALTER TABLE products DROP COLUMN name_key;
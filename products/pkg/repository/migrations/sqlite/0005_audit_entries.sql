@@ -0,0 +1,15 @@
+-- Historial de auditoría de los cambios en los productos.
+
+-- +migrate Up
+CREATE TABLE audit_entries (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	product_id integer,
+	actor text,
+	operation text,
+	changes text,
+	created_at datetime
+);
+CREATE INDEX idx_audit_entries_product_id ON audit_entries (product_id);
+
+-- +migrate Down
+DROP TABLE audit_entries;
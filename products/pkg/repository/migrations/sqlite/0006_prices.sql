@@ -0,0 +1,21 @@
+-- Historial de precios y cambios de precio programados. El precio actual de cada
+-- producto existente se registra como vigente desde el momento de la migración.
+
+-- +migrate Up
+CREATE TABLE prices (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	product_id integer,
+	price real,
+	status text,
+	effective_from datetime,
+	effective_to datetime,
+	created_at datetime
+);
+CREATE INDEX idx_prices_product_id ON prices (product_id);
+CREATE INDEX idx_prices_status ON prices (status);
+CREATE INDEX idx_prices_effective_from ON prices (effective_from);
+INSERT INTO prices (product_id, price, status, effective_from, created_at)
+SELECT id, price, 'applied', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP FROM products;
+
+-- +migrate Down
+DROP TABLE prices;
@@ -0,0 +1,11 @@
+-- Moneda de los precios (ISO 4217). Los precios existentes quedan en product.DefaultCurrency.
+-- SQLite no tiene un tipo decimal: los precios se siguen almacenando como real, que conserva
+-- exactamente hasta 15 dígitos significativos. Alcanza para el uso local al que está destinado.
+
+-- +migrate Up
+ALTER TABLE products ADD COLUMN currency text NOT NULL DEFAULT 'USD';
+ALTER TABLE prices ADD COLUMN currency text NOT NULL DEFAULT 'USD';
+
+-- +migrate Down
+ALTER TABLE prices DROP COLUMN currency;
+ALTER TABLE products DROP COLUMN currency;
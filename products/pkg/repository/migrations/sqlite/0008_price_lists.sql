@@ -0,0 +1,22 @@
+-- Listas de precios por mercado, con precios propios por producto.
+
+-- +migrate Up
+CREATE TABLE price_lists (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	name text,
+	currency text,
+	description text,
+	created_at datetime
+);
+CREATE UNIQUE INDEX idx_price_lists_name ON price_lists (name);
+CREATE TABLE list_prices (
+	price_list_id integer NOT NULL,
+	product_id integer NOT NULL,
+	price real,
+	updated_at datetime,
+	PRIMARY KEY (price_list_id, product_id)
+);
+
+-- +migrate Down
+DROP TABLE list_prices;
+DROP TABLE price_lists;
@@ -0,0 +1,26 @@
+-- Árbol de categorías de productos (camino materializado) y asignación de productos a categorías.
+
+-- +migrate Up
+CREATE TABLE categories (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	name text,
+	name_key text,
+	description text,
+	parent_id integer,
+	path text,
+	created_at datetime,
+	updated_at datetime
+);
+CREATE UNIQUE INDEX idx_categories_name_key ON categories (name_key);
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+CREATE INDEX idx_categories_path ON categories (path);
+CREATE TABLE product_categories (
+	product_id integer NOT NULL,
+	category_id integer NOT NULL,
+	PRIMARY KEY (product_id, category_id)
+);
+CREATE INDEX idx_product_categories_category_id ON product_categories (category_id);
+
+-- +migrate Down
+DROP TABLE product_categories;
+DROP TABLE categories;
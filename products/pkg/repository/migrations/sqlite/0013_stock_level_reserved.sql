@@ -0,0 +1,15 @@
+-- Stock reservado de cada producto y variante por ubicación. Las reservas pendientes que no indican ubicación se
+-- asignan a la ubicación por defecto, y el stock que reservan se registra en su nivel de stock.
+
+-- +migrate Up
+ALTER TABLE stock_levels ADD COLUMN reserved real NOT NULL DEFAULT 0;
+UPDATE reservations SET location_id = (SELECT id FROM locations WHERE code = 'default')
+WHERE status = 'pending' AND location_id IS NULL;
+UPDATE stock_levels SET reserved = (
+	SELECT COALESCE(SUM(reservations.quantity), 0) FROM reservations
+	WHERE reservations.status = 'pending' AND reservations.location_id = stock_levels.location_id
+	AND reservations.product_id = stock_levels.product_id AND COALESCE(reservations.variant_id, 0) = stock_levels.variant_id
+);
+
+-- +migrate Down
+ALTER TABLE stock_levels DROP COLUMN reserved;
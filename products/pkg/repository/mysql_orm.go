@@ -2,8 +2,11 @@ package mysql_orm
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/marceloaguero/go-nats-products/products/pkg/repository/migrations"
 	"github.com/pkg/errors"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -15,24 +18,61 @@ type ormRepo struct {
 }
 
 // NewRepo crea un repositorio implementado en ORM (MySQL)
-func NewRepo(dsName, dbName string) (product.Repository, error) {
+func NewRepo(dsName, dbName string) (product.Store, error) {
 	db, err := dbConnect(dsName, dbName)
 	if err != nil {
 		return nil, errors.Wrap(err, "MySQL ORM - Can't connect to DB")
 	}
 
-	db.AutoMigrate(&product.Product{})
+	repo, err := newRepo(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "MySQL ORM - Can't migrate DB")
+	}
+
+	return repo, nil
+}
+
+// newRepo crea el repositorio sobre una conexión ya abierta, con cualquiera de los dialectos soportados.
+// Antes aplica las migraciones pendientes del esquema.
+func newRepo(db *gorm.DB) (*ormRepo, error) {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrator.Up(0, false); err != nil {
+		return nil, err
+	}
 
 	return &ormRepo{
 		db: db,
 	}, nil
 }
 
+// translateError traduce los errores de GORM a los errores de dominio del paquete product
+func translateError(err error, entity string, key interface{}) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &product.NotFoundError{Entity: entity, Key: key}
+	}
+
+	return err
+}
+
+// translateDuplicate traduce la violación de un índice único al error de dominio correspondiente.
+// Requiere que la conexión se abra con TranslateError, para que GORM devuelva gorm.ErrDuplicatedKey.
+func translateDuplicate(err error, entity, field string, value interface{}) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return &product.AlreadyExistsError{Entity: entity, Field: field, Value: value}
+	}
+
+	return err
+}
+
 func dbConnect(dsName, dbName string) (*gorm.DB, error) {
 	conn := fmt.Sprintf("%s/%s?charset=utf8&parseTime=True&loc=Local", dsName, dbName)
 
 	db, err := gorm.Open(mysql.Open(conn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Warn),
+		Logger:         logger.Default.LogMode(logger.Warn),
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, err
@@ -41,38 +81,240 @@ func dbConnect(dsName, dbName string) (*gorm.DB, error) {
 	return db, nil
 }
 
-func (r *ormRepo) Create(product *product.Product) (*product.Product, error) {
-	result := r.db.Create(&product)
-	return product, result.Error
+// Transaction ejecuta fn con un repositorio que opera dentro de una transacción de la base de datos
+func (r *ormRepo) Transaction(fn func(store product.Store) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&ormRepo{db: tx})
+	})
+}
+
+func (r *ormRepo) Create(p *product.Product) (*product.Product, error) {
+	// El índice único sobre el nombre normalizado impide duplicados aun con altas simultáneas
+	p.NameKey = product.NormalizeName(p.Name)
+	result := r.db.Create(p)
+	return p, translateDuplicate(result.Error, "product", "name", p.Name)
+}
+
+// notDeleted excluye los productos eliminados (baja lógica)
+func notDeleted(db *gorm.DB) *gorm.DB {
+	return db.Where("deleted_at IS NULL")
 }
 
 func (r *ormRepo) GetByID(id uint) (*product.Product, error) {
 	var product product.Product
-	result := r.db.Take(&product, id)
-	return &product, result.Error
+	result := r.db.Scopes(notDeleted).Take(&product, id)
+	return &product, translateError(result.Error, "product", id)
 }
 
-func (r *ormRepo) GetByName(name string) (*product.Product, error) {
+func (r *ormRepo) GetByIDIncludeDeleted(id uint) (*product.Product, error) {
 	var product product.Product
-	result := r.db.Take(&product, "name = ?", name)
-	return &product, result.Error
+	result := r.db.Take(&product, id)
+	return &product, translateError(result.Error, "product", id)
 }
 
-func (r *ormRepo) GetAll() ([]*product.Product, error) {
-	products := []*product.Product{}
-	result := r.db.Find(&products)
-	return products, result.Error
+func (r *ormRepo) GetByName(name string) (*product.Product, error) {
+	var p product.Product
+	result := r.db.Scopes(notDeleted).Take(&p, "name_key = ?", product.NormalizeName(name))
+	return &p, translateError(result.Error, "product", name)
 }
 
-func (r *ormRepo) Update(product *product.Product) (*product.Product, error) {
-	result := r.db.Model(&product).Updates(product)
-	if result.Error == nil {
-		r.db.Model(&product).Updates(map[string]interface{}{"is_active": product.IsActive})
+func (r *ormRepo) GetAll(query *product.Query) (*product.Page, error) {
+	page := &product.Page{
+		Products: []*product.Product{},
 	}
-	return product, result.Error
+
+	if !query.SkipTotal {
+		result := r.db.Model(&product.Product{}).Scopes(filterProducts(query)).Count(&page.Total)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+	}
+
+	result := r.db.Scopes(filterProducts(query), pageProducts(query)).Find(&page.Products)
+	return page, result.Error
 }
 
-func (r *ormRepo) Delete(product *product.Product) error {
-	result := r.db.Delete(&product)
+// updateColumns son las columnas que modifica Update, aunque tengan el valor cero. Las demás conservan su valor:
+// el stock sólo se modifica a través de AddStockMovement, el stock reservado a través de las reservas,
+// y la versión y la baja lógica las controla el repositorio
+var updateColumns = []string{
+	"name", "name_key", "description", "unit", "price", "currency", "reorder_threshold", "reorder_quantity", "is_active",
+}
+
+func (r *ormRepo) Update(p *product.Product) (*product.Product, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Se incrementa la versión, sólo si coincide con la informada, en la misma sentencia que la verifica
+		db := tx.Model(&product.Product{}).Scopes(notDeleted).Where("id = ?", p.ID)
+		if p.Version != 0 {
+			db = db.Where("version = ?", p.Version)
+		}
+		result := db.Update("version", gorm.Expr("version + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if err := tx.Scopes(notDeleted).Take(&product.Product{}, p.ID).Error; err != nil {
+				return translateError(err, "product", p.ID)
+			}
+			return &product.ConflictError{ID: p.ID, Version: p.Version}
+		}
+
+		p.NameKey = product.NormalizeName(p.Name)
+		result = tx.Model(p).Select(updateColumns).Updates(p)
+		if result.Error != nil {
+			return translateDuplicate(result.Error, "product", "name", p.Name)
+		}
+
+		return tx.Take(p, p.ID).Error
+	})
+	return p, err
+}
+
+func (r *ormRepo) Delete(p *product.Product) error {
+	// Se libera el nombre normalizado, para que pueda usarlo otro producto
+	result := r.db.Model(&product.Product{}).Scopes(notDeleted).Where("id = ?", p.ID).
+		Updates(map[string]interface{}{
+			"deleted_at": time.Now(),
+			"name_key":   nil,
+			"version":    gorm.Expr("version + 1"),
+		})
 	return result.Error
 }
+
+func (r *ormRepo) Restore(p *product.Product) (*product.Product, error) {
+	restored := &product.Product{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("deleted_at IS NOT NULL").Take(restored, p.ID).Error; err != nil {
+			return translateError(err, "product", p.ID)
+		}
+
+		result := tx.Model(&product.Product{}).Where("id = ? AND deleted_at IS NOT NULL", p.ID).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"name_key":   product.NormalizeName(restored.Name),
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return translateDuplicate(result.Error, "product", "name", restored.Name)
+		}
+		if result.RowsAffected == 0 {
+			return &product.NotFoundError{Entity: "product", Key: p.ID}
+		}
+
+		return tx.Take(restored, p.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func (r *ormRepo) Purge(p *product.Product) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Se liberan los SKU de las variantes, para que puedan usarlos otros productos
+		if err := tx.Where("product_id = ?", p.ID).Delete(&product.Variant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", p.ID).Delete(&product.StockLevel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", p.ID).Delete(&product.ProductCategory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", p.ID).Delete(&product.ListPrice{}).Error; err != nil {
+			return err
+		}
+		// Los precios programados se descartan, para que no se apliquen a un producto inexistente. El historial de precios se conserva
+		if err := tx.Where("product_id = ? AND status = ?", p.ID, product.PriceScheduled).Delete(&product.Price{}).Error; err != nil {
+			return err
+		}
+		// Las reservas pendientes se liberan, porque sin el producto ya no pueden confirmarse ni vencer
+		err := tx.Model(&product.Reservation{}).
+			Where("product_id = ? AND status = ?", p.ID, product.ReservationPending).
+			Update("status", product.ReservationReleased).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&product.Product{}, p.ID).Error
+	})
+}
+
+// sortColumns mapea los campos de orden de product.Query a columnas de la tabla.
+// El nombre se ordena en minúsculas (product.NameSortKey), porque la collation de name distingue mayúsculas
+// en SQLite y Postgres pero no en MySQL
+var sortColumns = map[string]string{
+	product.SortByID:    "id",
+	product.SortByName:  "lower(name)",
+	product.SortByPrice: "price",
+	product.SortByStock: "stock",
+}
+
+// likeEscaper escapa los comodines de LIKE. Se usa '!' como caracter de escape porque,
+// a diferencia de la barra invertida, no requiere un escape adicional dentro del literal SQL.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// filterProducts aplica los filtros de query
+func filterProducts(query *product.Query) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !query.IncludeDeleted {
+			db = db.Scopes(notDeleted)
+		}
+		if query.IsActive != nil {
+			db = db.Where("is_active = ?", *query.IsActive)
+		}
+		if query.MinPrice != nil {
+			db = db.Where("price >= ?", *query.MinPrice)
+		}
+		if query.MaxPrice != nil {
+			db = db.Where("price <= ?", *query.MaxPrice)
+		}
+		if query.MinStock != nil {
+			db = db.Where("stock >= ?", *query.MinStock)
+		}
+		if query.MaxStock != nil {
+			db = db.Where("stock <= ?", *query.MaxStock)
+		}
+		if query.LowStock {
+			db = db.Where("reorder_threshold > 0 AND stock - reserved <= reorder_threshold")
+		}
+		if query.NamePrefix != "" {
+			db = db.Where("lower(name) LIKE lower(?) ESCAPE '!'", likeEscaper.Replace(query.NamePrefix)+"%")
+		}
+		if query.CategoryPath != "" {
+			// La categoría o cualquiera de sus subcategorías, cuyos caminos comienzan con el de la categoría
+			db = db.Where("id IN (?)", db.Session(&gorm.Session{NewDB: true}).Table("product_categories").
+				Select("product_categories.product_id").
+				Joins("JOIN categories ON categories.id = product_categories.category_id").
+				Where("categories.path LIKE ?", query.CategoryPath+"%"))
+		}
+		return db
+	}
+}
+
+// pageProducts aplica el orden y la paginación (por offset o por cursor) de query
+func pageProducts(query *product.Query) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		column := sortColumns[query.SortBy]
+		direction, comparison := "ASC", ">"
+		if query.SortOrder == product.SortDesc {
+			direction, comparison = "DESC", "<"
+		}
+
+		if after := query.After; after != nil {
+			if column == "id" {
+				db = db.Where("id "+comparison+" ?", after.ID)
+			} else {
+				db = db.Where("("+column+" "+comparison+" ?) OR ("+column+" = ? AND id "+comparison+" ?)", after.Value, after.Value, after.ID)
+			}
+		} else if query.Offset > 0 {
+			db = db.Offset(query.Offset)
+		}
+
+		if column != "id" {
+			db = db.Order(column + " " + direction)
+		}
+		return db.Order("id " + direction).Limit(query.Limit)
+	}
+}
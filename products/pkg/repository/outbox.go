@@ -0,0 +1,61 @@
+package mysql_orm
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+)
+
+// outboxEvent es la fila de la tabla outbox que guarda un evento pendiente de publicación
+type outboxEvent struct {
+	ID        uint       `gorm:"primaryKey"`          // Orden en que se agregaron los eventos
+	EventID   string     `gorm:"size:32;uniqueIndex"` // ID del evento, se publica como Nats-Msg-Id
+	Subject   string     `gorm:"size:64"`             // Subject con el que se publica el evento
+	Payload   []byte     // Evento serializado en JSON
+	CreatedAt time.Time  // Momento en que se agregó el evento
+	SentAt    *time.Time `gorm:"index"` // Momento en que se publicó el evento, nil si está pendiente
+}
+
+func (r *ormRepo) AddEvent(event *product.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	result := r.db.Create(&outboxEvent{
+		EventID: event.ID,
+		Subject: event.Type,
+		Payload: payload,
+	})
+	return result.Error
+}
+
+func (r *ormRepo) GetPendingEvents(limit int) ([]*product.Event, error) {
+	rows := []*outboxEvent{}
+	result := r.db.Where("sent_at IS NULL").Order("id").Limit(limit).Find(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	events := make([]*product.Event, 0, len(rows))
+	for _, row := range rows {
+		event := &product.Event{}
+		if err := json.Unmarshal(row.Payload, event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (r *ormRepo) MarkEventSent(event *product.Event) error {
+	result := r.db.Model(&outboxEvent{}).Where("event_id = ?", event.ID).Update("sent_at", time.Now())
+	return result.Error
+}
+
+func (r *ormRepo) DeleteSentEvents(before time.Time) (int64, error) {
+	result := r.db.Where("sent_at < ?", before).Delete(&outboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,86 @@
+package mysql_orm
+
+import (
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"gorm.io/gorm"
+)
+
+func (r *ormRepo) AddPrice(price *product.Price) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if price.Status == product.PriceApplied {
+			if err := closePrice(tx, price.ProductID, price.EffectiveFrom); err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(price).Error
+	})
+}
+
+func (r *ormRepo) GetPrice(id uint) (*product.Price, error) {
+	var price product.Price
+	result := r.db.Take(&price, id)
+	return &price, translateError(result.Error, "price", id)
+}
+
+func (r *ormRepo) ApplyPrice(price *product.Price) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := closePrice(tx, price.ProductID, price.EffectiveFrom); err != nil {
+			return err
+		}
+
+		// Sólo una operación puede aplicar el precio, aunque varias lo intenten en simultáneo
+		result := tx.Model(&product.Price{}).
+			Where("id = ? AND status = ?", price.ID, product.PriceScheduled).
+			Updates(map[string]interface{}{
+				"status":         product.PriceApplied,
+				"effective_from": price.EffectiveFrom,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return product.ErrPriceNotScheduled
+		}
+
+		price.Status = product.PriceApplied
+		return nil
+	})
+}
+
+func (r *ormRepo) CancelPrice(price *product.Price) error {
+	result := r.db.Model(&product.Price{}).
+		Where("id = ? AND status = ?", price.ID, product.PriceScheduled).
+		Update("status", product.PriceCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return product.ErrPriceNotScheduled
+	}
+
+	price.Status = product.PriceCancelled
+	return nil
+}
+
+func (r *ormRepo) GetPrices(productID uint) ([]*product.Price, error) {
+	prices := []*product.Price{}
+	result := r.db.Where("product_id = ?", productID).Order("effective_from").Order("id").Find(&prices)
+	return prices, result.Error
+}
+
+func (r *ormRepo) GetDuePrices(now time.Time, limit int) ([]*product.Price, error) {
+	prices := []*product.Price{}
+	result := r.db.Where("status = ? AND effective_from <= ?", product.PriceScheduled, now).Order("effective_from").Order("id").Limit(limit).Find(&prices)
+	return prices, result.Error
+}
+
+// closePrice cierra en el momento at la vigencia del precio vigente de un producto
+func closePrice(tx *gorm.DB, productID uint, at time.Time) error {
+	result := tx.Model(&product.Price{}).
+		Where("product_id = ? AND status = ? AND effective_to IS NULL", productID, product.PriceApplied).
+		Update("effective_to", at)
+	return result.Error
+}
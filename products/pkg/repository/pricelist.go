@@ -0,0 +1,68 @@
+package mysql_orm
+
+import (
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *ormRepo) CreatePriceList(list *product.PriceList) error {
+	result := r.db.Create(list)
+	return translateDuplicate(result.Error, "price list", "name", list.Name)
+}
+
+func (r *ormRepo) GetPriceList(name string) (*product.PriceList, error) {
+	var list product.PriceList
+	result := r.db.Take(&list, "name = ?", name)
+	return &list, translateError(result.Error, "price list", name)
+}
+
+func (r *ormRepo) GetPriceLists() ([]*product.PriceList, error) {
+	lists := []*product.PriceList{}
+	result := r.db.Order("name").Find(&lists)
+	return lists, result.Error
+}
+
+func (r *ormRepo) DeletePriceList(list *product.PriceList) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("price_list_id = ?", list.ID).Delete(&product.ListPrice{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&product.PriceList{}, list.ID).Error
+	})
+}
+
+func (r *ormRepo) SetListPrices(prices []*product.ListPrice) error {
+	// Si el producto ya tiene precio en la lista, se reemplaza
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "price_list_id"}, {Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price", "updated_at"}),
+	}).Create(prices)
+	return result.Error
+}
+
+func (r *ormRepo) RemoveListPrices(listID uint, productIDs []uint) (int64, error) {
+	result := r.db.Where("price_list_id = ? AND product_id IN ?", listID, productIDs).Delete(&product.ListPrice{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *ormRepo) GetListPrice(listID, productID uint) (*product.ListPrice, error) {
+	var price product.ListPrice
+	result := r.db.Take(&price, "price_list_id = ? AND product_id = ?", listID, productID)
+	return &price, translateError(result.Error, "list price", productID)
+}
+
+func (r *ormRepo) GetListPrices(listID uint, query *product.ListPricesQuery) (*product.ListPricesPage, error) {
+	page := &product.ListPricesPage{
+		Prices: []*product.ListPrice{},
+	}
+
+	db := r.db.Model(&product.ListPrice{}).Where("price_list_id = ?", listID)
+	if err := db.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	result := r.db.Where("price_list_id = ?", listID).Order("product_id").Offset(query.Offset).Limit(query.Limit).Find(&page.Prices)
+	return page, result.Error
+}
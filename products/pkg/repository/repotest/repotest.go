@@ -0,0 +1,1433 @@
+// Package repotest contiene una batería de pruebas de conformidad para las implementaciones de product.Store.
+// Cada implementación la ejecuta desde sus propias pruebas, de modo que todas respeten la misma semántica.
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/shopspring/decimal"
+)
+
+// Run ejecuta la batería de pruebas. newStore debe devolver un Store vacío cada vez que se lo llama.
+func Run(t *testing.T, newStore func(t *testing.T) product.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store product.Store)
+	}{
+		{"Create", testCreate},
+		{"GetByID", testGetByID},
+		{"GetByName", testGetByName},
+		{"DuplicateName", testDuplicateName},
+		{"GetAllFilters", testGetAllFilters},
+		{"GetAllPaging", testGetAllPaging},
+		{"SortByName", testSortByName},
+		{"Update", testUpdate},
+		{"UpdateVersionConflict", testUpdateVersionConflict},
+		{"Delete", testDelete},
+		{"DeleteReleasesName", testDeleteReleasesName},
+		{"StockMovements", testStockMovements},
+		{"Reservations", testReservations},
+		{"ExpiredReservations", testExpiredReservations},
+		{"Outbox", testOutbox},
+		{"AuditEntries", testAuditEntries},
+		{"Prices", testPrices},
+		{"PriceLists", testPriceLists},
+		{"Categories", testCategories},
+		{"Variants", testVariants},
+		{"Locations", testLocations},
+		{"TransactionCommit", testTransactionCommit},
+		{"TransactionRollback", testTransactionRollback},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+func newProduct(name string, price float64) *product.Product {
+	return &product.Product{
+		Name:     name,
+		Unit:     "unit",
+		Price:    decimal.NewFromFloat(price),
+		Currency: product.DefaultCurrency,
+		IsActive: true,
+		Version:  1,
+	}
+}
+
+func mustCreate(t *testing.T, store product.Store, p *product.Product) *product.Product {
+	t.Helper()
+
+	created, err := store.Create(p)
+	if err != nil {
+		t.Fatalf("Create(%q): unexpected error: %v", p.Name, err)
+	}
+
+	return created
+}
+
+func mustAddStock(t *testing.T, store product.Store, id uint, quantity float64) *product.Product {
+	t.Helper()
+
+	p, err := store.AddStockMovement(&product.StockMovement{ProductID: id, Quantity: quantity, Reason: product.ReasonPurchase})
+	if err != nil {
+		t.Fatalf("AddStockMovement(%d, %v): unexpected error: %v", id, quantity, err)
+	}
+
+	return p
+}
+
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	if got := product.ErrorCode(err); got != code {
+		t.Fatalf("expected error code %q, got %q (error: %v)", code, got, err)
+	}
+}
+
+func testCreate(t *testing.T, store product.Store) {
+	first := mustCreate(t, store, newProduct("first", 10))
+	second := mustCreate(t, store, newProduct("second", 20))
+
+	if first.ID == 0 {
+		t.Fatal("expected an ID to be assigned")
+	}
+	if second.ID <= first.ID {
+		t.Fatalf("expected incremental IDs, got %d after %d", second.ID, first.ID)
+	}
+}
+
+func testGetByID(t *testing.T, store product.Store) {
+	created := mustCreate(t, store, newProduct("product", 10))
+
+	p, err := store.GetByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Name != "product" || !p.Price.Equal(decimal.NewFromInt(10)) || p.Currency != product.DefaultCurrency || p.Unit != "unit" || !p.IsActive {
+		t.Fatalf("GetByID: unexpected product %+v", p)
+	}
+
+	// El precio se almacena sin pérdida de precisión
+	exact := newProduct("exact", 0)
+	exact.Price = decimal.RequireFromString("12345678901.2345")
+	exact.Currency = "EUR"
+	created = mustCreate(t, store, exact)
+	p, err = store.GetByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if !p.Price.Equal(exact.Price) || p.Currency != "EUR" {
+		t.Fatalf("expected price EUR %s, got %s %s", exact.Price, p.Currency, p.Price)
+	}
+
+	_, err = store.GetByID(created.ID + 100)
+	assertCode(t, err, product.CodeNotFound)
+}
+
+func testGetByName(t *testing.T, store product.Store) {
+	created := mustCreate(t, store, newProduct("product", 10))
+	mustCreate(t, store, newProduct("other", 20))
+
+	p, err := store.GetByName("product")
+	if err != nil {
+		t.Fatalf("GetByName: unexpected error: %v", err)
+	}
+	if p.ID != created.ID {
+		t.Fatalf("GetByName: expected product %d, got %d", created.ID, p.ID)
+	}
+
+	// La búsqueda por nombre compara los nombres normalizados
+	p, err = store.GetByName("  PRODUCT ")
+	if err != nil {
+		t.Fatalf("GetByName: unexpected error: %v", err)
+	}
+	if p.ID != created.ID {
+		t.Fatalf("GetByName: expected product %d, got %d", created.ID, p.ID)
+	}
+
+	_, err = store.GetByName("missing")
+	assertCode(t, err, product.CodeNotFound)
+}
+
+func testDuplicateName(t *testing.T, store product.Store) {
+	mustCreate(t, store, newProduct("Red  Apple", 10))
+	other := mustCreate(t, store, newProduct("Green Apple", 10))
+
+	// Los nombres se comparan normalizados: sin distinguir mayúsculas ni espacios repetidos
+	_, err := store.Create(newProduct(" red apple", 20))
+	assertCode(t, err, product.CodeAlreadyExists)
+
+	other.Name = "RED APPLE"
+	_, err = store.Update(other)
+	assertCode(t, err, product.CodeAlreadyExists)
+
+	// Un producto puede conservar su propio nombre, o cambiar sólo mayúsculas y espacios
+	other.Name = "green  apple"
+	if _, err := store.Update(other); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	// Un duplicado dentro de una transacción sólo descarta esa transacción
+	err = store.Transaction(func(tx product.Store) error {
+		_, err := tx.Create(newProduct("RED APPLE", 20))
+		return err
+	})
+	assertCode(t, err, product.CodeAlreadyExists)
+
+	if _, err := store.Create(newProduct("Yellow Apple", 10)); err != nil {
+		t.Fatalf("Create: unexpected error: %v", err)
+	}
+}
+
+func names(page *product.Page) []string {
+	names := []string{}
+	for _, p := range page.Products {
+		names = append(names, p.Name)
+	}
+
+	return names
+}
+
+func assertNames(t *testing.T, page *product.Page, expected ...string) {
+	t.Helper()
+
+	got := names(page)
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("expected products %v, got %v", expected, got)
+	}
+}
+
+func testGetAllFilters(t *testing.T, store product.Store) {
+	// Umbrales de reposición: apple sin control, apricot con stock bajo, cherry por encima del umbral
+	thresholds := []float64{0, 1, 0, 2}
+	for i, name := range []string{"apple", "apricot", "banana", "cherry"} {
+		p := newProduct(name, float64(10*(i+1)))
+		p.ReorderThreshold = thresholds[i]
+		p = mustCreate(t, store, p)
+		mustAddStock(t, store, p.ID, float64(i))
+	}
+	inactive := newProduct("avocado", 50)
+	inactive.IsActive = false
+	mustCreate(t, store, inactive)
+
+	active := true
+	minPrice, maxPrice := decimal.NewFromInt(20), decimal.NewFromInt(40)
+	minStock := 2.0
+
+	tests := []struct {
+		name     string
+		query    product.Query
+		expected []string
+		total    int64
+	}{
+		{"all", product.Query{}, []string{"apple", "apricot", "banana", "cherry", "avocado"}, 5},
+		{"active", product.Query{IsActive: &active}, []string{"apple", "apricot", "banana", "cherry"}, 4},
+		{"price range", product.Query{MinPrice: &minPrice, MaxPrice: &maxPrice}, []string{"apricot", "banana", "cherry"}, 3},
+		{"min stock", product.Query{MinStock: &minStock}, []string{"banana", "cherry"}, 2},
+		{"name prefix", product.Query{NamePrefix: "ap"}, []string{"apple", "apricot"}, 2},
+		{"name prefix case", product.Query{NamePrefix: "AP"}, []string{"apple", "apricot"}, 2},
+		{"name prefix wildcards", product.Query{NamePrefix: "a%"}, []string{}, 0},
+		{"low stock", product.Query{LowStock: true}, []string{"apricot"}, 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Limit = product.DefaultLimit
+			tt.query.SortBy = product.SortByID
+			tt.query.SortOrder = product.SortAsc
+
+			page, err := store.GetAll(&tt.query)
+			if err != nil {
+				t.Fatalf("GetAll: unexpected error: %v", err)
+			}
+			assertNames(t, page, tt.expected...)
+			if page.Total != tt.total {
+				t.Fatalf("expected total %d, got %d", tt.total, page.Total)
+			}
+		})
+	}
+}
+
+func testGetAllPaging(t *testing.T, store product.Store) {
+	// Los precios repetidos obligan a desempatar por ID
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		mustCreate(t, store, newProduct(name, float64(10*(i/2))))
+	}
+
+	// Paginado por offset
+	page, err := store.GetAll(&product.Query{Limit: 2, Offset: 2, SortBy: product.SortByName, SortOrder: product.SortAsc})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page, "c", "d")
+	if page.Total != 5 {
+		t.Fatalf("expected total 5, got %d", page.Total)
+	}
+
+	// Paginado por cursor, recorriendo todas las páginas
+	got := []string{}
+	query := &product.Query{Limit: 2, SortBy: product.SortByPrice, SortOrder: product.SortDesc}
+	for i := 0; i < 5; i++ {
+		page, err := store.GetAll(query)
+		if err != nil {
+			t.Fatalf("GetAll: unexpected error: %v", err)
+		}
+		got = append(got, names(page)...)
+		if len(page.Products) < query.Limit {
+			break
+		}
+
+		last := page.Products[len(page.Products)-1]
+		query.After = &product.Cursor{SortBy: query.SortBy, SortOrder: query.SortOrder, ID: last.ID, Value: last.Price}
+	}
+
+	expected := []string{"e", "d", "c", "b", "a"}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("expected products %v, got %v", expected, got)
+	}
+
+	// Sin calcular el total
+	page, err = store.GetAll(&product.Query{Limit: 2, SortBy: product.SortByID, SortOrder: product.SortAsc, SkipTotal: true})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page, "a", "b")
+	if page.Total != 0 {
+		t.Fatalf("expected no total, got %d", page.Total)
+	}
+}
+
+func testSortByName(t *testing.T, store product.Store) {
+	for _, name := range []string{"cherry", "Banana", "apple"} {
+		mustCreate(t, store, newProduct(name, 10))
+	}
+
+	// Las mayúsculas no alteran el orden, tampoco al continuar desde un cursor
+	query := &product.Query{Limit: 10, SortBy: product.SortByName, SortOrder: product.SortAsc}
+	page, err := store.GetAll(query)
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page, "apple", "Banana", "cherry")
+
+	first := page.Products[0]
+	query.After = &product.Cursor{SortBy: query.SortBy, SortOrder: query.SortOrder, ID: first.ID, Value: product.NameSortKey(first.Name)}
+	page, err = store.GetAll(query)
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page, "Banana", "cherry")
+
+	query.SortOrder = product.SortDesc
+	query.After = &product.Cursor{SortBy: query.SortBy, SortOrder: query.SortOrder, ID: page.Products[1].ID, Value: "cherry"}
+	page, err = store.GetAll(query)
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page, "Banana", "apple")
+}
+
+func testUpdate(t *testing.T, store product.Store) {
+	created := mustCreate(t, store, newProduct("product", 10))
+	mustAddStock(t, store, created.ID, 5)
+	if _, err := store.CreateReservation(newReservation("r1", created.ID, 2, time.Now().Add(time.Hour))); err != nil {
+		t.Fatalf("CreateReservation: unexpected error: %v", err)
+	}
+
+	current, err := store.GetByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+
+	version := current.Version
+	current.Name = "renamed"
+	current.Price = decimal.NewFromInt(15)
+	current.IsActive = false // Los valores cero también se guardan
+	current.Stock = 100      // El stock y el stock reservado no se modifican con Update
+	current.Reserved = 0
+	updated, err := store.Update(current)
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if updated.Name != "renamed" || !updated.Price.Equal(decimal.NewFromInt(15)) || updated.IsActive {
+		t.Fatalf("Update: unexpected product %+v", updated)
+	}
+	if updated.Stock != 5 || updated.Reserved != 2 {
+		t.Fatalf("Update: expected stock 5 and reserved 2 to be preserved, got %v and %v", updated.Stock, updated.Reserved)
+	}
+	if updated.Version != version+1 {
+		t.Fatalf("Update: expected version to be incremented, got %d", updated.Version)
+	}
+
+	p, err := store.GetByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Name != "renamed" || p.Stock != 5 || p.Version != updated.Version {
+		t.Fatalf("GetByID: unexpected product after update %+v", p)
+	}
+
+	_, err = store.Update(newProduct("missing", 10))
+	assertCode(t, err, product.CodeNotFound)
+}
+
+func testUpdateVersionConflict(t *testing.T, store product.Store) {
+	created := mustCreate(t, store, newProduct("product", 10))
+
+	stale := *created
+	created.Price = decimal.NewFromInt(20)
+	if _, err := store.Update(created); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	stale.Price = decimal.NewFromInt(30)
+	_, err := store.Update(&stale)
+	assertCode(t, err, product.CodeVersionMismatch)
+
+	// Sin versión, la modificación se realiza siempre
+	stale.Version = 0
+	if _, err := store.Update(&stale); err != nil {
+		t.Fatalf("Update without version: unexpected error: %v", err)
+	}
+}
+
+func testDelete(t *testing.T, store product.Store) {
+	created := mustCreate(t, store, newProduct("product", 10))
+	other := mustCreate(t, store, newProduct("other", 10))
+
+	if err := store.Delete(created); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	// Los productos eliminados no son visibles
+	_, err := store.GetByID(created.ID)
+	assertCode(t, err, product.CodeNotFound)
+	_, err = store.GetByName("product")
+	assertCode(t, err, product.CodeNotFound)
+	_, err = store.Update(created)
+	assertCode(t, err, product.CodeNotFound)
+	_, err = store.AddStockMovement(&product.StockMovement{ProductID: created.ID, Quantity: 1, Reason: product.ReasonPurchase})
+	assertCode(t, err, product.CodeNotFound)
+	_, err = store.CreateReservation(newReservation("r1", created.ID, 1, time.Now().Add(time.Hour)))
+	assertCode(t, err, product.CodeNotFound)
+
+	page, err := store.GetAll(&product.Query{Limit: product.DefaultLimit, SortBy: product.SortByID, SortOrder: product.SortAsc})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page, "other")
+
+	// Salvo que se pidan explícitamente
+	deleted, err := store.GetByIDIncludeDeleted(created.ID)
+	if err != nil {
+		t.Fatalf("GetByIDIncludeDeleted: unexpected error: %v", err)
+	}
+	if deleted.DeletedAt == nil || deleted.Name != "product" {
+		t.Fatalf("GetByIDIncludeDeleted: unexpected product %+v", deleted)
+	}
+
+	page, err = store.GetAll(&product.Query{Limit: product.DefaultLimit, SortBy: product.SortByID, SortOrder: product.SortAsc, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page, "product", "other")
+
+	// Un producto no eliminado no puede restaurarse
+	_, err = store.Restore(&product.Product{ID: page.Products[1].ID})
+	assertCode(t, err, product.CodeNotFound)
+
+	restored, err := store.Restore(&product.Product{ID: created.ID})
+	if err != nil {
+		t.Fatalf("Restore: unexpected error: %v", err)
+	}
+	if restored.DeletedAt != nil || restored.Name != "product" {
+		t.Fatalf("Restore: unexpected product %+v", restored)
+	}
+	if _, err := store.GetByName("product"); err != nil {
+		t.Fatalf("GetByName after Restore: unexpected error: %v", err)
+	}
+
+	// Las reservas pendientes del producto se liberan al eliminarlo definitivamente
+	mustAddStock(t, store, created.ID, 5)
+	if _, err := store.CreateReservation(newReservation("r1", created.ID, 2, time.Now().Add(time.Hour))); err != nil {
+		t.Fatalf("CreateReservation: unexpected error: %v", err)
+	}
+
+	// Sus categorías, precios en listas y precios programados se eliminan con el producto; los del otro producto no
+	category := mustCreateCategory(t, store, "clothes", nil)
+	list := &product.PriceList{Name: "retail-ARS", Currency: "ARS"}
+	if err := store.CreatePriceList(list); err != nil {
+		t.Fatalf("CreatePriceList: unexpected error: %v", err)
+	}
+	now := time.Now()
+	applied := newPrice(created.ID, 10, product.PriceApplied, now.Add(-time.Hour))
+	for _, id := range []uint{created.ID, other.ID} {
+		if err := store.SetProductCategories(id, []uint{category.ID}); err != nil {
+			t.Fatalf("SetProductCategories: unexpected error: %v", err)
+		}
+		if err := store.SetListPrices([]*product.ListPrice{newListPrice(list.ID, id, "100")}); err != nil {
+			t.Fatalf("SetListPrices: unexpected error: %v", err)
+		}
+		if err := store.AddPrice(newPrice(id, 20, product.PriceScheduled, now.Add(time.Hour))); err != nil {
+			t.Fatalf("AddPrice: unexpected error: %v", err)
+		}
+	}
+	if err := store.AddPrice(applied); err != nil {
+		t.Fatalf("AddPrice: unexpected error: %v", err)
+	}
+
+	if err := store.Purge(restored); err != nil {
+		t.Fatalf("Purge: unexpected error: %v", err)
+	}
+	_, err = store.GetByIDIncludeDeleted(created.ID)
+	assertCode(t, err, product.CodeNotFound)
+
+	for _, id := range []uint{created.ID, other.ID} {
+		purged := id == created.ID
+		categories, err := store.GetProductCategories(id)
+		if err != nil {
+			t.Fatalf("GetProductCategories: unexpected error: %v", err)
+		}
+		if (len(categories) == 0) != purged {
+			t.Fatalf("product %d: unexpected categories %+v", id, categories)
+		}
+		_, err = store.GetListPrice(list.ID, id)
+		if purged {
+			assertCode(t, err, product.CodeNotFound)
+		} else if err != nil {
+			t.Fatalf("GetListPrice: unexpected error: %v", err)
+		}
+	}
+	// El historial de precios se conserva
+	prices, err := store.GetPrices(created.ID)
+	if err != nil {
+		t.Fatalf("GetPrices: unexpected error: %v", err)
+	}
+	if len(prices) != 1 || prices[0].ID != applied.ID {
+		t.Fatalf("expected only the applied price, got %+v", prices)
+	}
+	prices, err = store.GetPrices(other.ID)
+	if err != nil {
+		t.Fatalf("GetPrices: unexpected error: %v", err)
+	}
+	if len(prices) != 1 || prices[0].Status != product.PriceScheduled {
+		t.Fatalf("expected the scheduled price of the other product, got %+v", prices)
+	}
+
+	reservation, err := store.GetReservation("r1")
+	if err != nil {
+		t.Fatalf("GetReservation: unexpected error: %v", err)
+	}
+	if reservation.Status != product.ReservationReleased {
+		t.Fatalf("expected status %q, got %q", product.ReservationReleased, reservation.Status)
+	}
+}
+
+func testDeleteReleasesName(t *testing.T, store product.Store) {
+	deleted := mustCreate(t, store, newProduct("product", 10))
+	if err := store.Delete(deleted); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	// El nombre de un producto eliminado puede usarse para otro
+	created := mustCreate(t, store, newProduct("Product", 20))
+
+	p, err := store.GetByName("product")
+	if err != nil {
+		t.Fatalf("GetByName: unexpected error: %v", err)
+	}
+	if p.ID != created.ID {
+		t.Fatalf("GetByName: expected product %d, got %d", created.ID, p.ID)
+	}
+
+	// En ese caso, el producto eliminado no puede restaurarse
+	_, err = store.Restore(&product.Product{ID: deleted.ID})
+	assertCode(t, err, product.CodeAlreadyExists)
+}
+
+func testStockMovements(t *testing.T, store product.Store) {
+	created := mustCreate(t, store, newProduct("product", 10))
+
+	p := mustAddStock(t, store, created.ID, 10)
+	if p.Stock != 10 {
+		t.Fatalf("expected stock 10, got %v", p.Stock)
+	}
+
+	p, err := store.AddStockMovement(&product.StockMovement{ProductID: created.ID, Quantity: -4, Reason: product.ReasonSale, Reference: "order 1"})
+	if err != nil {
+		t.Fatalf("AddStockMovement: unexpected error: %v", err)
+	}
+	if p.Stock != 6 {
+		t.Fatalf("expected stock 6, got %v", p.Stock)
+	}
+
+	_, err = store.AddStockMovement(&product.StockMovement{ProductID: created.ID, Quantity: -7, Reason: product.ReasonSale})
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+
+	_, err = store.AddStockMovement(&product.StockMovement{ProductID: created.ID + 100, Quantity: 1, Reason: product.ReasonPurchase})
+	assertCode(t, err, product.CodeNotFound)
+
+	movements, err := store.GetStockMovements(created.ID)
+	if err != nil {
+		t.Fatalf("GetStockMovements: unexpected error: %v", err)
+	}
+	if len(movements) != 2 {
+		t.Fatalf("expected 2 movements, got %d", len(movements))
+	}
+	if movements[0].Quantity != 10 || movements[1].Quantity != -4 || movements[1].Reference != "order 1" {
+		t.Fatalf("unexpected movements %+v, %+v", movements[0], movements[1])
+	}
+	if movements[0].ID == 0 || movements[0].CreatedAt.IsZero() {
+		t.Fatalf("expected movement ID and creation time to be assigned, got %+v", movements[0])
+	}
+}
+
+func newReservation(id string, productID uint, quantity float64, expiresAt time.Time) *product.Reservation {
+	return &product.Reservation{
+		ID:        id,
+		ProductID: productID,
+		Quantity:  quantity,
+		Status:    product.ReservationPending,
+		ExpiresAt: expiresAt,
+	}
+}
+
+func testReservations(t *testing.T, store product.Store) {
+	created := mustCreate(t, store, newProduct("product", 10))
+	mustAddStock(t, store, created.ID, 10)
+	expiresAt := time.Now().Add(time.Hour)
+
+	p, err := store.CreateReservation(newReservation("r1", created.ID, 6, expiresAt))
+	if err != nil {
+		t.Fatalf("CreateReservation: unexpected error: %v", err)
+	}
+	if p.Reserved != 6 {
+		t.Fatalf("expected 6 reserved, got %v", p.Reserved)
+	}
+
+	_, err = store.CreateReservation(newReservation("r2", created.ID, 5, expiresAt))
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+
+	// El stock reservado no puede egresar
+	_, err = store.AddStockMovement(&product.StockMovement{ProductID: created.ID, Quantity: -5, Reason: product.ReasonSale})
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+
+	reservation, err := store.GetReservation("r1")
+	if err != nil {
+		t.Fatalf("GetReservation: unexpected error: %v", err)
+	}
+	if reservation.ProductID != created.ID || reservation.Quantity != 6 || reservation.Status != product.ReservationPending {
+		t.Fatalf("GetReservation: unexpected reservation %+v", reservation)
+	}
+
+	_, err = store.GetReservation("missing")
+	assertCode(t, err, product.CodeNotFound)
+
+	p, err = store.CloseReservation(reservation, product.ReservationReleased)
+	if err != nil {
+		t.Fatalf("CloseReservation: unexpected error: %v", err)
+	}
+	if p.Reserved != 0 {
+		t.Fatalf("expected 0 reserved, got %v", p.Reserved)
+	}
+
+	_, err = store.CloseReservation(reservation, product.ReservationConfirmed)
+	if !errors.Is(err, product.ErrReservationClosed) {
+		t.Fatalf("expected ErrReservationClosed, got %v", err)
+	}
+
+	reservation, err = store.GetReservation("r1")
+	if err != nil {
+		t.Fatalf("GetReservation: unexpected error: %v", err)
+	}
+	if reservation.Status != product.ReservationReleased {
+		t.Fatalf("expected status %q, got %q", product.ReservationReleased, reservation.Status)
+	}
+}
+
+func testExpiredReservations(t *testing.T, store product.Store) {
+	created := mustCreate(t, store, newProduct("product", 10))
+	mustAddStock(t, store, created.ID, 10)
+	now := time.Now()
+
+	for i, expiresAt := range []time.Time{now.Add(-time.Minute), now.Add(-2 * time.Minute), now.Add(time.Minute), now.Add(-3 * time.Minute)} {
+		if _, err := store.CreateReservation(newReservation(fmt.Sprintf("r%d", i), created.ID, 1, expiresAt)); err != nil {
+			t.Fatalf("CreateReservation: unexpected error: %v", err)
+		}
+	}
+	released, err := store.GetReservation("r3")
+	if err != nil {
+		t.Fatalf("GetReservation: unexpected error: %v", err)
+	}
+	if _, err := store.CloseReservation(released, product.ReservationReleased); err != nil {
+		t.Fatalf("CloseReservation: unexpected error: %v", err)
+	}
+
+	reservations, err := store.GetExpiredReservations(now, 10)
+	if err != nil {
+		t.Fatalf("GetExpiredReservations: unexpected error: %v", err)
+	}
+	ids := []string{}
+	for _, r := range reservations {
+		ids = append(ids, r.ID)
+	}
+	if fmt.Sprint(ids) != fmt.Sprint([]string{"r1", "r0"}) {
+		t.Fatalf("expected expired reservations [r1 r0], got %v", ids)
+	}
+
+	reservations, err = store.GetExpiredReservations(now, 1)
+	if err != nil {
+		t.Fatalf("GetExpiredReservations: unexpected error: %v", err)
+	}
+	if len(reservations) != 1 {
+		t.Fatalf("expected 1 expired reservation, got %d", len(reservations))
+	}
+}
+
+func newEvent(id string) *product.Event {
+	return &product.Event{
+		ID:         id,
+		Type:       product.EventCreated,
+		ProductID:  1,
+		After:      newProduct("product", 10),
+		OccurredAt: time.Now(),
+	}
+}
+
+func testOutbox(t *testing.T, store product.Store) {
+	for _, id := range []string{"e1", "e2", "e3"} {
+		if err := store.AddEvent(newEvent(id)); err != nil {
+			t.Fatalf("AddEvent: unexpected error: %v", err)
+		}
+	}
+
+	events, err := store.GetPendingEvents(2)
+	if err != nil {
+		t.Fatalf("GetPendingEvents: unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "e1" || events[1].ID != "e2" {
+		t.Fatalf("expected pending events e1 and e2, got %+v", events)
+	}
+	if events[0].Type != product.EventCreated || events[0].After == nil || events[0].After.Name != "product" {
+		t.Fatalf("unexpected event %+v", events[0])
+	}
+
+	if err := store.MarkEventSent(events[0]); err != nil {
+		t.Fatalf("MarkEventSent: unexpected error: %v", err)
+	}
+
+	events, err = store.GetPendingEvents(10)
+	if err != nil {
+		t.Fatalf("GetPendingEvents: unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "e2" || events[1].ID != "e3" {
+		t.Fatalf("expected pending events e2 and e3, got %+v", events)
+	}
+
+	// Sólo se eliminan los eventos publicados antes del momento indicado, nunca los pendientes
+	deleted, err := store.DeleteSentEvents(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteSentEvents: unexpected error: %v", err)
+	}
+	if deleted != 0 {
+		t.Fatalf("expected no events to be deleted, got %d", deleted)
+	}
+	deleted, err = store.DeleteSentEvents(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteSentEvents: unexpected error: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 deleted event, got %d", deleted)
+	}
+	events, err = store.GetPendingEvents(10)
+	if err != nil {
+		t.Fatalf("GetPendingEvents: unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected the pending events to be kept, got %+v", events)
+	}
+}
+
+func testAuditEntries(t *testing.T, store product.Store) {
+	for i, operation := range []string{product.OperationCreate, product.OperationUpdate, product.OperationDelete} {
+		err := store.AddAuditEntry(&product.AuditEntry{
+			ProductID: 1,
+			Actor:     "alice",
+			Operation: operation,
+			Changes:   []product.FieldChange{{Field: "price", Old: float64(i), New: float64(i + 1)}},
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("AddAuditEntry: unexpected error: %v", err)
+		}
+	}
+	if err := store.AddAuditEntry(&product.AuditEntry{ProductID: 2, Actor: "bob", Operation: product.OperationCreate, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("AddAuditEntry: unexpected error: %v", err)
+	}
+
+	// Del cambio más reciente al más antiguo
+	page, err := store.GetAuditEntries(&product.HistoryQuery{ProductID: 1, Limit: 2})
+	if err != nil {
+		t.Fatalf("GetAuditEntries: unexpected error: %v", err)
+	}
+	if page.Total != 3 || len(page.Entries) != 2 {
+		t.Fatalf("expected 2 of 3 entries, got %d of %d", len(page.Entries), page.Total)
+	}
+	entry := page.Entries[0]
+	if entry.Operation != product.OperationDelete || entry.Actor != "alice" || entry.ID == 0 || entry.CreatedAt.IsZero() {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].Field != "price" || entry.Changes[0].Old != 2.0 || entry.Changes[0].New != 3.0 {
+		t.Fatalf("unexpected changes %+v", entry.Changes)
+	}
+
+	page, err = store.GetAuditEntries(&product.HistoryQuery{ProductID: 1, Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("GetAuditEntries: unexpected error: %v", err)
+	}
+	if page.Total != 3 || len(page.Entries) != 1 || page.Entries[0].Operation != product.OperationCreate {
+		t.Fatalf("expected the oldest entry, got %+v", page.Entries)
+	}
+}
+
+func newPrice(productID uint, price float64, status string, effectiveFrom time.Time) *product.Price {
+	return &product.Price{
+		ProductID:     productID,
+		Price:         decimal.NewFromFloat(price),
+		Currency:      product.DefaultCurrency,
+		Status:        status,
+		EffectiveFrom: effectiveFrom,
+	}
+}
+
+func testPrices(t *testing.T, store product.Store) {
+	now := time.Now()
+
+	first := newPrice(1, 10, product.PriceApplied, now.Add(-2*time.Hour))
+	second := newPrice(1, 12, product.PriceApplied, now.Add(-time.Hour))
+	due := newPrice(1, 15, product.PriceScheduled, now.Add(-time.Minute))
+	later := newPrice(1, 20, product.PriceScheduled, now.Add(time.Hour))
+	cancelled := newPrice(1, 30, product.PriceScheduled, now.Add(-2*time.Minute))
+	other := newPrice(2, 50, product.PriceScheduled, now.Add(-3*time.Minute))
+	for _, price := range []*product.Price{first, second, due, later, cancelled, other} {
+		if err := store.AddPrice(price); err != nil {
+			t.Fatalf("AddPrice: unexpected error: %v", err)
+		}
+		if price.ID == 0 {
+			t.Fatalf("expected AddPrice to assign an ID")
+		}
+	}
+
+	// Al registrar un precio aplicado, se cierra la vigencia del anterior
+	retrieved, err := store.GetPrice(first.ID)
+	if err != nil {
+		t.Fatalf("GetPrice: unexpected error: %v", err)
+	}
+	if retrieved.EffectiveTo == nil || !retrieved.EffectiveTo.Equal(second.EffectiveFrom) {
+		t.Fatalf("expected the first price to end when the second one starts, got %v", retrieved.EffectiveTo)
+	}
+
+	_, err = store.GetPrice(1000)
+	assertCode(t, err, product.CodeNotFound)
+
+	if err := store.CancelPrice(cancelled); err != nil {
+		t.Fatalf("CancelPrice: unexpected error: %v", err)
+	}
+	if err := store.CancelPrice(cancelled); !errors.Is(err, product.ErrPriceNotScheduled) {
+		t.Fatalf("expected ErrPriceNotScheduled cancelling twice, got %v", err)
+	}
+
+	prices, err := store.GetDuePrices(now, 10)
+	if err != nil {
+		t.Fatalf("GetDuePrices: unexpected error: %v", err)
+	}
+	if len(prices) != 2 || prices[0].ID != other.ID || prices[1].ID != due.ID {
+		t.Fatalf("expected due prices %d and %d, got %+v", other.ID, due.ID, prices)
+	}
+
+	due.EffectiveFrom = now
+	if err := store.ApplyPrice(due); err != nil {
+		t.Fatalf("ApplyPrice: unexpected error: %v", err)
+	}
+	if err := store.ApplyPrice(due); !errors.Is(err, product.ErrPriceNotScheduled) {
+		t.Fatalf("expected ErrPriceNotScheduled applying twice, got %v", err)
+	}
+
+	prices, err = store.GetPrices(1)
+	if err != nil {
+		t.Fatalf("GetPrices: unexpected error: %v", err)
+	}
+	expected := []struct {
+		id     uint
+		status string
+		open   bool
+	}{
+		{first.ID, product.PriceApplied, false},
+		{second.ID, product.PriceApplied, false},
+		{cancelled.ID, product.PriceCancelled, true},
+		{due.ID, product.PriceApplied, true},
+		{later.ID, product.PriceScheduled, true},
+	}
+	if len(prices) != len(expected) {
+		t.Fatalf("expected %d prices, got %d", len(expected), len(prices))
+	}
+	for i, e := range expected {
+		price := prices[i]
+		if price.ID != e.id || price.Status != e.status || (price.EffectiveTo == nil) != e.open {
+			t.Fatalf("price %d: expected id %d %s (open %v), got %+v", i, e.id, e.status, e.open, price)
+		}
+	}
+	if !prices[1].EffectiveTo.Equal(now) {
+		t.Fatalf("expected the second price to end when the scheduled one is applied, got %v", prices[1].EffectiveTo)
+	}
+}
+
+func newListPrice(listID, productID uint, price string) *product.ListPrice {
+	return &product.ListPrice{
+		PriceListID: listID,
+		ProductID:   productID,
+		Price:       decimal.RequireFromString(price),
+	}
+}
+
+func testPriceLists(t *testing.T, store product.Store) {
+	retail := &product.PriceList{Name: "retail-ARS", Currency: "ARS"}
+	wholesale := &product.PriceList{Name: "wholesale-USD", Currency: "USD"}
+	for _, list := range []*product.PriceList{wholesale, retail} {
+		if err := store.CreatePriceList(list); err != nil {
+			t.Fatalf("CreatePriceList: unexpected error: %v", err)
+		}
+	}
+	err := store.CreatePriceList(&product.PriceList{Name: "retail-ARS", Currency: "ARS"})
+	assertCode(t, err, product.CodeAlreadyExists)
+
+	list, err := store.GetPriceList("retail-ARS")
+	if err != nil {
+		t.Fatalf("GetPriceList: unexpected error: %v", err)
+	}
+	if list.ID != retail.ID || list.Currency != "ARS" {
+		t.Fatalf("unexpected price list %+v", list)
+	}
+	_, err = store.GetPriceList("missing")
+	assertCode(t, err, product.CodeNotFound)
+
+	lists, err := store.GetPriceLists()
+	if err != nil {
+		t.Fatalf("GetPriceLists: unexpected error: %v", err)
+	}
+	if len(lists) != 2 || lists[0].Name != "retail-ARS" || lists[1].Name != "wholesale-USD" {
+		t.Fatalf("expected the price lists sorted by name, got %+v", lists)
+	}
+
+	err = store.SetListPrices([]*product.ListPrice{newListPrice(retail.ID, 2, "200.5"), newListPrice(retail.ID, 1, "100"), newListPrice(wholesale.ID, 1, "1.25")})
+	if err != nil {
+		t.Fatalf("SetListPrices: unexpected error: %v", err)
+	}
+	// Reemplaza el precio existente y agrega uno nuevo
+	err = store.SetListPrices([]*product.ListPrice{newListPrice(retail.ID, 1, "110.75"), newListPrice(retail.ID, 3, "300")})
+	if err != nil {
+		t.Fatalf("SetListPrices: unexpected error: %v", err)
+	}
+
+	price, err := store.GetListPrice(retail.ID, 1)
+	if err != nil {
+		t.Fatalf("GetListPrice: unexpected error: %v", err)
+	}
+	if !price.Price.Equal(decimal.RequireFromString("110.75")) {
+		t.Fatalf("expected price 110.75, got %s", price.Price)
+	}
+	_, err = store.GetListPrice(wholesale.ID, 2)
+	assertCode(t, err, product.CodeNotFound)
+
+	page, err := store.GetListPrices(retail.ID, &product.ListPricesQuery{PriceList: retail.Name, Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("GetListPrices: unexpected error: %v", err)
+	}
+	if page.Total != 3 || len(page.Prices) != 2 || page.Prices[0].ProductID != 2 || page.Prices[1].ProductID != 3 {
+		t.Fatalf("expected products 2 and 3 of 3, got %+v (total %d)", page.Prices, page.Total)
+	}
+
+	removed, err := store.RemoveListPrices(retail.ID, []uint{1, 4})
+	if err != nil {
+		t.Fatalf("RemoveListPrices: unexpected error: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("expected 1 removed price, got %d", removed)
+	}
+
+	if err := store.DeletePriceList(retail); err != nil {
+		t.Fatalf("DeletePriceList: unexpected error: %v", err)
+	}
+	_, err = store.GetPriceList("retail-ARS")
+	assertCode(t, err, product.CodeNotFound)
+	page, err = store.GetListPrices(retail.ID, &product.ListPricesQuery{PriceList: retail.Name, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetListPrices: unexpected error: %v", err)
+	}
+	if page.Total != 0 {
+		t.Fatalf("expected the prices of the deleted list to be removed, got %d", page.Total)
+	}
+
+	// Los precios de las otras listas se conservan
+	if _, err := store.GetListPrice(wholesale.ID, 1); err != nil {
+		t.Fatalf("GetListPrice: unexpected error: %v", err)
+	}
+}
+
+func mustCreateCategory(t *testing.T, store product.Store, name string, parent *product.Category) *product.Category {
+	t.Helper()
+
+	category := &product.Category{Name: name}
+	if parent != nil {
+		category.ParentID = &parent.ID
+	}
+	if err := store.CreateCategory(category); err != nil {
+		t.Fatalf("CreateCategory(%s): unexpected error: %v", name, err)
+	}
+
+	return category
+}
+
+func categoryPaths(categories []*product.Category) []string {
+	paths := []string{}
+	for _, c := range categories {
+		paths = append(paths, c.Path)
+	}
+	return paths
+}
+
+func testCategories(t *testing.T, store product.Store) {
+	electronics := mustCreateCategory(t, store, "Electronics", nil)
+	phones := mustCreateCategory(t, store, "Phones", electronics)
+	smartphones := mustCreateCategory(t, store, "Smartphones", phones)
+	home := mustCreateCategory(t, store, "Home", nil)
+
+	if smartphones.Path != product.CategoryPath(product.CategoryPath(electronics.Path, phones.ID), smartphones.ID) || smartphones.Depth() != 3 {
+		t.Fatalf("unexpected path %s", smartphones.Path)
+	}
+
+	// Los nombres son únicos entre las subcategorías de una misma categoría
+	err := store.CreateCategory(&product.Category{Name: " phones ", ParentID: &electronics.ID})
+	assertCode(t, err, product.CodeAlreadyExists)
+	mustCreateCategory(t, store, "Phones", home)
+
+	categories, err := store.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories: unexpected error: %v", err)
+	}
+	paths := categoryPaths(categories)
+	if len(paths) != 5 || paths[0] != electronics.Path || paths[1] != phones.Path || paths[2] != smartphones.Path {
+		t.Fatalf("expected the categories sorted by path, got %v", paths)
+	}
+
+	phone := mustCreate(t, store, newProduct("Phone", 100))
+	smartphone := mustCreate(t, store, newProduct("Smartphone", 500))
+	mustCreate(t, store, newProduct("Lamp", 20))
+	if err := store.SetProductCategories(phone.ID, []uint{phones.ID, home.ID}); err != nil {
+		t.Fatalf("SetProductCategories: unexpected error: %v", err)
+	}
+	if err := store.SetProductCategories(smartphone.ID, []uint{smartphones.ID}); err != nil {
+		t.Fatalf("SetProductCategories: unexpected error: %v", err)
+	}
+
+	assigned, err := store.GetProductCategories(phone.ID)
+	if err != nil {
+		t.Fatalf("GetProductCategories: unexpected error: %v", err)
+	}
+	if paths := categoryPaths(assigned); len(paths) != 2 || paths[0] != phones.Path || paths[1] != home.Path {
+		t.Fatalf("unexpected categories %v", paths)
+	}
+
+	// El filtro por categoría incluye sus subcategorías
+	page, err := store.GetAll(&product.Query{Limit: 10, SortBy: product.SortByID, SortOrder: product.SortAsc, CategoryPath: electronics.Path})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page, "Phone", "Smartphone")
+	page, err = store.GetAll(&product.Query{Limit: 10, SortBy: product.SortByID, SortOrder: product.SortAsc, CategoryPath: smartphones.Path})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page, "Smartphone")
+
+	// Al mover una categoría, se mueven sus subcategorías
+	phones.ParentID = &home.ID
+	phones.Name = "Mobile phones"
+	if err := store.UpdateCategory(phones); err != nil {
+		t.Fatalf("UpdateCategory: unexpected error: %v", err)
+	}
+	if phones.Path != product.CategoryPath(home.Path, phones.ID) {
+		t.Fatalf("unexpected path %s", phones.Path)
+	}
+	moved, err := store.GetCategory(smartphones.ID)
+	if err != nil {
+		t.Fatalf("GetCategory: unexpected error: %v", err)
+	}
+	if moved.Path != product.CategoryPath(phones.Path, smartphones.ID) {
+		t.Fatalf("expected the subcategory to be moved, got path %s", moved.Path)
+	}
+	page, err = store.GetAll(&product.Query{Limit: 10, SortBy: product.SortByID, SortOrder: product.SortAsc, CategoryPath: electronics.Path})
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	assertNames(t, page)
+
+	err = store.DeleteCategory(phones)
+	assertCode(t, err, product.CodeConflict)
+
+	if err := store.DeleteCategory(moved); err != nil {
+		t.Fatalf("DeleteCategory: unexpected error: %v", err)
+	}
+	_, err = store.GetCategory(smartphones.ID)
+	assertCode(t, err, product.CodeNotFound)
+	assigned, err = store.GetProductCategories(smartphone.ID)
+	if err != nil {
+		t.Fatalf("GetProductCategories: unexpected error: %v", err)
+	}
+	if len(assigned) != 0 {
+		t.Fatalf("expected the assignments of the deleted category to be removed, got %v", categoryPaths(assigned))
+	}
+
+	// Las categorías informadas reemplazan a las anteriores
+	if err := store.SetProductCategories(phone.ID, nil); err != nil {
+		t.Fatalf("SetProductCategories: unexpected error: %v", err)
+	}
+	assigned, err = store.GetProductCategories(phone.ID)
+	if err != nil {
+		t.Fatalf("GetProductCategories: unexpected error: %v", err)
+	}
+	if len(assigned) != 0 {
+		t.Fatalf("expected no categories, got %v", categoryPaths(assigned))
+	}
+}
+
+func testVariants(t *testing.T, store product.Store) {
+	shirt := mustCreate(t, store, newProduct("Shirt", 20))
+	mustAddStock(t, store, shirt.ID, 5) // Stock previo a las variantes
+
+	price := decimal.RequireFromString("22.5")
+	small := &product.Variant{ProductID: shirt.ID, SKU: "SHIRT-S", Options: map[string]string{"size": "S", "color": "red"}}
+	large := &product.Variant{ProductID: shirt.ID, SKU: "SHIRT-L", Options: map[string]string{"size": "L"}, Price: &price}
+	for _, v := range []*product.Variant{small, large} {
+		if err := store.CreateVariant(v); err != nil {
+			t.Fatalf("CreateVariant: unexpected error: %v", err)
+		}
+	}
+	err := store.CreateVariant(&product.Variant{ProductID: shirt.ID, SKU: "SHIRT-S"})
+	assertCode(t, err, product.CodeAlreadyExists)
+
+	v, err := store.GetVariantBySKU("SHIRT-L")
+	if err != nil {
+		t.Fatalf("GetVariantBySKU: unexpected error: %v", err)
+	}
+	if v.ID != large.ID || v.Price == nil || !v.Price.Equal(price) || v.Options["size"] != "L" {
+		t.Fatalf("unexpected variant %+v", v)
+	}
+	_, err = store.GetVariantBySKU("MISSING")
+	assertCode(t, err, product.CodeNotFound)
+
+	// Los movimientos de una variante modifican el stock de la variante y el del producto
+	smallID := small.ID
+	p, err := store.AddStockMovement(&product.StockMovement{ProductID: shirt.ID, VariantID: &smallID, Quantity: 3, Reason: product.ReasonPurchase})
+	if err != nil {
+		t.Fatalf("AddStockMovement: unexpected error: %v", err)
+	}
+	if p.Stock != 8 {
+		t.Fatalf("expected product stock 8, got %v", p.Stock)
+	}
+	_, err = store.AddStockMovement(&product.StockMovement{ProductID: shirt.ID, VariantID: &smallID, Quantity: -4, Reason: product.ReasonSale})
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+	other := mustCreate(t, store, newProduct("Other", 1))
+	_, err = store.AddStockMovement(&product.StockMovement{ProductID: other.ID, VariantID: &smallID, Quantity: 1, Reason: product.ReasonPurchase})
+	assertCode(t, err, product.CodeNotFound)
+
+	reservation := newReservation("variant-reservation", shirt.ID, 2, time.Now().Add(time.Hour))
+	reservation.VariantID = &smallID
+	p, err = store.CreateReservation(reservation)
+	if err != nil {
+		t.Fatalf("CreateReservation: unexpected error: %v", err)
+	}
+	if p.Reserved != 2 {
+		t.Fatalf("expected product reserved 2, got %v", p.Reserved)
+	}
+	excess := newReservation("excess", shirt.ID, 2, time.Now().Add(time.Hour))
+	excess.VariantID = &smallID
+	_, err = store.CreateReservation(excess)
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+
+	v, err = store.GetVariant(small.ID)
+	if err != nil {
+		t.Fatalf("GetVariant: unexpected error: %v", err)
+	}
+	if v.Stock != 3 || v.Reserved != 2 {
+		t.Fatalf("expected variant stock 3 and reserved 2, got %v and %v", v.Stock, v.Reserved)
+	}
+
+	// La modificación de una variante no altera su stock
+	small.SKU = "SHIRT-S-RED"
+	small.Stock = 100
+	if err := store.UpdateVariant(small); err != nil {
+		t.Fatalf("UpdateVariant: unexpected error: %v", err)
+	}
+	if small.Stock != 3 || small.Reserved != 2 || small.SKU != "SHIRT-S-RED" {
+		t.Fatalf("unexpected variant %+v", small)
+	}
+	large.SKU = "SHIRT-S-RED"
+	err = store.UpdateVariant(large)
+	assertCode(t, err, product.CodeAlreadyExists)
+
+	if _, err := store.CloseReservation(reservation, product.ReservationReleased); err != nil {
+		t.Fatalf("CloseReservation: unexpected error: %v", err)
+	}
+	v, err = store.GetVariant(small.ID)
+	if err != nil {
+		t.Fatalf("GetVariant: unexpected error: %v", err)
+	}
+	if v.Reserved != 0 {
+		t.Fatalf("expected the variant reservation to be released, got reserved %v", v.Reserved)
+	}
+
+	err = store.DeleteVariant(small)
+	assertCode(t, err, product.CodeConflict)
+	if err := store.DeleteVariant(large); err != nil {
+		t.Fatalf("DeleteVariant: unexpected error: %v", err)
+	}
+	variants, err := store.GetVariants(shirt.ID)
+	if err != nil {
+		t.Fatalf("GetVariants: unexpected error: %v", err)
+	}
+	if len(variants) != 1 || variants[0].ID != small.ID {
+		t.Fatalf("unexpected variants %+v", variants)
+	}
+
+	// Al eliminar definitivamente el producto, se liberan los SKU de sus variantes
+	if err := store.Purge(shirt); err != nil {
+		t.Fatalf("Purge: unexpected error: %v", err)
+	}
+	_, err = store.GetVariant(small.ID)
+	assertCode(t, err, product.CodeNotFound)
+}
+
+func testLocations(t *testing.T, store product.Store) {
+	defaultLocation, err := store.GetLocationByCode(product.DefaultLocationCode)
+	if err != nil {
+		t.Fatalf("GetLocationByCode: unexpected error: %v", err)
+	}
+
+	warehouse := &product.Location{Code: "warehouse", Name: "Warehouse"}
+	if err := store.CreateLocation(warehouse); err != nil {
+		t.Fatalf("CreateLocation: unexpected error: %v", err)
+	}
+	err = store.CreateLocation(&product.Location{Code: "warehouse", Name: "Other"})
+	assertCode(t, err, product.CodeAlreadyExists)
+	_, err = store.GetLocation(warehouse.ID + 100)
+	assertCode(t, err, product.CodeNotFound)
+
+	locations, err := store.GetLocations()
+	if err != nil {
+		t.Fatalf("GetLocations: unexpected error: %v", err)
+	}
+	if len(locations) != 2 || locations[0].ID != defaultLocation.ID || locations[1].ID != warehouse.ID {
+		t.Fatalf("unexpected locations %+v", locations)
+	}
+
+	// Los movimientos sin ubicación se registran en la ubicación por defecto
+	created := mustCreate(t, store, newProduct("product", 10))
+	mustAddStock(t, store, created.ID, 10)
+	p, err := store.AddStockMovement(&product.StockMovement{ProductID: created.ID, LocationID: &warehouse.ID, Quantity: 4, Reason: product.ReasonPurchase})
+	if err != nil {
+		t.Fatalf("AddStockMovement: unexpected error: %v", err)
+	}
+	if p.Stock != 14 {
+		t.Fatalf("expected stock 14, got %v", p.Stock)
+	}
+	_, err = store.AddStockMovement(&product.StockMovement{ProductID: created.ID, LocationID: &warehouse.ID, Quantity: -5, Reason: product.ReasonSale})
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+
+	movements, err := store.TransferStock(&product.StockTransfer{ProductID: created.ID, FromLocationID: defaultLocation.ID, ToLocationID: warehouse.ID, Quantity: 6})
+	if err != nil {
+		t.Fatalf("TransferStock: unexpected error: %v", err)
+	}
+	if len(movements) != 2 || movements[0].Quantity != -6 || movements[1].Quantity != 6 || movements[0].Reason != product.ReasonTransfer {
+		t.Fatalf("unexpected transfer movements %+v", movements)
+	}
+	_, err = store.TransferStock(&product.StockTransfer{ProductID: created.ID, FromLocationID: defaultLocation.ID, ToLocationID: warehouse.ID, Quantity: 5})
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+
+	// La transferencia no modifica el stock del producto
+	p, err = store.GetByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Stock != 14 {
+		t.Fatalf("expected stock 14 after the transfer, got %v", p.Stock)
+	}
+
+	levels, err := store.GetStockLevels(&product.StockLevelQuery{ProductID: created.ID})
+	if err != nil {
+		t.Fatalf("GetStockLevels: unexpected error: %v", err)
+	}
+	if len(levels) != 2 || levels[0].LocationID != defaultLocation.ID || levels[0].Quantity != 4 || levels[1].LocationID != warehouse.ID || levels[1].Quantity != 10 {
+		t.Fatalf("unexpected stock levels %+v", levels)
+	}
+	levels, err = store.GetStockLevels(&product.StockLevelQuery{LocationID: warehouse.ID})
+	if err != nil {
+		t.Fatalf("GetStockLevels: unexpected error: %v", err)
+	}
+	if len(levels) != 1 || levels[0].ProductID != created.ID || levels[0].Quantity != 10 {
+		t.Fatalf("unexpected stock levels %+v", levels)
+	}
+
+	// El stock reservado en una ubicación no puede reservarse de nuevo, transferirse ni egresar de ella
+	reservation := newReservation("r1", created.ID, 8, time.Now().Add(time.Hour))
+	reservation.LocationID = &warehouse.ID
+	if _, err := store.CreateReservation(reservation); err != nil {
+		t.Fatalf("CreateReservation: unexpected error: %v", err)
+	}
+	other := newReservation("r2", created.ID, 5, time.Now().Add(time.Hour))
+	other.LocationID = &defaultLocation.ID
+	_, err = store.CreateReservation(other)
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+	levels, err = store.GetStockLevels(&product.StockLevelQuery{LocationID: warehouse.ID})
+	if err != nil {
+		t.Fatalf("GetStockLevels: unexpected error: %v", err)
+	}
+	if len(levels) != 1 || levels[0].Reserved != 8 {
+		t.Fatalf("unexpected stock levels %+v", levels)
+	}
+	_, err = store.TransferStock(&product.StockTransfer{ProductID: created.ID, FromLocationID: warehouse.ID, ToLocationID: defaultLocation.ID, Quantity: 3})
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+	_, err = store.AddStockMovement(&product.StockMovement{ProductID: created.ID, LocationID: &warehouse.ID, Quantity: -3, Reason: product.ReasonSale})
+	if !errors.Is(err, product.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+
+	// Al cerrar la reserva se libera el stock reservado en la ubicación
+	if _, err := store.CloseReservation(reservation, product.ReservationReleased); err != nil {
+		t.Fatalf("CloseReservation: unexpected error: %v", err)
+	}
+	if _, err := store.AddStockMovement(&product.StockMovement{ProductID: created.ID, LocationID: &warehouse.ID, Quantity: -3, Reason: product.ReasonSale}); err != nil {
+		t.Fatalf("AddStockMovement: unexpected error: %v", err)
+	}
+	levels, err = store.GetStockLevels(&product.StockLevelQuery{LocationID: warehouse.ID})
+	if err != nil {
+		t.Fatalf("GetStockLevels: unexpected error: %v", err)
+	}
+	if len(levels) != 1 || levels[0].Reserved != 0 || levels[0].Quantity != 7 {
+		t.Fatalf("unexpected stock levels %+v", levels)
+	}
+
+	// Al eliminar definitivamente el producto, se eliminan sus niveles de stock
+	if err := store.Purge(created); err != nil {
+		t.Fatalf("Purge: unexpected error: %v", err)
+	}
+	levels, err = store.GetStockLevels(&product.StockLevelQuery{ProductID: created.ID})
+	if err != nil {
+		t.Fatalf("GetStockLevels: unexpected error: %v", err)
+	}
+	if len(levels) != 0 {
+		t.Fatalf("expected no stock levels after purging, got %+v", levels)
+	}
+}
+
+func testTransactionCommit(t *testing.T, store product.Store) {
+	var created *product.Product
+	err := store.Transaction(func(tx product.Store) error {
+		var err error
+		created, err = tx.Create(newProduct("product", 10))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.AddStockMovement(&product.StockMovement{ProductID: created.ID, Quantity: 3, Reason: product.ReasonPurchase})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction: unexpected error: %v", err)
+	}
+
+	p, err := store.GetByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Stock != 3 {
+		t.Fatalf("expected stock 3, got %v", p.Stock)
+	}
+}
+
+func testTransactionRollback(t *testing.T, store product.Store) {
+	existing := mustCreate(t, store, newProduct("existing", 10))
+	mustAddStock(t, store, existing.ID, 5)
+
+	errRollback := errors.New("rollback")
+	err := store.Transaction(func(tx product.Store) error {
+		if _, err := tx.Create(newProduct("product", 10)); err != nil {
+			return err
+		}
+		if _, err := tx.AddStockMovement(&product.StockMovement{ProductID: existing.ID, Quantity: 3, Reason: product.ReasonPurchase}); err != nil {
+			return err
+		}
+		if err := tx.AddEvent(newEvent("e1")); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Transaction: expected the error returned by fn, got %v", err)
+	}
+
+	_, err = store.GetByName("product")
+	assertCode(t, err, product.CodeNotFound)
+
+	p, err := store.GetByID(existing.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if p.Stock != 5 {
+		t.Fatalf("expected stock 5, got %v", p.Stock)
+	}
+
+	movements, err := store.GetStockMovements(existing.ID)
+	if err != nil {
+		t.Fatalf("GetStockMovements: unexpected error: %v", err)
+	}
+	if len(movements) != 1 {
+		t.Fatalf("expected 1 movement, got %d", len(movements))
+	}
+
+	events, err := store.GetPendingEvents(10)
+	if err != nil {
+		t.Fatalf("GetPendingEvents: unexpected error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no pending events, got %d", len(events))
+	}
+}
@@ -0,0 +1,116 @@
+package mysql_orm
+
+import (
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"gorm.io/gorm"
+)
+
+func (r *ormRepo) CreateReservation(reservation *product.Reservation) (*product.Product, error) {
+	p := &product.Product{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Se reserva sólo si el stock disponible alcanza, en la misma sentencia que modifica el stock reservado
+		result := tx.Model(&product.Product{}).Scopes(notDeleted).
+			Where("id = ? AND stock - reserved >= ?", reservation.ProductID, reservation.Quantity).
+			Updates(map[string]interface{}{
+				"reserved": gorm.Expr("reserved + ?", reservation.Quantity),
+				"version":  gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if err := tx.Scopes(notDeleted).Take(p, reservation.ProductID).Error; err != nil {
+				return translateError(err, "product", reservation.ProductID)
+			}
+			return product.ErrInsufficientStock
+		}
+
+		if reservation.VariantID != nil {
+			err := updateVariantStock(tx, reservation.ProductID, *reservation.VariantID, "stock - reserved >= ?", reservation.Quantity, map[string]interface{}{
+				"reserved":   gorm.Expr("reserved + ?", reservation.Quantity),
+				"updated_at": time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := reserveStockLevel(tx, reservation, reservation.Quantity); err != nil {
+			return err
+		}
+
+		if err := tx.Create(reservation).Error; err != nil {
+			return translateDuplicate(err, "reservation", "id", reservation.ID)
+		}
+
+		return tx.Take(p, reservation.ProductID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *ormRepo) GetReservation(id string) (*product.Reservation, error) {
+	var reservation product.Reservation
+	result := r.db.Take(&reservation, "id = ?", id)
+	return &reservation, translateError(result.Error, "reservation", id)
+}
+
+func (r *ormRepo) CloseReservation(reservation *product.Reservation, status string) (*product.Product, error) {
+	p := &product.Product{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Sólo una operación puede cerrar la reserva, aunque varias lo intenten en simultáneo
+		result := tx.Model(&product.Reservation{}).
+			Where("id = ? AND status = ?", reservation.ID, product.ReservationPending).
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return product.ErrReservationClosed
+		}
+
+		result = tx.Model(&product.Product{}).
+			Where("id = ?", reservation.ProductID).
+			Updates(map[string]interface{}{
+				"reserved": gorm.Expr("reserved - ?", reservation.Quantity),
+				"version":  gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if reservation.VariantID != nil {
+			result = tx.Model(&product.Variant{}).
+				Where("id = ?", *reservation.VariantID).
+				Updates(map[string]interface{}{
+					"reserved":   gorm.Expr("reserved - ?", reservation.Quantity),
+					"updated_at": time.Now(),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		if err := reserveStockLevel(tx, reservation, -reservation.Quantity); err != nil {
+			return err
+		}
+
+		return tx.Take(p, reservation.ProductID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *ormRepo) GetExpiredReservations(now time.Time, limit int) ([]*product.Reservation, error) {
+	reservations := []*product.Reservation{}
+	result := r.db.Where("status = ? AND expires_at < ?", product.ReservationPending, now).Order("expires_at").Limit(limit).Find(&reservations)
+	return reservations, result.Error
+}
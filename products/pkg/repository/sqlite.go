@@ -0,0 +1,69 @@
+package mysql_orm
+
+import (
+	"github.com/glebarez/sqlite"
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewSQLiteRepo crea un repositorio implementado en ORM (SQLite), por ejemplo con dsn "file:products.db".
+// Utiliza un driver escrito en Go, de modo que no requiere CGO.
+func NewSQLiteRepo(dsn string) (product.Store, error) {
+	db, err := sqliteConnect(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "SQLite ORM - Can't connect to DB")
+	}
+
+	repo, err := newRepo(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "SQLite ORM - Can't migrate DB")
+	}
+
+	return repo, nil
+}
+
+// SQLite result codes extendidos de las violaciones de unicidad
+const (
+	sqliteConstraintPrimaryKey = 1555
+	sqliteConstraintUnique     = 2067
+)
+
+// sqliteDialector agrega al dialecto de SQLite la traducción de los errores de unicidad a gorm.ErrDuplicatedKey,
+// que el driver no provee
+type sqliteDialector struct {
+	*sqlite.Dialector
+}
+
+func (d sqliteDialector) Translate(err error) error {
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqliteConstraintPrimaryKey, sqliteConstraintUnique:
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	return err
+}
+
+func sqliteConnect(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqliteDialector{&sqlite.Dialector{DSN: dsn}}, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Warn),
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite admite un único escritor a la vez. Con una sola conexión, las transacciones se ejecutan de a una
+	// en lugar de fallar con "database is locked"
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return db, nil
+}
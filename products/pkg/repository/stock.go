@@ -0,0 +1,65 @@
+package mysql_orm
+
+import (
+	"time"
+
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"gorm.io/gorm"
+)
+
+func (r *ormRepo) AddStockMovement(movement *product.StockMovement) (*product.Product, error) {
+	p := &product.Product{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// La condición sobre el stock resultante se evalúa en la misma sentencia que lo modifica,
+		// de modo que movimientos concurrentes no pueden dejarlo por debajo del stock reservado
+		result := tx.Model(&product.Product{}).Scopes(notDeleted).
+			Where("id = ? AND stock + ? >= reserved", movement.ProductID, movement.Quantity).
+			Updates(map[string]interface{}{
+				"stock":   gorm.Expr("stock + ?", movement.Quantity),
+				"version": gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if err := tx.Scopes(notDeleted).Take(p, movement.ProductID).Error; err != nil {
+				return translateError(err, "product", movement.ProductID)
+			}
+			return product.ErrInsufficientStock
+		}
+
+		if movement.VariantID != nil {
+			err := updateVariantStock(tx, movement.ProductID, *movement.VariantID, "stock + ? >= reserved", movement.Quantity, map[string]interface{}{
+				"stock":      gorm.Expr("stock + ?", movement.Quantity),
+				"updated_at": time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := defaultLocation(tx, movement); err != nil {
+			return err
+		}
+		if err := updateStockLevel(tx, movement); err != nil {
+			return err
+		}
+
+		if err := tx.Create(movement).Error; err != nil {
+			return err
+		}
+
+		return tx.Take(p, movement.ProductID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *ormRepo) GetStockMovements(productID uint) ([]*product.StockMovement, error) {
+	movements := []*product.StockMovement{}
+	result := r.db.Where("product_id = ?", productID).Order("id").Find(&movements)
+	return movements, result.Error
+}
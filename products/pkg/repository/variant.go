@@ -0,0 +1,77 @@
+package mysql_orm
+
+import (
+	"github.com/marceloaguero/go-nats-products/products/pkg/product"
+	"gorm.io/gorm"
+)
+
+func (r *ormRepo) CreateVariant(v *product.Variant) error {
+	result := r.db.Create(v)
+	return translateDuplicate(result.Error, "variant", "sku", v.SKU)
+}
+
+func (r *ormRepo) GetVariant(id uint) (*product.Variant, error) {
+	var variant product.Variant
+	result := r.db.Take(&variant, id)
+	return &variant, translateError(result.Error, "variant", id)
+}
+
+func (r *ormRepo) GetVariantBySKU(sku string) (*product.Variant, error) {
+	var variant product.Variant
+	result := r.db.Take(&variant, "sku = ?", sku)
+	return &variant, translateError(result.Error, "variant", sku)
+}
+
+func (r *ormRepo) GetVariants(productID uint) ([]*product.Variant, error) {
+	variants := []*product.Variant{}
+	result := r.db.Where("product_id = ?", productID).Order("id").Find(&variants)
+	return variants, result.Error
+}
+
+func (r *ormRepo) UpdateVariant(v *product.Variant) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// El stock sólo se modifica a través de los movimientos de stock y las reservas
+		result := tx.Model(v).Select("sku", "options", "price", "updated_at").Updates(v)
+		if result.Error != nil {
+			return translateDuplicate(result.Error, "variant", "sku", v.SKU)
+		}
+
+		return translateError(tx.Take(v, v.ID).Error, "variant", v.ID)
+	})
+}
+
+func (r *ormRepo) DeleteVariant(v *product.Variant) error {
+	result := r.db.Where("stock = 0 AND reserved = 0").Delete(&product.Variant{}, v.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := r.db.Take(&product.Variant{}, v.ID).Error; err != nil {
+			return translateError(err, "variant", v.ID)
+		}
+		return product.ErrVariantHasStock
+	}
+
+	return nil
+}
+
+// updateVariantStock aplica a una variante del producto productID la modificación updates, siempre que se cumpla la condición
+// sobre su stock. Si la variante no existe devuelve NotFoundError y, si no se cumple la condición, ErrInsufficientStock.
+func updateVariantStock(tx *gorm.DB, productID, variantID uint, condition string, quantity float64, updates map[string]interface{}) error {
+	db := tx.Model(&product.Variant{}).Where("id = ? AND product_id = ?", variantID, productID)
+	if condition != "" {
+		db = db.Where(condition, quantity)
+	}
+	result := db.Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := tx.Take(&product.Variant{}, "id = ? AND product_id = ?", variantID, productID).Error; err != nil {
+			return translateError(err, "variant", variantID)
+		}
+		return product.ErrInsufficientStock
+	}
+
+	return nil
+}
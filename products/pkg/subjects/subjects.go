@@ -0,0 +1,66 @@
+// Package subjects define los subjects NATS que expone el servicio de productos.
+// Tanto la delivery del servicio como el cliente (productsclient) los arman a partir
+// del prefijo configurado (SUBJ_PREFIX) más alguno de estos sufijos, de modo que el
+// protocolo quede definido en un único lugar.
+package subjects
+
+const (
+	Create      = ".create"      // Alta de un producto
+	GetByID     = ".getbyid"     // Consulta de un producto por ID (product.ByIDQuery)
+	GetByName   = ".getbyname"   // Consulta de un producto por nombre
+	GetAll      = ".getall"      // Listado paginado de productos
+	Update      = ".update"      // Modificación de un producto
+	Delete      = ".delete"      // Baja lógica de un producto
+	Restore     = ".restore"     // Restauración de un producto eliminado
+	Purge       = ".purge"       // Baja definitiva de un producto
+	UpdateStock = ".updatestock" // Actualización del stock de un producto
+	History     = ".history"     // Historial de auditoría de un producto
+
+	AddStockMovement  = ".addstockmovement"  // Registro de un movimiento de stock
+	GetStockMovements = ".getstockmovements" // Consulta de los movimientos de stock de un producto
+
+	Reserve        = ".reserve"        // Reserva de stock de un producto
+	GetReservation = ".getreservation" // Consulta de una reserva por ID
+	Confirm        = ".confirm"        // Confirmación de una reserva
+	Release        = ".release"        // Liberación de una reserva
+
+	SchedulePrice = ".scheduleprice" // Programación de un cambio de precio
+	CancelPrice   = ".cancelprice"   // Cancelación de un cambio de precio programado
+	GetPrices     = ".getprices"     // Consulta de los precios pasados y programados de un producto
+
+	CreatePriceList  = ".createpricelist"  // Alta de una lista de precios
+	GetPriceList     = ".getpricelist"     // Consulta de una lista de precios por nombre
+	GetPriceLists    = ".getpricelists"    // Consulta de todas las listas de precios
+	DeletePriceList  = ".deletepricelist"  // Baja de una lista de precios, con sus precios
+	UpdateListPrices = ".updatelistprices" // Modificación masiva de los precios de una lista (product.ListPricesUpdate)
+	GetListPrices    = ".getlistprices"    // Consulta paginada de los precios de una lista
+	ResolvePrice     = ".resolveprice"     // Resolución del precio de un producto en una lista (product.ResolvePriceQuery)
+
+	CreateCategory       = ".createcategory"       // Alta de una categoría
+	GetCategory          = ".getcategory"          // Consulta de una categoría por ID
+	GetCategories        = ".getcategories"        // Consulta de todas las categorías
+	UpdateCategory       = ".updatecategory"       // Modificación de una categoría, incluso su categoría padre
+	DeleteCategory       = ".deletecategory"       // Baja de una categoría sin subcategorías
+	SetProductCategories = ".setproductcategories" // Asignación de las categorías de un producto (product.ProductCategories)
+	GetProductCategories = ".getproductcategories" // Consulta de las categorías de un producto
+
+	CreateVariant   = ".createvariant"   // Alta de una variante de un producto
+	GetVariant      = ".getvariant"      // Consulta de una variante de un producto por ID
+	GetVariantBySKU = ".getvariantbysku" // Consulta de una variante por SKU
+	GetVariants     = ".getvariants"     // Consulta de las variantes de un producto
+	UpdateVariant   = ".updatevariant"   // Modificación de una variante, salvo su stock
+	DeleteVariant   = ".deletevariant"   // Baja de una variante sin stock
+
+	CreateLocation = ".createlocation" // Alta de una ubicación de stock
+	GetLocations   = ".getlocations"   // Consulta de todas las ubicaciones de stock
+	GetStockLevels = ".getstocklevels" // Consulta del stock por ubicación de un producto o de una ubicación (product.StockLevelQuery)
+	TransferStock  = ".transferstock"  // Transferencia de stock de un producto entre dos ubicaciones (product.StockTransfer)
+
+	ImportProducts = ".importproducts" // Importación masiva de un lote de productos (product.ImportRequest)
+	ExportProducts = ".exportproducts" // Página de una exportación del catálogo, paginada por cursor y sin total (product.Query)
+	Batch          = ".batch"          // Lote de altas, modificaciones, bajas y modificaciones de stock (product.BatchRequest)
+)
+
+// ActorHeader es el header NATS con el que el cliente informa quién realiza el pedido.
+// El servicio lo registra como autor de los cambios en el historial de auditoría.
+const ActorHeader = "Products-Actor"
@@ -0,0 +1,56 @@
+// Package worker ejecuta tareas periódicas en background dentro del servicio de productos
+package worker
+
+import (
+	"log"
+	"time"
+)
+
+// Worker ejecuta una tarea cada cierto intervalo, hasta que se lo detiene
+type Worker struct {
+	name     string
+	interval time.Duration
+	job      func() error
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New crea un worker que ejecuta job cada interval. name se utiliza para identificarlo en el log.
+func New(name string, interval time.Duration, job func() error) *Worker {
+	return &Worker{
+		name:     name,
+		interval: interval,
+		job:      job,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start inicia la ejecución periódica en una goroutine
+func (w *Worker) Start() {
+	go w.run()
+}
+
+// Stop detiene el worker y espera a que termine la ejecución en curso
+func (w *Worker) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+func (w *Worker) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.job(); err != nil {
+				log.Printf("WRK - %s - %s", w.name, err.Error())
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}